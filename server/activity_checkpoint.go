@@ -0,0 +1,142 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/mattermost/mattermost-plugin-msteams/server/msteams"
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/pkg/errors"
+)
+
+// activityCheckpointKeyPrefix prefixes the keys of the activities saved when the activity handler
+// stops. Each stop saves under its own key, so nodes stopping together don't overwrite each other.
+const activityCheckpointKeyPrefix = "activity_checkpoint_"
+
+// checkpointedActivity is an activity saved by checkpointQueue, along with the handling state not
+// encoded with the activity itself, so a retry or replay resumes as it would have run.
+type checkpointedActivity struct {
+	Activity      msteams.Activity `json:"activity"`
+	CorrelationID string           `json:"correlation_id"`
+	Attempts      int              `json:"attempts"`
+}
+
+// checkpointQueue saves the activities still queued or deferred once the workers have stopped, so
+// they are handled after the plugin is activated again, e.g. following an upgrade, instead of being
+// lost.
+func (ah *ActivityHandler) checkpointQueue() {
+	// Take the deferred activities first: any requeued before then are drained below.
+	activities := ah.takeDeferredActivities()
+drain:
+	for {
+		select {
+		case activity := <-ah.queue:
+			ah.dequeued()
+			ah.plugin.GetMetrics().DecrementChangeEventQueueLength(activity.ChangeType)
+			activities = append(activities, activity)
+		default:
+			break drain
+		}
+	}
+
+	if len(activities) == 0 {
+		return
+	}
+
+	if err := ah.saveCheckpoint(activities); err != nil {
+		ah.plugin.GetAPI().LogWarn("Unable to checkpoint the queued activities", "count", len(activities), "error", err.Error())
+		return
+	}
+
+	ah.plugin.GetAPI().LogInfo("Checkpointed the queued activities", "count", len(activities))
+}
+
+// saveCheckpoint saves the given activities under a new checkpoint key, without the webhook secret
+// or encrypted content sent along with them by MS Graph.
+func (ah *ActivityHandler) saveCheckpoint(activities []msteams.Activity) error {
+	checkpointed := make([]checkpointedActivity, 0, len(activities))
+	for _, activity := range activities {
+		checkpointed = append(checkpointed, checkpointedActivity{
+			Activity:      withoutActivitySecrets(activity),
+			CorrelationID: activity.CorrelationID,
+			Attempts:      activity.Attempts,
+		})
+	}
+
+	data, err := json.Marshal(checkpointed)
+	if err != nil {
+		return errors.Wrap(err, "failed to encode the activities")
+	}
+
+	if appErr := ah.plugin.GetAPI().KVSet(activityCheckpointKeyPrefix+model.NewId(), data); appErr != nil {
+		return errors.Wrap(appErr, "failed to store the activities")
+	}
+
+	return nil
+}
+
+// resumeFromCheckpoints queues the activities saved by checkpointQueue. Each checkpoint is
+// claimed by deleting it first, so only one node handles it, and the activities that cannot be
+// queued, e.g. when the queue is full, are saved again to be resumed on the next activation.
+func (ah *ActivityHandler) resumeFromCheckpoints() {
+	keys, appErr := ah.plugin.listKVKeys(activityCheckpointKeyPrefix)
+	if appErr != nil {
+		ah.plugin.GetAPI().LogWarn("Unable to list the activity checkpoints", "error", appErr.Error())
+		return
+	}
+
+	for _, key := range keys {
+		data, appErr := ah.plugin.GetAPI().KVGet(key)
+		if appErr != nil || data == nil {
+			continue
+		}
+
+		deleted, appErr := ah.plugin.GetAPI().KVCompareAndDelete(key, data)
+		if appErr != nil || !deleted {
+			continue
+		}
+
+		var checkpointed []checkpointedActivity
+		if err := json.Unmarshal(data, &checkpointed); err != nil {
+			ah.plugin.GetAPI().LogWarn("Unable to decode the activity checkpoint", "key", key, "error", err.Error())
+			continue
+		}
+
+		var unqueued []msteams.Activity
+		for _, c := range checkpointed {
+			activity := c.Activity
+			activity.CorrelationID = c.CorrelationID
+			activity.Attempts = c.Attempts
+			if err := ah.Handle(activity); err != nil {
+				unqueued = append(unqueued, activity)
+			}
+		}
+
+		if len(unqueued) > 0 {
+			if err := ah.saveCheckpoint(unqueued); err != nil {
+				ah.plugin.GetAPI().LogWarn("Unable to checkpoint the activities that could not be resumed", "count", len(unqueued), "error", err.Error())
+			}
+		}
+
+		ah.plugin.GetAPI().LogInfo("Resumed the checkpointed activities", "count", len(checkpointed)-len(unqueued), "deferred", len(unqueued))
+	}
+}
+
+// listKVKeys returns the keys of the plugin's key value store starting with the given prefix.
+func (p *Plugin) listKVKeys(prefix string) ([]string, *model.AppError) {
+	var keys []string
+	for page := 0; ; page++ {
+		pageKeys, appErr := p.GetAPI().KVList(page, MaxPerPage)
+		if appErr != nil {
+			return nil, appErr
+		}
+		for _, key := range pageKeys {
+			if strings.HasPrefix(key, prefix) {
+				keys = append(keys, key)
+			}
+		}
+		if len(pageKeys) < MaxPerPage {
+			return keys, nil
+		}
+	}
+}
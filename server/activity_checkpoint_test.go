@@ -0,0 +1,70 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/mattermost/mattermost-plugin-msteams/server/msteams"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestActivityCheckpoint(t *testing.T) {
+	th := setupTestHelper(t)
+
+	stopped := NewActivityHandler(th.p)
+	require.NoError(t, stopped.Handle(msteams.Activity{Resource: "chats('chat-id')/messages('1')", ChangeType: "created"}))
+	require.NoError(t, stopped.Handle(msteams.Activity{Resource: "chats('chat-id')/messages('2')", ChangeType: "updated"}))
+
+	stopped.checkpointQueue()
+	assert.Empty(t, stopped.queue)
+
+	resumed := NewActivityHandler(th.p)
+	resumed.resumeFromCheckpoints()
+	require.Len(t, resumed.queue, 2)
+	assert.Equal(t, "chats('chat-id')/messages('1')", (<-resumed.queue).Resource)
+	assert.Equal(t, "chats('chat-id')/messages('2')", (<-resumed.queue).Resource)
+
+	t.Run("checkpoints are only resumed once", func(t *testing.T) {
+		again := NewActivityHandler(th.p)
+		again.resumeFromCheckpoints()
+		assert.Empty(t, again.queue)
+	})
+
+	t.Run("handling state kept, webhook secret not kept", func(t *testing.T) {
+		require.NoError(t, stopped.Handle(msteams.Activity{
+			Resource:         "chats('chat-id')/messages('5')",
+			ChangeType:       "created",
+			ClientState:      "webhook-secret",
+			EncryptedContent: &msteams.EncryptedContent{Data: "data"},
+			CorrelationID:    "correlation-id",
+			Attempts:         2,
+		}))
+		stopped.checkpointQueue()
+
+		next := NewActivityHandler(th.p)
+		next.resumeFromCheckpoints()
+		require.Len(t, next.queue, 1)
+		activity := <-next.queue
+		assert.Equal(t, "correlation-id", activity.CorrelationID)
+		assert.Equal(t, 2, activity.Attempts)
+		assert.Empty(t, activity.ClientState)
+		assert.Nil(t, activity.EncryptedContent)
+	})
+
+	t.Run("activities that cannot be queued are checkpointed again", func(t *testing.T) {
+		require.NoError(t, stopped.Handle(msteams.Activity{Resource: "chats('chat-id')/messages('3')", ChangeType: "created"}))
+		require.NoError(t, stopped.Handle(msteams.Activity{Resource: "chats('chat-id')/messages('4')", ChangeType: "created"}))
+		stopped.checkpointQueue()
+
+		full := NewActivityHandler(th.p)
+		full.queue = make(chan msteams.Activity, 1)
+		full.resumeFromCheckpoints()
+		require.Len(t, full.queue, 1)
+		assert.Equal(t, "chats('chat-id')/messages('3')", (<-full.queue).Resource)
+
+		next := NewActivityHandler(th.p)
+		next.resumeFromCheckpoints()
+		require.Len(t, next.queue, 1)
+		assert.Equal(t, "chats('chat-id')/messages('4')", (<-next.queue).Resource)
+	})
+}
@@ -18,6 +18,7 @@ import (
 	"github.com/gorilla/mux"
 	"github.com/mattermost/mattermost-plugin-msteams/server/metrics"
 	"github.com/mattermost/mattermost-plugin-msteams/server/msteams"
+	"github.com/mattermost/mattermost-plugin-msteams/server/msteams/clientmodels"
 	"github.com/mattermost/mattermost-plugin-msteams/server/store"
 	"github.com/mattermost/mattermost-plugin-msteams/server/store/storemodels"
 
@@ -45,6 +46,8 @@ const (
 	QueryParamChannelID                       = "channel_id"
 	QueryParamPostID                          = "post_id"
 	QueryParamFromPreferences                 = "from_preferences"
+	QueryParamMonth                           = "month"
+	QueryParamID                              = "id"
 )
 
 type UpdateWhitelistResult struct {
@@ -75,9 +78,21 @@ func NewAPI(p *Plugin, store store.Store) *API {
 	router.HandleFunc("/connected-users/download", api.getConnectedUsersFile).Methods(http.MethodGet)
 	router.HandleFunc("/whitelist", api.updateWhitelist).Methods(http.MethodPut)
 	router.HandleFunc("/whitelist/download", api.getWhitelistEmailsFile).Methods(http.MethodGet)
+	router.HandleFunc("/settings/schema", api.getSettingsSchema).Methods(http.MethodGet)
+	router.HandleFunc("/settings/validate", api.validateSettings).Methods(http.MethodPost)
+	router.HandleFunc("/settings/test-credentials", api.testCredentials).Methods(http.MethodPost)
+	router.HandleFunc("/settings/app-manifest", api.getAppManifest).Methods(http.MethodGet)
+	router.HandleFunc("/backup/export", api.exportBackup).Methods(http.MethodGet)
+	router.HandleFunc("/backup/import", api.importBackup).Methods(http.MethodPost)
 	router.HandleFunc("/notify-connect", api.notifyConnect).Methods("GET")
 	router.HandleFunc("/account-connected", api.accountConnectedPage).Methods(http.MethodGet)
 	router.HandleFunc("/stats/site", api.siteStats).Methods("GET")
+	router.HandleFunc("/stats/messages", api.messageStats).Methods("GET")
+	router.HandleFunc("/stats/graph", api.graphStats).Methods("GET")
+	router.HandleFunc("/quarantine", api.getQuarantine).Methods(http.MethodGet)
+	router.HandleFunc("/quarantine", api.deleteQuarantine).Methods(http.MethodDelete)
+	router.HandleFunc("/quarantine/replay", api.replayQuarantine).Methods(http.MethodPost)
+	router.HandleFunc("/debug/replay-message", api.replayMessage).Methods(http.MethodPost)
 	router.HandleFunc("/enable-notifications", api.enableNotifications).Methods("POST")
 	router.HandleFunc("/disable-notifications", api.disableNotifications).Methods("POST")
 
@@ -105,6 +120,12 @@ func (a *API) processActivity(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
+	if !a.p.isWebhookSourceAllowed(req) {
+		a.p.metricsService.ObserveChangeEvent("", "", metrics.DiscardedReasonUntrustedSource)
+		http.Error(w, "untrusted source", http.StatusForbidden)
+		return
+	}
+
 	activities := Activities{}
 	err := json.NewDecoder(req.Body).Decode(&activities)
 	if err != nil {
@@ -113,9 +134,18 @@ func (a *API) processActivity(w http.ResponseWriter, req *http.Request) {
 	}
 	defer req.Body.Close()
 
+	if a.p.activityHandler.isUnderBackpressure() {
+		a.p.metricsService.ObserveChangeEventQueueBackpressure()
+		a.p.API.LogWarn("Deferring change notifications while the activity queue is nearly full", "count", len(activities.Value))
+		w.Header().Set("Retry-After", strconv.Itoa(int(activityQueueRetryAfter.Seconds())))
+		http.Error(w, "activity queue nearly full", http.StatusServiceUnavailable)
+		return
+	}
+
 	errors := ""
 	for _, activity := range activities.Value {
-		if activity.ClientState != a.p.getConfiguration().WebhookSecret {
+		if !a.validClientState(activity) {
+			a.p.metricsService.ObserveChangeEvent(activity.ChangeType, activityResourceKind(activity.Resource), metrics.DiscardedReasonInvalidWebhookSecret)
 			errors += "Invalid webhook secret"
 			continue
 		}
@@ -143,6 +173,12 @@ func (a *API) processLifecycle(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
+	if !a.p.isWebhookSourceAllowed(req) {
+		a.p.metricsService.ObserveLifecycleEvent("", metrics.DiscardedReasonUntrustedSource)
+		http.Error(w, "untrusted source", http.StatusForbidden)
+		return
+	}
+
 	lifecycleEvents := Activities{}
 	err := json.NewDecoder(req.Body).Decode(&lifecycleEvents)
 	if err != nil {
@@ -153,8 +189,7 @@ func (a *API) processLifecycle(w http.ResponseWriter, req *http.Request) {
 
 	errors := ""
 	for _, event := range lifecycleEvents.Value {
-		// Check the webhook secret using ContantTimeCompare to prevent timing attacks
-		if subtle.ConstantTimeCompare([]byte(event.ClientState), []byte(a.p.getConfiguration().WebhookSecret)) == 0 {
+		if !a.validClientState(event) {
 			a.p.metricsService.ObserveLifecycleEvent(event.LifecycleEvent, metrics.DiscardedReasonInvalidWebhookSecret)
 			errors += "Invalid webhook secret"
 			continue
@@ -169,6 +204,12 @@ func (a *API) processLifecycle(w http.ResponseWriter, req *http.Request) {
 	w.WriteHeader(http.StatusOK)
 }
 
+// validClientState reports whether the activity carries the webhook secret, comparing in constant
+// time to prevent timing attacks.
+func (a *API) validClientState(activity msteams.Activity) bool {
+	return subtle.ConstantTimeCompare([]byte(activity.ClientState), []byte(a.p.getConfiguration().WebhookSecret)) == 1
+}
+
 func (a *API) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	a.router.ServeHTTP(w, r)
 }
@@ -184,14 +225,7 @@ func (a *API) autocompleteTeams(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	teams, err := client.ListTeams()
-	if err != nil {
-		a.p.API.LogWarn("Unable to get the MS Teams teams", "error", err.Error())
-		a.returnJSON(w, out)
-		return
-	}
-
-	for _, t := range teams {
+	err = msteams.ForEachPage(client.ListTeams, func(t clientmodels.Team) bool {
 		s := model.AutocompleteListItem{
 			Item:     t.ID,
 			Hint:     t.DisplayName,
@@ -199,6 +233,10 @@ func (a *API) autocompleteTeams(w http.ResponseWriter, r *http.Request) {
 		}
 
 		out = append(out, s)
+		return true
+	})
+	if err != nil {
+		a.p.API.LogWarn("Unable to get the MS Teams teams", "error", err.Error())
 	}
 
 	a.returnJSON(w, out)
@@ -221,14 +259,10 @@ func (a *API) autocompleteChannels(w http.ResponseWriter, r *http.Request) {
 	}
 
 	teamID := args[2]
-	channels, err := client.ListChannels(teamID)
-	if err != nil {
-		a.p.API.LogWarn("Unable to get the channels for MS Teams team", "team_id", teamID, "error", err.Error())
-		a.returnJSON(w, out)
-		return
+	listChannels := func(pageToken string) ([]clientmodels.Channel, string, error) {
+		return client.ListChannels(teamID, pageToken)
 	}
-
-	for _, c := range channels {
+	err = msteams.ForEachPage(listChannels, func(c clientmodels.Channel) bool {
 		s := model.AutocompleteListItem{
 			Item:     c.ID,
 			Hint:     c.DisplayName,
@@ -236,6 +270,10 @@ func (a *API) autocompleteChannels(w http.ResponseWriter, r *http.Request) {
 		}
 
 		out = append(out, s)
+		return true
+	})
+	if err != nil {
+		a.p.API.LogWarn("Unable to get the channels for MS Teams team", "team_id", teamID, "error", err.Error())
 	}
 	a.returnJSON(w, out)
 }
@@ -263,14 +301,14 @@ func (a *API) connect(w http.ResponseWriter, r *http.Request) {
 
 	if storedToken, _ := a.p.store.GetTokenForMattermostUser(userID); storedToken != nil {
 		a.p.API.LogWarn("The account is already connected to MS Teams", "user_id", userID)
-		http.Error(w, "Error in trying to connect the account, please try again.", http.StatusForbidden)
+		http.Error(w, "Your account is already connected to MS Teams. Please disconnect your account first before connecting again.", http.StatusForbidden)
 		return
 	}
 
 	state := fmt.Sprintf("%s_%s_%s", model.NewId(), userID, stateSuffix)
 	if err := a.store.StoreOAuth2State(state); err != nil {
 		a.p.API.LogWarn("Error in storing the OAuth state", "error", err.Error())
-		http.Error(w, "Error in trying to connect the account, please try again.", http.StatusInternalServerError)
+		http.Error(w, "Unable to start connecting your account. "+storeErrorRemediation, http.StatusInternalServerError)
 		return
 	}
 
@@ -278,7 +316,7 @@ func (a *API) connect(w http.ResponseWriter, r *http.Request) {
 	codeVerifierKey := "_code_verifier_" + userID
 	if appErr := a.p.API.KVSet(codeVerifierKey, []byte(codeVerifier)); appErr != nil {
 		a.p.API.LogWarn("Error in storing the code verifier", "error", appErr.Message)
-		http.Error(w, "Error in trying to connect the account, please try again.", http.StatusInternalServerError)
+		http.Error(w, "Unable to start connecting your account. "+storeErrorRemediation, http.StatusInternalServerError)
 		return
 	}
 
@@ -421,22 +459,22 @@ func (a *API) oauthRedirectHandler(w http.ResponseWriter, r *http.Request) {
 	ctx := context.Background()
 	token, err := conf.Exchange(ctx, code, oauth2.SetAuthURLParam("code_verifier", string(codeVerifierBytes)))
 	if err != nil {
-		a.p.API.LogWarn("Unable to get OAuth2 token", "error", err.Error())
-		http.Error(w, "Unable to complete authentication", http.StatusInternalServerError)
+		a.p.API.LogWarn("Unable to get OAuth2 token", "error", err.Error(), "error_category", msteams.CategorizeError(err))
+		http.Error(w, "Unable to complete authentication. "+remediationForError(err), httpStatusForError(err))
 		return
 	}
 
 	client := msteams.NewTokenClient(a.p.GetURL()+"/oauth-redirect", a.p.configuration.TenantID, a.p.configuration.ClientID, a.p.configuration.ClientSecret, token, &a.p.apiClient.Log)
 	if err = client.Connect(); err != nil {
-		a.p.API.LogWarn("Unable to connect to the client", "error", err.Error())
-		http.Error(w, "failed to connect to the client", http.StatusInternalServerError)
+		a.p.API.LogWarn("Unable to connect to the client", "error", err.Error(), "error_category", msteams.CategorizeError(err))
+		http.Error(w, "Unable to connect to Microsoft Teams. "+remediationForError(err), httpStatusForError(err))
 		return
 	}
 
 	msteamsUser, err := client.GetMe()
 	if err != nil {
-		a.p.API.LogWarn("Unable to get the MS Teams user", "error", err.Error())
-		http.Error(w, "failed to get the MS Teams user", http.StatusInternalServerError)
+		a.p.API.LogWarn("Unable to get the MS Teams user", "error", err.Error(), "error_category", msteams.CategorizeError(err))
+		http.Error(w, "Unable to get your Microsoft Teams user. "+remediationForError(err), httpStatusForError(err))
 		return
 	}
 
@@ -498,10 +536,13 @@ func (a *API) oauthRedirectHandler(w http.ResponseWriter, r *http.Request) {
 
 	if err = a.p.store.SetUserInfo(mmUserID, msteamsUser.ID, token); err != nil {
 		a.p.API.LogWarn("Unable to store the token", "error", err.Error(), "user_id", mmUserID, "teams_user_id", msteamsUser.ID)
+		a.p.audit(auditEventUserConnected, mmUserID, auditStatusFail, "teams_user_id", msteamsUser.ID)
 		http.Error(w, "failed to store the token", http.StatusInternalServerError)
 		return
 	}
 
+	a.p.audit(auditEventUserConnected, mmUserID, auditStatusSuccess, "teams_user_id", msteamsUser.ID)
+
 	a.p.API.PublishWebSocketEvent(WSEventUserConnected, map[string]any{}, &model.WebsocketBroadcast{
 		UserId: mmUserID,
 	})
@@ -763,11 +804,13 @@ func (a *API) updateWhitelist(w http.ResponseWriter, r *http.Request) {
 
 	if err := a.p.store.SetWhitelist(ids, MaxPerPage); err != nil {
 		a.p.API.LogWarn("Error processing whitelist", "error", err.Error())
+		a.p.audit(auditEventWhitelistUpdated, userID, auditStatusFail, "size", len(ids))
 		http.Error(w, "error processing whitelist - please check data and try again", http.StatusInternalServerError)
 		return
 	}
 
 	a.p.API.LogInfo("Whitelist updated", "size", len(ids))
+	a.p.audit(auditEventWhitelistUpdated, userID, auditStatusSuccess, "size", len(ids), "failed", len(failed))
 
 	w.WriteHeader(http.StatusOK)
 	w.Header().Set("Content-Type", "application/json")
@@ -901,6 +944,38 @@ func (a *API) siteStats(w http.ResponseWriter, r *http.Request) {
 	a.returnJSON(w, siteStats)
 }
 
+// messageStats reports how many messages were relayed for each user in a month, given as
+// YYYY-MM and defaulting to the current one.
+func (a *API) messageStats(w http.ResponseWriter, r *http.Request) {
+	userID := r.Header.Get("Mattermost-User-ID")
+
+	if !a.p.API.HasPermissionTo(userID, model.PermissionManageSystem) {
+		a.p.API.LogWarn("Insufficient permissions", "user_id", userID)
+		http.Error(w, "not able to authorize the user", http.StatusForbidden)
+		return
+	}
+
+	month := r.URL.Query().Get(QueryParamMonth)
+	if month == "" {
+		month = messageStatsMonth(time.Now())
+	} else if _, err := time.Parse(messageStatsMonthLayout, month); err != nil {
+		http.Error(w, "invalid month, expected YYYY-MM", http.StatusBadRequest)
+		return
+	}
+
+	stats, err := a.p.store.GetMessageStats(month)
+	if err != nil {
+		a.p.API.LogWarn("Failed to get message stats", "month", month, "error", err.Error())
+		http.Error(w, "unable to get message stats", http.StatusInternalServerError)
+		return
+	}
+	if stats == nil {
+		stats = []*storemodels.MessageStats{}
+	}
+
+	a.returnJSON(w, stats)
+}
+
 func (a *API) preHandleNotifications(w http.ResponseWriter, r *http.Request) *model.Post {
 	userID := r.Header.Get("Mattermost-User-ID")
 
@@ -958,7 +1033,7 @@ func (a *API) enableNotifications(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	post.Message = "You'll now start receiving notifications here in Mattermost from chats and group chats from Microsoft Teams. To change this Mattermost setting, select **Settings > MS Teams**, or run the **/msteams notifications** slash command."
+	post.Message = "You'll now start receiving notifications here in Mattermost from chats and group chats from Microsoft Teams. To change this Mattermost setting, select **Settings > MS Teams**, or run the **/" + a.p.getCommandTrigger() + " notifications** slash command."
 	post.DelProp("attachments")
 
 	err = json.NewEncoder(w).Encode(model.PostActionIntegrationResponse{
@@ -986,7 +1061,7 @@ func (a *API) disableNotifications(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	post.Message = "You'll stop receiving notifications here in Mattermost from chats and group chats from Microsoft Teams. To change this Mattermost setting, select **Settings > MS Teams**, or run the **/msteams notifications** slash command."
+	post.Message = "You'll stop receiving notifications here in Mattermost from chats and group chats from Microsoft Teams. To change this Mattermost setting, select **Settings > MS Teams**, or run the **/" + a.p.getCommandTrigger() + " notifications** slash command."
 	post.DelProp("attachments")
 
 	err = json.NewEncoder(w).Encode(model.PostActionIntegrationResponse{
@@ -390,7 +390,7 @@ func TestAutocompleteTeams(t *testing.T) {
 		th.Reset(t)
 
 		th.ConnectUser(t, user1.Id)
-		th.clientMock.On("ListTeams").Return(nil, errors.New("unable to get the teams list")).Times(1)
+		th.clientMock.On("ListTeams", "").Return(nil, "", errors.New("unable to get the teams list")).Times(1)
 
 		response, list := sendRequest(t, user1)
 		assert.Equal(t, http.StatusOK, response.StatusCode)
@@ -401,13 +401,13 @@ func TestAutocompleteTeams(t *testing.T) {
 		th.Reset(t)
 
 		th.ConnectUser(t, user1.Id)
-		th.clientMock.On("ListTeams").Return([]clientmodels.Team{
+		th.clientMock.On("ListTeams", "").Return([]clientmodels.Team{
 			{
 				ID:          "mockTeamsTeamID-1",
 				DisplayName: "mockDisplayName-1",
 				Description: "mockDescription-1",
 			},
-		}, nil).Times(1)
+		}, "", nil).Times(1)
 
 		response, list := sendRequest(t, user1)
 		assert.Equal(t, http.StatusOK, response.StatusCode)
@@ -424,18 +424,20 @@ func TestAutocompleteTeams(t *testing.T) {
 		th.Reset(t)
 
 		th.ConnectUser(t, user1.Id)
-		th.clientMock.On("ListTeams").Return([]clientmodels.Team{
+		th.clientMock.On("ListTeams", "").Return([]clientmodels.Team{
 			{
 				ID:          "mockTeamsTeamID-1",
 				DisplayName: "mockDisplayName-1",
 				Description: "mockDescription-1",
 			},
+		}, "mockNextLink", nil).Times(1)
+		th.clientMock.On("ListTeams", "mockNextLink").Return([]clientmodels.Team{
 			{
 				ID:          "mockTeamsTeamID-2",
 				DisplayName: "mockDisplayName-2",
 				Description: "mockDescription-2",
 			},
-		}, nil).Times(1)
+		}, "", nil).Times(1)
 
 		response, list := sendRequest(t, user1)
 		assert.Equal(t, http.StatusOK, response.StatusCode)
@@ -509,7 +511,7 @@ func TestAutocompleteChannels(t *testing.T) {
 		th.Reset(t)
 
 		th.ConnectUser(t, user1.Id)
-		th.clientMock.On("ListChannels", "mockData-3").Return(nil, errors.New("unable to get the channels list")).Times(1)
+		th.clientMock.On("ListChannels", "mockData-3", "").Return(nil, "", errors.New("unable to get the channels list")).Times(1)
 
 		response, list := sendRequest(t, user1, "mockData-1 mockData-2 mockData-3")
 		assert.Equal(t, http.StatusOK, response.StatusCode)
@@ -520,13 +522,13 @@ func TestAutocompleteChannels(t *testing.T) {
 		th.Reset(t)
 
 		th.ConnectUser(t, user1.Id)
-		th.clientMock.On("ListChannels", "mockData-3").Return([]clientmodels.Channel{
+		th.clientMock.On("ListChannels", "mockData-3", "").Return([]clientmodels.Channel{
 			{
 				ID:          "mockTeamsChannelID-1",
 				DisplayName: "mockDisplayName-1",
 				Description: "mockDescription-1",
 			},
-		}, nil).Times(1)
+		}, "", nil).Times(1)
 
 		response, list := sendRequest(t, user1, "mockData-1 mockData-2 mockData-3")
 		assert.Equal(t, http.StatusOK, response.StatusCode)
@@ -543,7 +545,7 @@ func TestAutocompleteChannels(t *testing.T) {
 		th.Reset(t)
 
 		th.ConnectUser(t, user1.Id)
-		th.clientMock.On("ListChannels", "mockData-3").Return([]clientmodels.Channel{
+		th.clientMock.On("ListChannels", "mockData-3", "").Return([]clientmodels.Channel{
 			{
 				ID:          "mockTeamsChannelID-1",
 				DisplayName: "mockDisplayName-1",
@@ -554,7 +556,7 @@ func TestAutocompleteChannels(t *testing.T) {
 				DisplayName: "mockDisplayName-2",
 				Description: "mockDescription-2",
 			},
-		}, nil).Times(1)
+		}, "", nil).Times(1)
 
 		response, list := sendRequest(t, user1, "mockData-1 mockData-2 mockData-3")
 		assert.Equal(t, http.StatusOK, response.StatusCode)
@@ -1006,6 +1008,74 @@ func TestGetSiteStats(t *testing.T) {
 	})
 }
 
+func TestGetMessageStats(t *testing.T) {
+	th := setupTestHelper(t)
+	apiURL := th.pluginURL(t, "/stats/messages")
+	team := th.SetupTeam(t)
+
+	sendRequest := func(t *testing.T, user *model.User, month string) (*http.Response, []storemodels.MessageStats) {
+		t.Helper()
+		client1 := th.SetupClient(t, user.Id)
+
+		request, err := http.NewRequest(http.MethodGet, apiURL+"?month="+month, nil)
+		require.NoError(t, err)
+
+		request.Header.Set(model.HeaderAuth, client1.AuthType+" "+client1.AuthToken)
+
+		response, err := http.DefaultClient.Do(request)
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			require.NoError(t, response.Body.Close())
+		})
+
+		var stats []storemodels.MessageStats
+		if response.StatusCode == http.StatusOK {
+			require.NoError(t, json.NewDecoder(response.Body).Decode(&stats))
+		}
+
+		return response, stats
+	}
+
+	t.Run("insufficient permissions", func(t *testing.T) {
+		th.Reset(t)
+		user := th.SetupUser(t, team)
+
+		response, _ := sendRequest(t, user, "")
+		assert.Equal(t, http.StatusForbidden, response.StatusCode)
+	})
+
+	t.Run("invalid month", func(t *testing.T) {
+		th.Reset(t)
+		sysadmin := th.SetupSysadmin(t, team)
+
+		response, _ := sendRequest(t, sysadmin, "January")
+		assert.Equal(t, http.StatusBadRequest, response.StatusCode)
+	})
+
+	t.Run("messages counted in the month", func(t *testing.T) {
+		th.Reset(t)
+		sysadmin := th.SetupSysadmin(t, team)
+		user1 := th.SetupUser(t, team)
+
+		err := th.p.store.IncrementMessageCount(user1.Id, "2024-05", storemodels.MessageDirectionMSTeamsToMattermost)
+		require.NoError(t, err)
+		err = th.p.store.IncrementMessageCount(user1.Id, "2024-05", storemodels.MessageDirectionMSTeamsToMattermost)
+		require.NoError(t, err)
+
+		response, stats := sendRequest(t, sysadmin, "2024-05")
+		assert.Equal(t, http.StatusOK, response.StatusCode)
+		assert.Equal(t, []storemodels.MessageStats{
+			{
+				MattermostUserID: user1.Id,
+				Email:            user1.Email,
+				Month:            "2024-05",
+				Direction:        storemodels.MessageDirectionMSTeamsToMattermost,
+				Count:            2,
+			},
+		}, stats)
+	})
+}
+
 func TestConnectionStatus(t *testing.T) {
 	th := setupTestHelper(t)
 	apiURL := th.pluginURL(t, "/connection-status")
@@ -1121,7 +1191,7 @@ func TestNotificationsWelcomeMessage(t *testing.T) {
 		err := json.NewDecoder(response.Body).Decode(&resp)
 		require.NoError(t, err)
 		assert.Len(t, resp.Update.Attachments(), 0)
-		assert.Equal(t, "You'll now start receiving notifications here in Mattermost from chats and group chats from Microsoft Teams. To change this Mattermost setting, select **Settings > MS Teams**, or run the **/msteams notifications** slash command.", resp.Update.Message)
+		assert.Equal(t, "You'll now start receiving notifications here in Mattermost from chats and group chats from Microsoft Teams. To change this Mattermost setting, select **Settings > MS Teams**, or run the **/msteamssync notifications** slash command.", resp.Update.Message)
 
 		// Assert: 2. the notification preference is updated
 		assert.True(t, th.p.getNotificationPreference(user1.Id))
@@ -1149,7 +1219,7 @@ func TestNotificationsWelcomeMessage(t *testing.T) {
 		err = json.NewDecoder(response.Body).Decode(&resp)
 		require.NoError(t, err)
 		assert.Len(t, resp.Update.Attachments(), 0)
-		assert.Equal(t, "You'll stop receiving notifications here in Mattermost from chats and group chats from Microsoft Teams. To change this Mattermost setting, select **Settings > MS Teams**, or run the **/msteams notifications** slash command.", resp.Update.Message)
+		assert.Equal(t, "You'll stop receiving notifications here in Mattermost from chats and group chats from Microsoft Teams. To change this Mattermost setting, select **Settings > MS Teams**, or run the **/msteamssync notifications** slash command.", resp.Update.Message)
 
 		// Assert: 2. the notification preference is disabled
 		assert.False(t, th.p.getNotificationPreference(user1.Id))
@@ -1177,7 +1247,7 @@ func TestNotificationsWelcomeMessage(t *testing.T) {
 		err = json.NewDecoder(response.Body).Decode(&resp)
 		require.NoError(t, err)
 		assert.Len(t, resp.Update.Attachments(), 0)
-		assert.Equal(t, "You'll stop receiving notifications here in Mattermost from chats and group chats from Microsoft Teams. To change this Mattermost setting, select **Settings > MS Teams**, or run the **/msteams notifications** slash command.", resp.Update.Message)
+		assert.Equal(t, "You'll stop receiving notifications here in Mattermost from chats and group chats from Microsoft Teams. To change this Mattermost setting, select **Settings > MS Teams**, or run the **/msteamssync notifications** slash command.", resp.Update.Message)
 
 		// Assert: 2. the notification preference is disabled
 		assert.False(t, th.p.getNotificationPreference(user1.Id))
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/mattermost/mattermost/server/public/model"
+)
+
+// microsoftGraphAppID identifies the Microsoft Graph API among the resources an app registration
+// requires access to.
+const microsoftGraphAppID = "00000003-0000-0000-c000-000000000000"
+
+type appManifestResourceAccess struct {
+	ID   string `json:"id"`
+	Type string `json:"type"`
+}
+
+type appManifestRequiredResourceAccess struct {
+	ResourceAppID  string                      `json:"resourceAppId"`
+	ResourceAccess []appManifestResourceAccess `json:"resourceAccess"`
+}
+
+type appManifestWeb struct {
+	RedirectURIs []string `json:"redirectUris"`
+}
+
+// appManifest is the subset of a Microsoft Entra ID app registration manifest this plugin needs,
+// ready to be pasted into the manifest editor of the app registration.
+type appManifest struct {
+	DisplayName            string                              `json:"displayName"`
+	SignInAudience         string                              `json:"signInAudience"`
+	Web                    appManifestWeb                      `json:"web"`
+	RequiredResourceAccess []appManifestRequiredResourceAccess `json:"requiredResourceAccess"`
+}
+
+// buildAppManifest describes the app registration expected for the plugin served at the given
+// URL, granting exactly the permissions checked by checkPermissions.
+func buildAppManifest(pluginURL string) *appManifest {
+	graphAccess := appManifestRequiredResourceAccess{
+		ResourceAppID:  microsoftGraphAppID,
+		ResourceAccess: []appManifestResourceAccess{},
+	}
+	for _, permission := range getExpectedPermissions() {
+		graphAccess.ResourceAccess = append(graphAccess.ResourceAccess, appManifestResourceAccess{
+			ID:   permission.ResourceAccess.ID,
+			Type: permission.ResourceAccess.Type,
+		})
+	}
+
+	return &appManifest{
+		DisplayName:    "Mattermost MS Teams",
+		SignInAudience: "AzureADMyOrg",
+		Web: appManifestWeb{
+			RedirectURIs: []string{pluginURL + "/oauth-redirect"},
+		},
+		RequiredResourceAccess: []appManifestRequiredResourceAccess{graphAccess},
+	}
+}
+
+// getAppManifest returns the app registration manifest for this server, so admins can configure
+// the redirect URI and permissions in Microsoft Entra ID without transcribing them.
+func (a *API) getAppManifest(w http.ResponseWriter, r *http.Request) {
+	userID := r.Header.Get("Mattermost-User-ID")
+	if userID == "" {
+		a.p.API.LogWarn("Not authorized")
+		http.Error(w, "not authorized", http.StatusUnauthorized)
+		return
+	}
+
+	if !a.p.API.HasPermissionTo(userID, model.PermissionManageSystem) {
+		a.p.API.LogWarn("Insufficient permissions", "user_id", userID)
+		http.Error(w, "not able to authorize the user", http.StatusForbidden)
+		return
+	}
+
+	w.Header().Set("Content-Disposition", "attachment;filename=msteams-app-manifest.json")
+	a.returnJSON(w, buildAppManifest(a.p.GetURL()))
+}
@@ -0,0 +1,26 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildAppManifest(t *testing.T) {
+	manifest := buildAppManifest("https://mattermost.example.com/plugins/com.mattermost.msteams-sync")
+
+	assert.Equal(t, []string{"https://mattermost.example.com/plugins/com.mattermost.msteams-sync/oauth-redirect"}, manifest.Web.RedirectURIs)
+	assert.Equal(t, "AzureADMyOrg", manifest.SignInAudience)
+
+	require.Len(t, manifest.RequiredResourceAccess, 1)
+	graphAccess := manifest.RequiredResourceAccess[0]
+	assert.Equal(t, microsoftGraphAppID, graphAccess.ResourceAppID)
+
+	expectedPermissions := getExpectedPermissions()
+	require.Len(t, graphAccess.ResourceAccess, len(expectedPermissions))
+	for i, permission := range expectedPermissions {
+		assert.Equal(t, permission.ResourceAccess.ID, graphAccess.ResourceAccess[i].ID)
+		assert.Equal(t, permission.ResourceAccess.Type, graphAccess.ResourceAccess[i].Type)
+	}
+}
@@ -5,11 +5,14 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"math"
 	"mime"
 	"net/http"
 	"net/url"
+	"path"
 	"runtime/debug"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/mattermost/mattermost-plugin-msteams/server/metrics"
@@ -19,6 +22,35 @@ import (
 	"github.com/mattermost/mattermost/server/v8/channels/app/imaging"
 )
 
+const (
+	contentTypeAudioCard = "application/vnd.microsoft.card.audio"
+	contentTypeVideoCard = "application/vnd.microsoft.card.video"
+
+	// maxDownscaledImageDecodedBytes bounds the memory needed to decode an image to downscale,
+	// at decodedImageBytesPerPixel, larger images being skipped.
+	maxDownscaledImageDecodedBytes = 256 * 1024 * 1024
+	decodedImageBytesPerPixel      = 4
+	downscaledImageJPEGQuality     = 90
+)
+
+// getImageDecoder returns the decoder shared by the activity workers to downscale images. It
+// decodes a single image at a time, so the memory needed stays bounded however many workers run.
+var getImageDecoder = sync.OnceValues(func() (*imaging.Decoder, error) {
+	return imaging.NewDecoder(imaging.DecoderOptions{ConcurrencyLevel: 1})
+})
+
+// mediaClipExtensions maps raw media content types to file extensions for clips whose URL
+// doesn't otherwise reveal one, as is the case for hosted contents.
+var mediaClipExtensions = map[string]string{
+	"audio/mp4":       ".m4a",
+	"audio/mpeg":      ".mp3",
+	"audio/ogg":       ".ogg",
+	"audio/wav":       ".wav",
+	"video/mp4":       ".mp4",
+	"video/quicktime": ".mov",
+	"video/webm":      ".webm",
+}
+
 func GetResourceIDsFromURL(weburl string) (*clientmodels.ActivityIds, error) {
 	parsedURL, err := url.Parse(weburl)
 	if err != nil {
@@ -58,19 +90,29 @@ func (ah *ActivityHandler) handleDownloadFile(weburl string, client msteams.Clie
 	return client.GetHostedFileContent(activityIDs)
 }
 
-func (ah *ActivityHandler) ProcessAndUploadFileToMM(attachmentData []byte, attachmentName, channelID string) (fileInfoID string, resolutionErrorFound bool) {
+func (ah *ActivityHandler) ProcessAndUploadFileToMM(logger *activityLogger, attachmentData []byte, attachmentName, channelID string) (fileInfoID string, resolutionErrorFound bool) {
 	contentType := http.DetectContentType(attachmentData)
 	if strings.HasPrefix(contentType, "image") && contentType != "image/svg+xml" {
 		width, height, imageErr := imaging.GetDimensions(bytes.NewReader(attachmentData))
 		if imageErr != nil {
-			ah.plugin.GetAPI().LogWarn("failed to get image dimensions", "error", imageErr.Error())
+			logger.LogWarn("failed to get image dimensions", "error", imageErr.Error())
 			return "", false
 		}
 
 		imageRes := int64(width) * int64(height)
-		if imageRes > *ah.plugin.GetAPI().GetConfig().FileSettings.MaxImageResolution {
-			ah.plugin.GetAPI().LogWarn("image resolution is too high")
-			return "", true
+		maxImageRes := *ah.plugin.GetAPI().GetConfig().FileSettings.MaxImageResolution
+		if imageRes > maxImageRes {
+			if !canDownscaleImage(contentType, imageRes) {
+				logger.LogWarn("image resolution is too high")
+				return "", true
+			}
+
+			downscaledData, downscaleErr := downscaleImage(attachmentData, maxImageRes)
+			if downscaleErr != nil {
+				logger.LogWarn("failed to downscale image with a resolution that is too high", "error", downscaleErr.Error())
+				return "", true
+			}
+			attachmentData = downscaledData
 		}
 	}
 
@@ -78,7 +120,7 @@ func (ah *ActivityHandler) ProcessAndUploadFileToMM(attachmentData []byte, attac
 		extension := ""
 		extensions, extensionErr := mime.ExtensionsByType(contentType)
 		if extensionErr != nil {
-			ah.plugin.GetAPI().LogWarn("Unable to get the extensions using content type", "error", extensionErr.Error())
+			logger.LogWarn("Unable to get the extensions using content type", "error", extensionErr.Error())
 		} else if len(extensions) > 0 {
 			extension = extensions[0]
 		}
@@ -87,16 +129,45 @@ func (ah *ActivityHandler) ProcessAndUploadFileToMM(attachmentData []byte, attac
 
 	fileInfo, appErr := ah.plugin.GetAPI().UploadFile(attachmentData, channelID, attachmentName)
 	if appErr != nil {
-		ah.plugin.GetAPI().LogWarn("upload file to Mattermost failed", "filename", attachmentName, "error", appErr.Message)
+		logger.LogWarn("upload file to Mattermost failed", "filename", attachmentName, "error", appErr.Message)
 		return "", false
 	}
 
 	return fileInfo.Id, false
 }
 
-func (ah *ActivityHandler) handleAttachments(channelID, userID, text string, msg *clientmodels.Message, chat *clientmodels.Chat, existingFileIDs []string) (string, model.StringArray, string, int, bool) {
+// hostedImageFileName names an image embedded in a message body after its content type, e.g.
+// image.png.
+func hostedImageFileName(data []byte) string {
+	contentType := http.DetectContentType(data)
+	switch contentType {
+	case "image/png":
+		return "image.png"
+	case "image/jpeg":
+		return "image.jpg"
+	case "image/gif":
+		return "image.gif"
+	case "image/webp":
+		return "image.webp"
+	}
+
+	if strings.HasPrefix(contentType, "image/") {
+		if extensions, err := mime.ExtensionsByType(contentType); err == nil && len(extensions) > 0 {
+			return "image" + extensions[0]
+		}
+	}
+
+	return "image"
+}
+
+// handleAttachments converts the attachments of a message, returning along with the converted
+// text and files whether any file failed in a way a replay could fix, e.g. a failed download from
+// MS Teams. Files skipped by policy, such as those too large or of a blocked type, aren't errors.
+func (ah *ActivityHandler) handleAttachments(logger *activityLogger, channelID, userID, text string, msg *clientmodels.Message, chat *clientmodels.Chat, existingFileIDs []string) (string, model.StringArray, string, int, bool) {
 	attachments := []string{}
-	newText := text
+	// Remove the attachment tags from the text, including those of attachments already taken out
+	// of the message, e.g. rendered as link previews.
+	newText := attachRE.ReplaceAllString(text, "")
 	parentID := ""
 	countNonFileAttachments := 0
 	countFileAttachments := 0
@@ -114,7 +185,7 @@ func (ah *ActivityHandler) handleAttachments(channelID, userID, text string, msg
 
 	errorFound := false
 	if client == nil {
-		ah.plugin.GetAPI().LogWarn("Unable to get the client")
+		logger.LogWarn("Unable to get the client")
 		return "", nil, "", 0, errorFound
 	}
 
@@ -133,32 +204,55 @@ func (ah *ActivityHandler) handleAttachments(channelID, userID, text string, msg
 		}
 	}
 
+	configuration := ah.plugin.getConfiguration()
 	skippedFileAttachments := 0
 	for _, a := range msg.Attachments {
-		// remove the attachment tags from the text
-		newText = attachRE.ReplaceAllString(newText, "")
-
 		// handle a code snippet (code block)
 		if a.ContentType == "application/vnd.microsoft.card.codesnippet" {
-			newText = ah.handleCodeSnippet(client, a, newText)
+			newText = ah.handleCodeSnippet(logger, client, a, newText)
 			countNonFileAttachments++
 			continue
 		}
 
 		// handle a message reference (reply)
 		if a.ContentType == "messageReference" {
-			parentID = ah.handleMessageReference(a, msg.ChatID+msg.ChannelID)
+			parentID = ah.handleMessageReference(logger, a, msg.ChatID+msg.ChannelID)
+			countNonFileAttachments++
+			continue
+		}
+
+		// handle an audio or video clip, resolving the media itself as a file reference
+		isMediaClip := isMediaClipContentType(a.ContentType)
+		if isMediaClip {
+			var err error
+			if a, err = resolveMediaClip(a); err != nil {
+				logger.LogWarn("failed to resolve media clip", "content_type", a.ContentType, "error", err.Error())
+				countNonFileAttachments++
+				continue
+			}
+		} else if a.ContentType != "reference" && a.ContentType != contentTypeHostedImage {
+			// The rest of the code assumes a (file) reference: ignore other content types until we explicitly support them.
+			logger.LogWarn("ignored attachment content type", "filename", a.Name, "content_type", a.ContentType)
 			countNonFileAttachments++
 			continue
 		}
 
-		// The rest of the code assumes a (file) reference: ignore other content types until we explicitly support them.
-		if a.ContentType != "reference" {
-			ah.plugin.GetAPI().LogWarn("ignored attachment content type", "filename", a.Name, "content_type", a.ContentType)
+		// Check the file type policy before reusing, downloading or streaming the file, guessing the
+		// content type from the file name: only files downloaded completely are sniffed below.
+		if configuration.isBlockedFileType(a.Name, mime.TypeByExtension(path.Ext(a.Name))) {
+			logger.LogInfo("blocking file from MS Teams by file type policy", "filename", a.Name)
+			newText = appendBlockedFileNotice(newText, a.Name)
+			ah.plugin.GetMetrics().ObserveFile(metrics.ActionCreated, metrics.ActionSourceMSTeams, metrics.DiscardedReasonBlockedFileType, isDirectOrGroupMessage)
 			countNonFileAttachments++
 			continue
 		}
 
+		// Files are neither downloaded, scanned nor uploaded when only observing.
+		if configuration.ObserveOnly {
+			ah.plugin.GetMetrics().ObserveFile(metrics.ActionCreated, metrics.ActionSourceMSTeams, metrics.DiscardedReasonObserveOnly, isDirectOrGroupMessage)
+			continue
+		}
+
 		fileInfoID := fileNames[a.Name]
 		if fileInfoID != "" {
 			attachments = append(attachments, fileInfoID)
@@ -170,48 +264,85 @@ func (ah *ActivityHandler) handleAttachments(channelID, userID, text string, msg
 		var err error
 		var fileSize int64
 		downloadURL := ""
-		if strings.Contains(a.ContentURL, hostedContentsStr) && strings.HasSuffix(a.ContentURL, "$value") {
+		isHostedContent := strings.Contains(a.ContentURL, hostedContentsStr) && strings.HasSuffix(a.ContentURL, "$value")
+		if isHostedContent {
+			// The size of hosted contents is only known once downloaded.
 			attachmentData, err = ah.handleDownloadFile(a.ContentURL, client)
 			if err != nil {
-				ah.plugin.GetAPI().LogWarn("failed to download the file", "filename", a.Name, "error", err.Error())
+				logger.LogWarn("failed to download the file", "filename", a.Name, "error", err.Error())
 				ah.plugin.GetMetrics().ObserveFile(metrics.ActionCreated, metrics.ActionSourceMSTeams, metrics.DiscardedReasonUnableToGetTeamsData, isDirectOrGroupMessage)
+				errorFound = true
 				skippedFileAttachments++
 				continue
 			}
+			fileSize = int64(len(attachmentData))
 		} else {
 			fileSize, downloadURL, err = client.GetFileSizeAndDownloadURL(a.ContentURL)
 			if err != nil {
-				ah.plugin.GetAPI().LogWarn("failed to get file size and download URL", "error", err.Error())
+				logger.LogWarn("failed to get file size and download URL", "error", err.Error())
 				ah.plugin.GetMetrics().ObserveFile(metrics.ActionCreated, metrics.ActionSourceMSTeams, metrics.DiscardedReasonUnableToGetTeamsData, isDirectOrGroupMessage)
+				errorFound = true
 				skippedFileAttachments++
 				continue
 			}
+		}
 
-			fileSizeAllowed := *ah.plugin.GetAPI().GetConfig().FileSettings.MaxFileSize
-			if fileSize > fileSizeAllowed {
-				ah.plugin.GetAPI().LogWarn("skipping file download from MS Teams because the file size is greater than the allowed size")
+		fileSizeAllowed := *ah.plugin.GetAPI().GetConfig().FileSettings.MaxFileSize
+		if fileSize > fileSizeAllowed && isMediaClip {
+			// Clips too large to attach are still reachable in Teams, so link to the message instead.
+			logger.LogInfo("linking to media clip from MS Teams because the file size is greater than the allowed size", "filename", a.Name)
+			newText = appendMediaClipLink(newText, a.Name, teamsMessageLink(msg.ChatID, msg.ID, ah.plugin.GetTenantID()))
+			ah.plugin.GetMetrics().ObserveFile(metrics.ActionCreated, metrics.ActionSourceMSTeams, metrics.DiscardedReasonMaxFileSizeExceeded, isDirectOrGroupMessage)
+			countNonFileAttachments++
+			continue
+		} else if fileSize > fileSizeAllowed {
+			// Not an error: the file would be skipped again if the message were replayed.
+			logger.LogWarn("skipping file download from MS Teams because the file size is greater than the allowed size")
+			ah.plugin.GetMetrics().ObserveFile(metrics.ActionCreated, metrics.ActionSourceMSTeams, metrics.DiscardedReasonMaxFileSizeExceeded, isDirectOrGroupMessage)
+			skippedFileAttachments++
+			continue
+		}
+
+		// If the file size is less than or equal to the configurable value, then download the file directly instead of streaming.
+		if !isHostedContent && fileSize <= int64(ah.plugin.GetMaxSizeForCompleteDownload()*1024*1024) {
+			attachmentData, err = client.GetFileContent(downloadURL)
+			if err != nil {
+				logger.LogWarn("failed to get file content", "error", err.Error())
+				ah.plugin.GetMetrics().ObserveFile(metrics.ActionCreated, metrics.ActionSourceMSTeams, metrics.DiscardedReasonUnableToGetTeamsData, isDirectOrGroupMessage)
 				errorFound = true
-				ah.plugin.GetMetrics().ObserveFile(metrics.ActionCreated, metrics.ActionSourceMSTeams, metrics.DiscardedReasonMaxFileSizeExceeded, isDirectOrGroupMessage)
 				skippedFileAttachments++
 				continue
 			}
+		}
 
-			// If the file size is less than or equal to the configurable value, then download the file directly instead of streaming
-			if fileSize <= int64(ah.plugin.GetMaxSizeForCompleteDownload()*1024*1024) {
-				attachmentData, err = client.GetFileContent(downloadURL)
-				if err != nil {
-					ah.plugin.GetAPI().LogWarn("failed to get file content", "error", err.Error())
-					ah.plugin.GetMetrics().ObserveFile(metrics.ActionCreated, metrics.ActionSourceMSTeams, metrics.DiscardedReasonUnableToGetTeamsData, isDirectOrGroupMessage)
-					skippedFileAttachments++
-					continue
-				}
-			}
+		// Images embedded in the message body, including stickers, have no name of their own.
+		if a.Name == "" && attachmentData != nil {
+			a.Name = hostedImageFileName(attachmentData)
+		}
+
+		// Files downloaded completely can also be checked against blocked content types.
+		if attachmentData != nil && configuration.isBlockedFileType(a.Name, http.DetectContentType(attachmentData)) {
+			logger.LogInfo("blocking file from MS Teams by content type policy", "filename", a.Name)
+			newText = appendBlockedFileNotice(newText, a.Name)
+			ah.plugin.GetMetrics().ObserveFile(metrics.ActionCreated, metrics.ActionSourceMSTeams, metrics.DiscardedReasonBlockedFileType, isDirectOrGroupMessage)
+			countNonFileAttachments++
+			continue
+		}
+
+		if attachmentData != nil && !ah.checkFileScan(logger, a.Name, bytes.NewReader(attachmentData)) ||
+			attachmentData == nil && configuration.FileScanURL != "" && !ah.checkStreamedFileScan(logger, a.Name, downloadURL, client) {
+			newText = appendQuarantinedFileNotice(newText, a.Name)
+			ah.plugin.GetMetrics().ObserveFile(metrics.ActionCreated, metrics.ActionSourceMSTeams, metrics.DiscardedReasonFileQuarantined, isDirectOrGroupMessage)
+			countNonFileAttachments++
+			continue
 		}
 
 		if attachmentData != nil {
-			fileInfoID, errorFound = ah.ProcessAndUploadFileToMM(attachmentData, a.Name, channelID)
+			var uploadErrorFound bool
+			fileInfoID, uploadErrorFound = ah.ProcessAndUploadFileToMM(logger, attachmentData, a.Name, channelID)
+			errorFound = errorFound || uploadErrorFound
 		} else {
-			fileInfoID = ah.GetFileFromTeamsAndUploadToMM(downloadURL, client, &model.UploadSession{
+			fileInfoID = ah.GetFileFromTeamsAndUploadToMM(logger, downloadURL, client, &model.UploadSession{
 				Id:        model.NewId(),
 				Type:      model.UploadTypeAttachment,
 				ChannelId: channelID,
@@ -241,11 +372,191 @@ func (ah *ActivityHandler) handleAttachments(channelID, userID, text string, msg
 	return newText, attachments, parentID, skippedFileAttachments, errorFound
 }
 
-func (ah *ActivityHandler) GetFileFromTeamsAndUploadToMM(downloadURL string, client msteams.Client, us *model.UploadSession) string {
+// withholdFileAttachments removes the attachments delivered as files, returning the remaining
+// attachments and the number of files withheld.
+func withholdFileAttachments(attachments []clientmodels.Attachment) ([]clientmodels.Attachment, int) {
+	var remaining []clientmodels.Attachment
+	withheld := 0
+	for _, a := range attachments {
+		if a.ContentType == "reference" || a.ContentType == contentTypeHostedImage || isMediaClipContentType(a.ContentType) {
+			withheld++
+			continue
+		}
+		remaining = append(remaining, a)
+	}
+
+	return remaining, withheld
+}
+
+// isMediaClipContentType returns true if the attachment content type describes an audio or video
+// clip, either as a media card or as raw media.
+func isMediaClipContentType(contentType string) bool {
+	switch {
+	case contentType == contentTypeAudioCard, contentType == contentTypeVideoCard:
+		return true
+	case strings.HasPrefix(contentType, "audio/"), strings.HasPrefix(contentType, "video/"):
+		return true
+	}
+
+	return false
+}
+
+// resolveMediaClip returns a copy of the given audio or video clip attachment with the content URL
+// pointing at the media itself and a usable file name.
+func resolveMediaClip(a clientmodels.Attachment) (clientmodels.Attachment, error) {
+	clipType := "Audio clip"
+	if a.ContentType == contentTypeVideoCard || strings.HasPrefix(a.ContentType, "video/") {
+		clipType = "Video clip"
+	}
+
+	if a.ContentType == contentTypeAudioCard || a.ContentType == contentTypeVideoCard {
+		var content struct {
+			Title string `json:"title"`
+			Media []struct {
+				URL string `json:"url"`
+			} `json:"media"`
+		}
+		if err := json.Unmarshal([]byte(a.Content), &content); err != nil {
+			return a, fmt.Errorf("failed to unmarshal media card: %w", err)
+		}
+		if len(content.Media) == 0 || content.Media[0].URL == "" {
+			return a, fmt.Errorf("media card has no media url")
+		}
+
+		a.ContentURL = content.Media[0].URL
+		if a.Name == "" && content.Title != "" {
+			clipType = content.Title
+		}
+	}
+
+	if a.ContentURL == "" {
+		return a, fmt.Errorf("media clip has no content url")
+	}
+
+	if a.Name == "" {
+		extension := path.Ext(strings.TrimSuffix(a.ContentURL, "/$value"))
+		if extension == "" {
+			extension = mediaClipExtensions[a.ContentType]
+		}
+		a.Name = clipType + extension
+	}
+
+	return a, nil
+}
+
+// appendMediaClipLink appends a link to the MS Teams message with an audio or video clip that could
+// not be attached.
+func appendMediaClipLink(text, name, messageLink string) string {
+	link := fmt.Sprintf("[%s](%s)", name, messageLink)
+	if strings.TrimSpace(text) == "" {
+		return link
+	}
+
+	return text + "\n" + link
+}
+
+// canDownscaleImage returns true if an image exceeding the maximum resolution can be downscaled to
+// fit, rather than being skipped. Only static formats are supported, and images too large to
+// decode within maxDownscaledImageDecodedBytes are still skipped.
+func canDownscaleImage(contentType string, imageRes int64) bool {
+	if contentType != "image/jpeg" && contentType != "image/png" {
+		return false
+	}
+
+	return imageRes <= maxDownscaledImageDecodedBytes/decodedImageBytesPerPixel
+}
+
+// downscaleImage resizes the given JPEG or PNG image to fit within the maximum resolution,
+// preserving its aspect ratio and format. Any EXIF orientation is applied first, since it is
+// discarded when the image is encoded again.
+func downscaleImage(data []byte, maxImageRes int64) ([]byte, error) {
+	decoder, err := getImageDecoder()
+	if err != nil {
+		return nil, err
+	}
+
+	// The decoder is held until the image is encoded again, since the decoded image and its
+	// resized copies are what take up memory.
+	img, format, release, err := decoder.DecodeMemBounded(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode image: %w", err)
+	}
+	defer release()
+
+	// Images without EXIF data report an upright orientation alongside the error.
+	orientation, _ := imaging.GetImageOrientation(bytes.NewReader(data))
+	img = imaging.MakeImageUpright(img, orientation)
+
+	// Shave a pixel off the target width to absorb rounding of the scaled height.
+	width, height := img.Bounds().Dx(), img.Bounds().Dy()
+	scale := math.Sqrt(float64(maxImageRes) / (float64(width) * float64(height)))
+	targetWidth := int(float64(width)*scale) - 1
+	if targetWidth < 1 {
+		return nil, fmt.Errorf("image of %dx%d too narrow to fit the maximum resolution", width, height)
+	}
+	img = imaging.GeneratePreview(img, targetWidth)
+
+	encoder, err := imaging.NewEncoder(imaging.EncoderOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	if format == "png" {
+		err = encoder.EncodePNG(&buf, img)
+	} else {
+		err = encoder.EncodeJPEG(&buf, img, downscaledImageJPEGQuality)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode image: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// isBlockedFileType returns true if the given file name or content type matches an extension or
+// MIME type in the blocked file types setting. An empty content type only checks the extension.
+func (c *configuration) isBlockedFileType(fileName, contentType string) bool {
+	if c.BlockedFileTypes == "" {
+		return false
+	}
+
+	extension := strings.ToLower(strings.TrimPrefix(path.Ext(fileName), "."))
+	contentType, _, _ = strings.Cut(strings.ToLower(contentType), ";")
+	contentType = strings.TrimSpace(contentType)
+
+	for _, blocked := range strings.Split(c.BlockedFileTypes, ",") {
+		blocked = strings.ToLower(strings.TrimSpace(blocked))
+		switch {
+		case blocked == "":
+			continue
+		case strings.Contains(blocked, "/"):
+			if blocked == contentType {
+				return true
+			}
+		case extension != "" && strings.TrimPrefix(blocked, ".") == extension:
+			return true
+		}
+	}
+
+	return false
+}
+
+// appendBlockedFileNotice appends a notice in place of a file blocked by policy.
+func appendBlockedFileNotice(text, fileName string) string {
+	notice := fmt.Sprintf("_The file %q was blocked by your system administrator's file type policy._", fileName)
+	if strings.TrimSpace(text) == "" {
+		return notice
+	}
+
+	return text + "\n" + notice
+}
+
+func (ah *ActivityHandler) GetFileFromTeamsAndUploadToMM(logger *activityLogger, downloadURL string, client msteams.Client, us *model.UploadSession) string {
 	pipeReader, pipeWriter := io.Pipe()
 	uploadSession, err := ah.plugin.GetAPI().CreateUploadSession(us)
 	if err != nil {
-		ah.plugin.GetAPI().LogWarn("Unable to create an upload session in Mattermost", "error", err.Error())
+		logger.LogWarn("Unable to create an upload session in Mattermost", "error", err.Error())
 		return ""
 	}
 
@@ -253,7 +564,7 @@ func (ah *ActivityHandler) GetFileFromTeamsAndUploadToMM(downloadURL string, cli
 		defer func() {
 			if r := recover(); r != nil {
 				ah.plugin.GetMetrics().ObserveGoroutineFailure()
-				ah.plugin.GetAPI().LogError("Recovering from panic", "panic", r, "stack", string(debug.Stack()))
+				logger.LogError("Recovering from panic", "panic", r, "stack", string(debug.Stack()))
 			}
 		}()
 
@@ -261,50 +572,50 @@ func (ah *ActivityHandler) GetFileFromTeamsAndUploadToMM(downloadURL string, cli
 	}()
 	fileInfo, err := ah.plugin.GetAPI().UploadData(uploadSession, pipeReader)
 	if err != nil {
-		ah.plugin.GetAPI().LogWarn("Unable to upload data in the upload session", "upload_session_id", uploadSession.Id, "error", err.Error())
+		logger.LogWarn("Unable to upload data in the upload session", "upload_session_id", uploadSession.Id, "error", err.Error())
 		return ""
 	}
 
 	return fileInfo.Id
 }
 
-func (ah *ActivityHandler) handleCodeSnippet(client msteams.Client, attach clientmodels.Attachment, text string) string {
+func (ah *ActivityHandler) handleCodeSnippet(logger *activityLogger, client msteams.Client, attach clientmodels.Attachment, text string) string {
 	var content struct {
 		Language       string `json:"language"`
 		CodeSnippetURL string `json:"codeSnippetUrl"`
 	}
 	err := json.Unmarshal([]byte(attach.Content), &content)
 	if err != nil {
-		ah.plugin.GetAPI().LogWarn("failed to unmarshal codesnippet", "error", err.Error())
+		logger.LogWarn("failed to unmarshal codesnippet", "error", err.Error())
 		return text
 	}
 	s := strings.Split(content.CodeSnippetURL, "/")
 	if !strings.Contains(content.CodeSnippetURL, "chats") && !strings.Contains(content.CodeSnippetURL, "channels") {
-		ah.plugin.GetAPI().LogWarn("invalid codesnippetURL", "URL", content.CodeSnippetURL)
+		logger.LogWarn("invalid codesnippetURL", "URL", content.CodeSnippetURL)
 		return text
 	}
 
 	if (strings.Contains(content.CodeSnippetURL, "chats") && len(s) != 11) || (strings.Contains(content.CodeSnippetURL, "channels") && len(s) != 13 && len(s) != 15) {
-		ah.plugin.GetAPI().LogWarn("codesnippetURL has unexpected size", "URL", content.CodeSnippetURL)
+		logger.LogWarn("codesnippetURL has unexpected size", "URL", content.CodeSnippetURL)
 		return text
 	}
 
 	codeSnippetText, err := client.GetCodeSnippet(content.CodeSnippetURL)
 	if err != nil {
-		ah.plugin.GetAPI().LogWarn("retrieving snippet content failed", "error", err)
+		logger.LogWarn("retrieving snippet content failed", "error", err)
 		return text
 	}
 	newText := text + "\n```" + content.Language + "\n" + codeSnippetText + "\n```\n"
 	return newText
 }
 
-func (ah *ActivityHandler) handleMessageReference(attach clientmodels.Attachment, chatOrChannelID string) string {
+func (ah *ActivityHandler) handleMessageReference(logger *activityLogger, attach clientmodels.Attachment, chatOrChannelID string) string {
 	var content struct {
 		MessageID string `json:"messageId"`
 	}
 	err := json.Unmarshal([]byte(attach.Content), &content)
 	if err != nil {
-		ah.plugin.GetAPI().LogWarn("failed to unmarshal attachment content", "error", err)
+		logger.LogWarn("failed to unmarshal attachment content", "error", err)
 		return ""
 	}
 	postInfo, err := ah.plugin.GetStore().GetPostInfoByMSTeamsID(chatOrChannelID, content.MessageID)
@@ -4,6 +4,9 @@ import (
 	"bytes"
 	"errors"
 	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
 	"io"
 	"testing"
 	"time"
@@ -12,6 +15,7 @@ import (
 	"github.com/mattermost/mattermost-plugin-msteams/server/store/storemodels"
 	"github.com/mattermost/mattermost/server/public/model"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 )
 
@@ -28,7 +32,7 @@ func TestHandleCodeSnippet(t *testing.T) {
 		message := "message"
 
 		expectedOutput := "message"
-		actualOutput := th.p.activityHandler.handleCodeSnippet(th.appClientMock, attachment, message)
+		actualOutput := th.p.activityHandler.handleCodeSnippet(th.p.activityHandler.newActivityLogger(model.NewId()), th.appClientMock, attachment, message)
 		assert.Equal(t, actualOutput, expectedOutput)
 	})
 
@@ -42,7 +46,7 @@ func TestHandleCodeSnippet(t *testing.T) {
 		message := "message"
 
 		expectedOutput := "message"
-		actualOutput := th.p.activityHandler.handleCodeSnippet(th.appClientMock, attachment, message)
+		actualOutput := th.p.activityHandler.handleCodeSnippet(th.p.activityHandler.newActivityLogger(model.NewId()), th.appClientMock, attachment, message)
 		assert.Equal(t, actualOutput, expectedOutput)
 	})
 
@@ -58,7 +62,7 @@ func TestHandleCodeSnippet(t *testing.T) {
 		th.appClientMock.On("GetCodeSnippet", "https://example.com/version/teams/mock-team-id/channels/mock-channel-id/messages/mock-message-id/hostedContents/mock-content-id/$value").Return("", errors.New("Error while retrieving code snippet"))
 
 		expectedOutput := "message"
-		actualOutput := th.p.activityHandler.handleCodeSnippet(th.appClientMock, attachment, message)
+		actualOutput := th.p.activityHandler.handleCodeSnippet(th.p.activityHandler.newActivityLogger(model.NewId()), th.appClientMock, attachment, message)
 		assert.Equal(t, actualOutput, expectedOutput)
 	})
 
@@ -74,7 +78,7 @@ func TestHandleCodeSnippet(t *testing.T) {
 		th.appClientMock.On("GetCodeSnippet", "https://example.com/version/teams/mock-team-id/channels/mock-channel-id/messages/mock-message-id/hostedContents/mock-content-id/$value").Return("snippet content", nil)
 
 		expectedOutput := "message\n```go\nsnippet content\n```\n"
-		actualOutput := th.p.activityHandler.handleCodeSnippet(th.appClientMock, attachment, message)
+		actualOutput := th.p.activityHandler.handleCodeSnippet(th.p.activityHandler.newActivityLogger(model.NewId()), th.appClientMock, attachment, message)
 		assert.Equal(t, actualOutput, expectedOutput)
 	})
 
@@ -90,7 +94,7 @@ func TestHandleCodeSnippet(t *testing.T) {
 		th.appClientMock.On("GetCodeSnippet", "https://example.com/version/chats/mock-chat-id/messages/mock-message-id/hostedContents/mock-content-id/$value").Return("snippet content", nil)
 
 		expectedOutput := "message\n```go\nsnippet content\n```\n"
-		actualOutput := th.p.activityHandler.handleCodeSnippet(th.appClientMock, attachment, message)
+		actualOutput := th.p.activityHandler.handleCodeSnippet(th.p.activityHandler.newActivityLogger(model.NewId()), th.appClientMock, attachment, message)
 		assert.Equal(t, actualOutput, expectedOutput)
 	})
 }
@@ -107,7 +111,7 @@ func TestHandleMessageReference(t *testing.T) {
 			Content:     "Invalid JSON",
 		}
 
-		actualParentID := th.p.activityHandler.handleMessageReference(attachment, chatOrChannelID)
+		actualParentID := th.p.activityHandler.handleMessageReference(th.p.activityHandler.newActivityLogger(model.NewId()), attachment, chatOrChannelID)
 		assert.Empty(t, actualParentID)
 	})
 
@@ -120,7 +124,7 @@ func TestHandleMessageReference(t *testing.T) {
 			Content:     `{"messageId": "` + messageID + `"}`,
 		}
 
-		actualParentID := th.p.activityHandler.handleMessageReference(attachment, chatOrChannelID)
+		actualParentID := th.p.activityHandler.handleMessageReference(th.p.activityHandler.newActivityLogger(model.NewId()), attachment, chatOrChannelID)
 		assert.Empty(t, actualParentID)
 	})
 
@@ -156,7 +160,7 @@ func TestHandleMessageReference(t *testing.T) {
 
 		expectedParentID := post.Id
 
-		actualParentID := th.p.activityHandler.handleMessageReference(attachment, chatOrChannelID)
+		actualParentID := th.p.activityHandler.handleMessageReference(th.p.activityHandler.newActivityLogger(model.NewId()), attachment, chatOrChannelID)
 		assert.Equal(t, expectedParentID, actualParentID)
 	})
 
@@ -201,7 +205,7 @@ func TestHandleMessageReference(t *testing.T) {
 
 		expectedParentID := rootPost.Id
 
-		actualParentID := th.p.activityHandler.handleMessageReference(attachment, chatOrChannelID)
+		actualParentID := th.p.activityHandler.handleMessageReference(th.p.activityHandler.newActivityLogger(model.NewId()), attachment, chatOrChannelID)
 		assert.Equal(t, expectedParentID, actualParentID)
 	})
 }
@@ -249,6 +253,7 @@ func TestHandleAttachments(t *testing.T) {
 		th.appClientMock.On("GetFileContent", "mockDownloadURL").Return([]byte("abcde"), nil).Once()
 
 		newText, attachmentIDs, parentID, skippedFileAttachments, errorsFound := th.p.activityHandler.handleAttachments(
+			th.p.activityHandler.newActivityLogger(model.NewId()),
 			channel.Id,
 			user.Id,
 			text,
@@ -292,6 +297,7 @@ func TestHandleAttachments(t *testing.T) {
 		}
 
 		newText, attachmentIDs, parentID, skippedFileAttachments, errorsFound := th.p.activityHandler.handleAttachments(
+			th.p.activityHandler.newActivityLogger(model.NewId()),
 			channel.Id,
 			user.Id,
 			text,
@@ -343,6 +349,7 @@ func TestHandleAttachments(t *testing.T) {
 		th.appClientMock.On("GetFileContent", "mockDownloadURL").Return([]byte("abcde"), nil).Times(3)
 
 		newText, attachmentIDs, parentID, skippedFileAttachments, errorsFound := th.p.activityHandler.handleAttachments(
+			th.p.activityHandler.newActivityLogger(model.NewId()),
 			channel.Id,
 			user.Id,
 			text,
@@ -401,6 +408,7 @@ func TestHandleAttachments(t *testing.T) {
 		th.appClientMock.On("GetFileContent", "mockDownloadURL").Return([]byte("abcde"), nil).Times(2)
 
 		newText, attachmentIDs, parentID, skippedFileAttachments, errorsFound := th.p.activityHandler.handleAttachments(
+			th.p.activityHandler.newActivityLogger(model.NewId()),
 			channel.Id,
 			user.Id,
 			text,
@@ -464,6 +472,7 @@ func TestHandleAttachments(t *testing.T) {
 		}
 
 		newText, attachmentIDs, parentID, skippedFileAttachments, errorsFound := th.p.activityHandler.handleAttachments(
+			th.p.activityHandler.newActivityLogger(model.NewId()),
 			channel.Id,
 			user.Id,
 			text,
@@ -482,6 +491,54 @@ func TestHandleAttachments(t *testing.T) {
 		assert.False(t, errorsFound)
 	})
 
+	t.Run("failed download not masked by a later file", func(t *testing.T) {
+		th.Reset(t)
+
+		user := th.SetupUser(t, team)
+		channel := th.SetupPublicChannel(t, team, WithMembers(user))
+
+		text := "message"
+		message := &clientmodels.Message{
+			Attachments: []clientmodels.Attachment{
+				{
+					Name:        "mock-name-1",
+					ContentType: "reference",
+					ContentURL:  "https://example.com/path/to/file1.png",
+				},
+				{
+					Name:        "mock-name-2",
+					ContentType: "reference",
+					ContentURL:  "https://example.com/path/to/file2.png",
+				},
+			},
+			ChatID:    model.NewId(),
+			ChannelID: model.NewId(),
+		}
+		chat := (*clientmodels.Chat)(nil)
+		existingFileIDs := []string{}
+
+		th.appClientMock.On("GetFileSizeAndDownloadURL", "https://example.com/path/to/file1.png").Return(int64(0), "", errors.New("unavailable")).Once()
+		th.appClientMock.On("GetFileSizeAndDownloadURL", "https://example.com/path/to/file2.png").Return(int64(5), "mockDownloadURL2", nil).Once()
+		th.appClientMock.On("GetFileContent", "mockDownloadURL2").Return([]byte("fghij"), nil).Once()
+
+		newText, attachmentIDs, parentID, skippedFileAttachments, errorsFound := th.p.activityHandler.handleAttachments(
+			th.p.activityHandler.newActivityLogger(model.NewId()),
+			channel.Id,
+			user.Id,
+			text,
+			message,
+			chat,
+			existingFileIDs,
+		)
+		assert.Equal(t, "message", newText)
+		if assert.Len(t, attachmentIDs, 1) {
+			assertFile(th, t, "mock-name-2", []byte("fghij"), attachmentIDs[0])
+		}
+		assert.Equal(t, "", parentID)
+		assert.Equal(t, 1, skippedFileAttachments)
+		assert.True(t, errorsFound)
+	})
+
 	t.Run("more than 10 attachments", func(t *testing.T) {
 		th.Reset(t)
 
@@ -562,6 +619,7 @@ func TestHandleAttachments(t *testing.T) {
 		th.appClientMock.On("GetFileContent", "mockDownloadURL").Return([]byte("abcde"), nil).Times(10)
 
 		newText, attachmentIDs, parentID, skippedFileAttachments, errorsFound := th.p.activityHandler.handleAttachments(
+			th.p.activityHandler.newActivityLogger(model.NewId()),
 			channel.Id,
 			user.Id,
 			text,
@@ -604,6 +662,7 @@ func TestHandleAttachments(t *testing.T) {
 		th.appClientMock.On("GetCodeSnippet", "https://example.com/version/chats/mock-chat-id/messages/mock-message-id/hostedContents/mock-content-id/$value").Return("snippet content", nil)
 
 		newText, attachmentIDs, parentID, skippedFileAttachments, errorsFound := th.p.activityHandler.handleAttachments(
+			th.p.activityHandler.newActivityLogger(model.NewId()),
 			channel.Id,
 			user.Id,
 			text,
@@ -674,6 +733,7 @@ snippet content
 		existingFileIDs := []string{}
 
 		newText, attachmentIDs, parentID, skippedFileAttachments, errorsFound := th.p.activityHandler.handleAttachments(
+			th.p.activityHandler.newActivityLogger(model.NewId()),
 			channel.Id,
 			user.Id,
 			text,
@@ -689,6 +749,85 @@ snippet content
 		assert.False(t, errorsFound)
 	})
 
+	t.Run("video clip card", func(t *testing.T) {
+		th.Reset(t)
+
+		user := th.SetupUser(t, team)
+		channel := th.SetupPublicChannel(t, team, WithMembers(user))
+
+		text := "message"
+		message := &clientmodels.Message{
+			Attachments: []clientmodels.Attachment{
+				{
+					ContentType: "application/vnd.microsoft.card.video",
+					Content:     `{"title": "Standup", "media": [{"url": "https://example.com/path/to/clip.mp4"}]}`,
+				},
+			},
+			ChatID:    model.NewId(),
+			ChannelID: model.NewId(),
+		}
+		chat := (*clientmodels.Chat)(nil)
+		existingFileIDs := []string{}
+
+		th.appClientMock.On("GetFileSizeAndDownloadURL", "https://example.com/path/to/clip.mp4").Return(int64(5), "mockDownloadURL", nil).Once()
+		th.appClientMock.On("GetFileContent", "mockDownloadURL").Return([]byte("abcde"), nil).Once()
+
+		newText, attachmentIDs, parentID, skippedFileAttachments, errorsFound := th.p.activityHandler.handleAttachments(
+			th.p.activityHandler.newActivityLogger(model.NewId()),
+			channel.Id,
+			user.Id,
+			text,
+			message,
+			chat,
+			existingFileIDs,
+		)
+		assert.Equal(t, "message", newText)
+		if assert.Len(t, attachmentIDs, 1) {
+			assertFile(th, t, "Standup.mp4", []byte("abcde"), attachmentIDs[0])
+		}
+		assert.Equal(t, "", parentID)
+		assert.Equal(t, 0, skippedFileAttachments)
+		assert.False(t, errorsFound)
+	})
+
+	t.Run("audio clip exceeding max file size", func(t *testing.T) {
+		th.Reset(t)
+
+		user := th.SetupUser(t, team)
+		channel := th.SetupPublicChannel(t, team, WithMembers(user))
+
+		text := "message"
+		message := &clientmodels.Message{
+			Attachments: []clientmodels.Attachment{
+				{
+					ContentType: "audio/mp4",
+					ContentURL:  "https://example.com/path/to/clip.m4a",
+				},
+			},
+			ID:     "message-id",
+			ChatID: "chat-id",
+		}
+		chat := (*clientmodels.Chat)(nil)
+		existingFileIDs := []string{}
+
+		th.appClientMock.On("GetFileSizeAndDownloadURL", "https://example.com/path/to/clip.m4a").Return(int64(1<<40), "mockDownloadURL", nil).Once()
+
+		newText, attachmentIDs, parentID, skippedFileAttachments, errorsFound := th.p.activityHandler.handleAttachments(
+			th.p.activityHandler.newActivityLogger(model.NewId()),
+			channel.Id,
+			user.Id,
+			text,
+			message,
+			chat,
+			existingFileIDs,
+		)
+		assert.Equal(t, "message\n[Audio clip.m4a]("+teamsMessageLink("chat-id", "message-id", th.p.GetTenantID())+")", newText)
+		assert.Len(t, attachmentIDs, 0)
+		assert.Equal(t, "", parentID)
+		assert.Equal(t, 0, skippedFileAttachments)
+		assert.False(t, errorsFound)
+	})
+
 	t.Run("unsupported content type", func(t *testing.T) {
 		th.Reset(t)
 
@@ -712,6 +851,7 @@ snippet content
 		existingFileIDs := []string{}
 
 		newText, attachmentIDs, parentID, skippedFileAttachments, errorsFound := th.p.activityHandler.handleAttachments(
+			th.p.activityHandler.newActivityLogger(model.NewId()),
 			channel.Id,
 			user.Id,
 			text,
@@ -726,4 +866,361 @@ snippet content
 		assert.Equal(t, 0, skippedFileAttachments)
 		assert.False(t, errorsFound)
 	})
+
+	t.Run("hosted image attachment", func(t *testing.T) {
+		th.Reset(t)
+
+		user := th.SetupUser(t, team)
+		channel := th.SetupPublicChannel(t, team, WithMembers(user))
+
+		hostedURL := "https://graph.microsoft.com/v1.0/chats/chat-id/messages/message-id/hostedContents/content-id/$value"
+		message := &clientmodels.Message{
+			Attachments: []clientmodels.Attachment{
+				{
+					ContentType: contentTypeHostedImage,
+					ContentURL:  hostedURL,
+				},
+			},
+			ChatID: "chat-id",
+		}
+
+		th.appClientMock.On("GetHostedFileContent", &clientmodels.ActivityIds{
+			ChatID:           "chat-id",
+			MessageID:        "message-id",
+			HostedContentsID: "content-id",
+		}).Return([]byte("abcde"), nil).Once()
+
+		newText, attachmentIDs, parentID, skippedFileAttachments, errorsFound := th.p.activityHandler.handleAttachments(
+			th.p.activityHandler.newActivityLogger(model.NewId()),
+			channel.Id,
+			user.Id,
+			"",
+			message,
+			nil,
+			[]string{},
+		)
+
+		assert.Empty(t, newText)
+		assert.Len(t, attachmentIDs, 1)
+		assert.Empty(t, parentID)
+		assert.Equal(t, 0, skippedFileAttachments)
+		assert.False(t, errorsFound)
+	})
+
+	t.Run("blocked file type", func(t *testing.T) {
+		th.Reset(t)
+
+		th.setPluginConfigurationTemporarily(t, func(c *configuration) {
+			c.BlockedFileTypes = "exe, application/zip"
+		})
+
+		user := th.SetupUser(t, team)
+		channel := th.SetupPublicChannel(t, team, WithMembers(user))
+
+		text := "message"
+		message := &clientmodels.Message{
+			Attachments: []clientmodels.Attachment{
+				{
+					Name:        "setup.exe",
+					ContentType: "reference",
+					ContentURL:  "https://example.com/path/to/setup.exe",
+				},
+				{
+					Name:        "mock-name",
+					ContentType: "reference",
+					ContentURL:  "https://example.com/path/to/file.png",
+				},
+			},
+			ChatID:    model.NewId(),
+			ChannelID: model.NewId(),
+		}
+		chat := (*clientmodels.Chat)(nil)
+		existingFileIDs := []string{}
+
+		th.appClientMock.On("GetFileSizeAndDownloadURL", "https://example.com/path/to/file.png").Return(int64(5), "mockDownloadURL", nil).Once()
+		th.appClientMock.On("GetFileContent", "mockDownloadURL").Return([]byte("abcde"), nil).Once()
+
+		newText, attachmentIDs, parentID, skippedFileAttachments, errorsFound := th.p.activityHandler.handleAttachments(
+			th.p.activityHandler.newActivityLogger(model.NewId()),
+			channel.Id,
+			user.Id,
+			text,
+			message,
+			chat,
+			existingFileIDs,
+		)
+
+		assert.Equal(t, "message\n_The file \"setup.exe\" was blocked by your system administrator's file type policy._", newText)
+		if assert.Len(t, attachmentIDs, 1) {
+			assertFile(th, t, "mock-name", []byte("abcde"), attachmentIDs[0])
+		}
+		assert.Empty(t, parentID)
+		assert.Equal(t, 0, skippedFileAttachments)
+		assert.False(t, errorsFound)
+	})
+
+	t.Run("blocked content type checked before download", func(t *testing.T) {
+		th.Reset(t)
+
+		th.setPluginConfigurationTemporarily(t, func(c *configuration) {
+			c.BlockedFileTypes = "application/pdf"
+		})
+
+		user := th.SetupUser(t, team)
+		channel := th.SetupPublicChannel(t, team, WithMembers(user))
+
+		existingFileInfo, appErr := th.p.API.UploadFile([]byte("abcde"), channel.Id, "existing.pdf")
+		require.Nil(t, appErr)
+
+		message := &clientmodels.Message{
+			Attachments: []clientmodels.Attachment{
+				{
+					Name:        "existing.pdf",
+					ContentType: "reference",
+					ContentURL:  "https://example.com/path/to/existing.pdf",
+				},
+				{
+					Name:        "large.pdf",
+					ContentType: "reference",
+					ContentURL:  "https://example.com/path/to/large.pdf",
+				},
+			},
+			ChatID:    model.NewId(),
+			ChannelID: model.NewId(),
+		}
+
+		newText, attachmentIDs, _, skippedFileAttachments, errorsFound := th.p.activityHandler.handleAttachments(
+			th.p.activityHandler.newActivityLogger(model.NewId()),
+			channel.Id,
+			user.Id,
+			"",
+			message,
+			nil,
+			[]string{existingFileInfo.Id},
+		)
+
+		assert.Equal(t, "_The file \"existing.pdf\" was blocked by your system administrator's file type policy._\n_The file \"large.pdf\" was blocked by your system administrator's file type policy._", newText)
+		assert.Empty(t, attachmentIDs)
+		assert.Equal(t, 0, skippedFileAttachments)
+		assert.False(t, errorsFound)
+		th.appClientMock.AssertNotCalled(t, "GetFileSizeAndDownloadURL", mock.Anything)
+	})
+}
+
+func TestIsBlockedFileType(t *testing.T) {
+	for _, tc := range []struct {
+		Name             string
+		BlockedFileTypes string
+		FileName         string
+		ContentType      string
+		Expected         bool
+	}{
+		{
+			Name:     "nothing blocked",
+			FileName: "setup.exe",
+			Expected: false,
+		},
+		{
+			Name:             "blocked extension",
+			BlockedFileTypes: "exe, bat",
+			FileName:         "Setup.EXE",
+			Expected:         true,
+		},
+		{
+			Name:             "blocked extension with leading dot",
+			BlockedFileTypes: ".zip",
+			FileName:         "archive.zip",
+			Expected:         true,
+		},
+		{
+			Name:             "allowed extension",
+			BlockedFileTypes: "exe",
+			FileName:         "image.png",
+			Expected:         false,
+		},
+		{
+			Name:             "file without extension",
+			BlockedFileTypes: "exe",
+			FileName:         "README",
+			Expected:         false,
+		},
+		{
+			Name:             "blocked content type",
+			BlockedFileTypes: "application/zip",
+			FileName:         "archive",
+			ContentType:      "application/zip",
+			Expected:         true,
+		},
+		{
+			Name:             "blocked content type with parameters",
+			BlockedFileTypes: "text/html",
+			FileName:         "page",
+			ContentType:      "text/html; charset=utf-8",
+			Expected:         true,
+		},
+		{
+			Name:             "content type not checked by extension",
+			BlockedFileTypes: "application/zip",
+			FileName:         "archive.zip",
+			Expected:         false,
+		},
+	} {
+		t.Run(tc.Name, func(t *testing.T) {
+			c := &configuration{BlockedFileTypes: tc.BlockedFileTypes}
+			assert.Equal(t, tc.Expected, c.isBlockedFileType(tc.FileName, tc.ContentType))
+		})
+	}
+}
+
+func TestDownscaleImage(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 400, 200))
+
+	t.Run("png", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, png.Encode(&buf, img))
+
+		data, err := downscaleImage(buf.Bytes(), 20000)
+		require.NoError(t, err)
+
+		config, format, err := image.DecodeConfig(bytes.NewReader(data))
+		require.NoError(t, err)
+		assert.Equal(t, "png", format)
+		assert.LessOrEqual(t, config.Width*config.Height, 20000)
+		assert.InDelta(t, 2.0, float64(config.Width)/float64(config.Height), 0.05)
+	})
+
+	t.Run("jpeg", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, jpeg.Encode(&buf, img, nil))
+
+		data, err := downscaleImage(buf.Bytes(), 20000)
+		require.NoError(t, err)
+
+		config, format, err := image.DecodeConfig(bytes.NewReader(data))
+		require.NoError(t, err)
+		assert.Equal(t, "jpeg", format)
+		assert.LessOrEqual(t, config.Width*config.Height, 20000)
+	})
+
+	t.Run("invalid image", func(t *testing.T) {
+		_, err := downscaleImage([]byte("not an image"), 20000)
+		require.Error(t, err)
+	})
+
+	t.Run("image too narrow", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 2, 20000))))
+
+		_, err := downscaleImage(buf.Bytes(), 20000)
+		require.Error(t, err)
+	})
+}
+
+func TestCanDownscaleImage(t *testing.T) {
+	maxDownscaledImageRes := int64(maxDownscaledImageDecodedBytes / decodedImageBytesPerPixel)
+	assert.True(t, canDownscaleImage("image/jpeg", 200))
+	assert.True(t, canDownscaleImage("image/png", maxDownscaledImageRes))
+	assert.False(t, canDownscaleImage("image/png", maxDownscaledImageRes+1))
+	assert.False(t, canDownscaleImage("image/gif", 200))
+}
+
+func TestHostedImageFileName(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 2, 2))))
+
+	assert.Equal(t, "image.png", hostedImageFileName(buf.Bytes()))
+	assert.Equal(t, "image.gif", hostedImageFileName([]byte("GIF89a")))
+	assert.Equal(t, "image", hostedImageFileName([]byte{0x00, 0x01, 0x02}))
+}
+
+func TestResolveMediaClip(t *testing.T) {
+	for _, tc := range []struct {
+		Name              string
+		Attachment        clientmodels.Attachment
+		ExpectedName      string
+		ExpectedURL       string
+		ExpectedErrorText string
+	}{
+		{
+			Name: "audio card without title",
+			Attachment: clientmodels.Attachment{
+				ContentType: contentTypeAudioCard,
+				Content:     `{"media": [{"url": "https://example.com/clip.m4a"}]}`,
+			},
+			ExpectedName: "Audio clip.m4a",
+			ExpectedURL:  "https://example.com/clip.m4a",
+		},
+		{
+			Name: "video card with title",
+			Attachment: clientmodels.Attachment{
+				ContentType: contentTypeVideoCard,
+				Content:     `{"title": "Demo", "media": [{"url": "https://example.com/clip.mp4"}]}`,
+			},
+			ExpectedName: "Demo.mp4",
+			ExpectedURL:  "https://example.com/clip.mp4",
+		},
+		{
+			Name: "raw hosted video",
+			Attachment: clientmodels.Attachment{
+				ContentType: "video/mp4",
+				ContentURL:  "https://graph.microsoft.com/v1.0/chats/chat-id/messages/message-id/hostedContents/content-id/$value",
+			},
+			ExpectedName: "Video clip.mp4",
+			ExpectedURL:  "https://graph.microsoft.com/v1.0/chats/chat-id/messages/message-id/hostedContents/content-id/$value",
+		},
+		{
+			Name: "named raw audio",
+			Attachment: clientmodels.Attachment{
+				Name:        "voice.ogg",
+				ContentType: "audio/ogg",
+				ContentURL:  "https://example.com/voice.ogg",
+			},
+			ExpectedName: "voice.ogg",
+			ExpectedURL:  "https://example.com/voice.ogg",
+		},
+		{
+			Name: "invalid card",
+			Attachment: clientmodels.Attachment{
+				ContentType: contentTypeAudioCard,
+				Content:     "Invalid JSON",
+			},
+			ExpectedErrorText: "failed to unmarshal media card",
+		},
+		{
+			Name: "card without media",
+			Attachment: clientmodels.Attachment{
+				ContentType: contentTypeVideoCard,
+				Content:     `{"title": "Demo", "media": []}`,
+			},
+			ExpectedErrorText: "media card has no media url",
+		},
+	} {
+		t.Run(tc.Name, func(t *testing.T) {
+			a, err := resolveMediaClip(tc.Attachment)
+			if tc.ExpectedErrorText != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tc.ExpectedErrorText)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tc.ExpectedName, a.Name)
+			assert.Equal(t, tc.ExpectedURL, a.ContentURL)
+		})
+	}
+}
+
+func TestWithholdFileAttachments(t *testing.T) {
+	remaining, withheld := withholdFileAttachments([]clientmodels.Attachment{
+		{ContentType: "reference", Name: "file.txt"},
+		{ContentType: "application/vnd.microsoft.card.codesnippet"},
+		{ContentType: "messageReference"},
+		{ContentType: "video/mp4", Name: "clip.mp4"},
+	})
+
+	assert.Equal(t, 2, withheld)
+	assert.Equal(t, []clientmodels.Attachment{
+		{ContentType: "application/vnd.microsoft.card.codesnippet"},
+		{ContentType: "messageReference"},
+	}, remaining)
 }
@@ -0,0 +1,28 @@
+package main
+
+const (
+	auditEventUserConnected        = "msteamsUserConnected"
+	auditEventUserDisconnected     = "msteamsUserDisconnected"
+	auditEventWhitelistUpdated     = "msteamsWhitelistUpdated"
+	auditEventConfigurationRestart = "msteamsConfigurationRestart"
+	auditEventFileQuarantined      = "msteamsFileQuarantined"
+	auditEventBackupExported       = "msteamsBackupExported"
+	auditEventBackupImported       = "msteamsBackupImported"
+	auditEventNotificationObserved = "msteamsNotificationObserved"
+	auditEventQuarantineReplayed   = "msteamsQuarantineReplayed"
+	auditEventQuarantinePurged     = "msteamsQuarantinePurged"
+	auditStatusSuccess             = "success"
+	auditStatusFail                = "fail"
+	auditActorSystem               = "system"
+	auditLogMessage                = "Audit"
+)
+
+// audit records an administrative action on the bridge, identifying the user that performed it and
+// the subject of the action, if any.
+//
+// The plugin API in use does not expose the server audit system, so entries are written as
+// structured log entries with a stable message and keys that can be filtered and forwarded
+// alongside the server audit log.
+func (p *Plugin) audit(event, actorUserID, status string, keyValuePairs ...any) {
+	p.API.LogInfo(auditLogMessage, append([]any{"audit_event", event, "actor_user_id", actorUserID, "status", status}, keyValuePairs...)...)
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/pkg/errors"
+)
+
+// backupBundleVersion is bumped whenever the layout of backupBundle changes incompatibly.
+const backupBundleVersion = 1
+
+// backupSecretSettings lists the configuration keys never written to, or read from, a backup
+// bundle. A fresh install generates its own webhook secret and encryption key on activation.
+var backupSecretSettings = []string{"clientsecret", "encryptionkey", "webhooksecret"}
+
+// errInvalidBackup is returned when a backup bundle cannot be imported because of its contents,
+// rather than a failure of this server.
+var errInvalidBackup = errors.New("invalid backup")
+
+// backupBundle is the portable state of the plugin, as exported from one installation and
+// imported into another. Connected users are not included, since their tokens are secrets
+// encrypted with the installation's own key and users must reconnect after a migration.
+type backupBundle struct {
+	Version    int            `json:"version"`
+	ExportedAt int64          `json:"exported_at"`
+	Settings   map[string]any `json:"settings"`
+	Whitelist  []string       `json:"whitelist"`
+}
+
+type ImportBackupResult struct {
+	Settings int      `json:"settings"`
+	Count    int      `json:"count"`
+	Failed   []string `json:"failed"`
+}
+
+// exportBackup collects the non-secret settings and the whitelist into a backup bundle.
+func (p *Plugin) exportBackup() (*backupBundle, error) {
+	settings, err := p.getConfiguration().ToMap()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read the plugin configuration")
+	}
+	for _, key := range backupSecretSettings {
+		delete(settings, key)
+	}
+
+	whitelist, err := p.getWhitelistEmails()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read the whitelist")
+	}
+	if whitelist == nil {
+		whitelist = []string{}
+	}
+
+	return &backupBundle{
+		Version:    backupBundleVersion,
+		ExportedAt: time.Now().UnixMilli(),
+		Settings:   settings,
+		Whitelist:  whitelist,
+	}, nil
+}
+
+// importBackup applies the settings from a backup bundle on top of the current configuration,
+// keeping the local secrets, and replaces the whitelist with the users from the bundle found on
+// this server. It returns the emails of the whitelisted users that could not be found.
+func (p *Plugin) importBackup(bundle *backupBundle) (*ImportBackupResult, error) {
+	if bundle.Version != backupBundleVersion {
+		return nil, fmt.Errorf("%w: unsupported version %d", errInvalidBackup, bundle.Version)
+	}
+
+	result := &ImportBackupResult{Failed: []string{}}
+
+	var ids []string
+	for _, email := range bundle.Whitelist {
+		user, appErr := p.API.GetUserByEmail(email)
+		if appErr != nil {
+			result.Failed = append(result.Failed, email)
+			continue
+		}
+		ids = append(ids, user.Id)
+	}
+
+	configMap, err := p.getConfiguration().ToMap()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read the plugin configuration")
+	}
+	for key, value := range bundle.Settings {
+		// Unknown settings are skipped rather than persisted, so a bundle from a newer version
+		// cannot leave stale keys behind.
+		if _, ok := configMap[key]; !ok || isBackupSecretSetting(key) {
+			continue
+		}
+		configMap[key] = value
+		result.Settings++
+	}
+
+	// Validate the merged settings before anything is persisted.
+	data, err := json.Marshal(configMap)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to encode the imported settings")
+	}
+	imported := new(configuration)
+	if err = json.Unmarshal(data, imported); err != nil {
+		return nil, fmt.Errorf("%w: invalid settings: %w", errInvalidBackup, err)
+	}
+	if err = p.validateConfiguration(imported); err != nil {
+		return nil, fmt.Errorf("%w: invalid settings: %w", errInvalidBackup, err)
+	}
+
+	// Save the settings first, since they can be restored if importing the whitelist fails.
+	previousConfig := p.API.GetPluginConfig()
+	if appErr := p.API.SavePluginConfig(configMap); appErr != nil {
+		return nil, errors.Wrap(appErr, "failed to save the imported settings")
+	}
+
+	if err = p.store.SetWhitelist(ids, MaxPerPage); err != nil {
+		if appErr := p.API.SavePluginConfig(previousConfig); appErr != nil {
+			p.API.LogError("Unable to restore the settings after failing to import the whitelist", "error", appErr.Error())
+		}
+		return nil, errors.Wrap(err, "failed to import the whitelist")
+	}
+	result.Count = len(ids)
+
+	return result, nil
+}
+
+func isBackupSecretSetting(key string) bool {
+	for _, secret := range backupSecretSettings {
+		if key == secret {
+			return true
+		}
+	}
+	return false
+}
+
+func (a *API) exportBackup(w http.ResponseWriter, r *http.Request) {
+	userID := r.Header.Get("Mattermost-User-ID")
+	if userID == "" {
+		a.p.API.LogWarn("Not authorized")
+		http.Error(w, "not authorized", http.StatusUnauthorized)
+		return
+	}
+
+	if !a.p.API.HasPermissionTo(userID, model.PermissionManageSystem) {
+		a.p.API.LogWarn("Insufficient permissions", "user_id", userID)
+		http.Error(w, "not able to authorize the user", http.StatusForbidden)
+		return
+	}
+
+	bundle, err := a.p.exportBackup()
+	if err != nil {
+		a.p.API.LogWarn("Unable to export backup", "error", err.Error())
+		a.p.audit(auditEventBackupExported, userID, auditStatusFail)
+		http.Error(w, "unable to export backup", http.StatusInternalServerError)
+		return
+	}
+
+	a.p.audit(auditEventBackupExported, userID, auditStatusSuccess, "whitelist_size", len(bundle.Whitelist))
+
+	w.Header().Set("Content-Disposition", "attachment;filename=msteams-backup.json")
+	a.returnJSON(w, bundle)
+}
+
+func (a *API) importBackup(w http.ResponseWriter, r *http.Request) {
+	userID := r.Header.Get("Mattermost-User-ID")
+	if userID == "" {
+		a.p.API.LogWarn("Not authorized")
+		http.Error(w, "not authorized", http.StatusUnauthorized)
+		return
+	}
+
+	if !a.p.API.HasPermissionTo(userID, model.PermissionManageSystem) {
+		a.p.API.LogWarn("Insufficient permissions", "user_id", userID)
+		http.Error(w, "not able to authorize the user", http.StatusForbidden)
+		return
+	}
+
+	var bundle backupBundle
+	if err := json.NewDecoder(r.Body).Decode(&bundle); err != nil {
+		a.p.API.LogWarn("Error parsing backup", "error", err.Error())
+		http.Error(w, "error parsing backup", http.StatusBadRequest)
+		return
+	}
+
+	result, err := a.p.importBackup(&bundle)
+	if errors.Is(err, errInvalidBackup) {
+		a.p.API.LogWarn("Unable to import backup", "error", err.Error())
+		a.p.audit(auditEventBackupImported, userID, auditStatusFail)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	} else if err != nil {
+		a.p.API.LogError("Unable to import backup", "error", err.Error())
+		a.p.audit(auditEventBackupImported, userID, auditStatusFail)
+		http.Error(w, "unable to import backup", http.StatusInternalServerError)
+		return
+	}
+
+	a.p.API.LogInfo("Backup imported", "settings", result.Settings, "whitelist_size", result.Count, "failed", len(result.Failed))
+	a.p.audit(auditEventBackupImported, userID, auditStatusSuccess, "settings", result.Settings, "whitelist_size", result.Count, "failed", len(result.Failed))
+
+	a.returnJSON(w, result)
+}
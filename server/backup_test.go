@@ -0,0 +1,113 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExportBackup(t *testing.T) {
+	th := setupTestHelper(t)
+	apiURL := th.pluginURL(t, "/backup/export")
+	team := th.SetupTeam(t)
+
+	sendRequest := func(t *testing.T, user *model.User) (*http.Response, *backupBundle) {
+		t.Helper()
+		client1 := th.SetupClient(t, user.Id)
+
+		request, err := http.NewRequest(http.MethodGet, apiURL, nil)
+		require.NoError(t, err)
+
+		request.Header.Set(model.HeaderAuth, client1.AuthType+" "+client1.AuthToken)
+
+		response, err := http.DefaultClient.Do(request)
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			require.NoError(t, response.Body.Close())
+		})
+
+		var bundle *backupBundle
+		if response.StatusCode == http.StatusOK {
+			require.NoError(t, json.NewDecoder(response.Body).Decode(&bundle))
+		}
+
+		return response, bundle
+	}
+
+	t.Run("insufficient permissions", func(t *testing.T) {
+		th.Reset(t)
+		user := th.SetupUser(t, team)
+
+		response, bundle := sendRequest(t, user)
+		assert.Equal(t, http.StatusForbidden, response.StatusCode)
+		assert.Nil(t, bundle)
+	})
+
+	t.Run("settings and whitelist without secrets", func(t *testing.T) {
+		th.Reset(t)
+		sysadmin := th.SetupSysadmin(t, team)
+		user1 := th.SetupUser(t, team)
+		th.MarkUserWhitelisted(t, user1.Id)
+
+		response, bundle := sendRequest(t, sysadmin)
+		assert.Equal(t, http.StatusOK, response.StatusCode)
+		require.NotNil(t, bundle)
+		assert.Equal(t, backupBundleVersion, bundle.Version)
+		assert.Equal(t, []string{user1.Email}, bundle.Whitelist)
+		assert.Equal(t, th.p.getConfiguration().TenantID, bundle.Settings["tenantid"])
+		for _, key := range backupSecretSettings {
+			assert.NotContains(t, bundle.Settings, key)
+		}
+	})
+}
+
+func TestImportBackup(t *testing.T) {
+	th := setupTestHelper(t)
+	apiURL := th.pluginURL(t, "/backup/import")
+	team := th.SetupTeam(t)
+
+	sendRequest := func(t *testing.T, user *model.User, bundle backupBundle) *http.Response {
+		t.Helper()
+		client1 := th.SetupClient(t, user.Id)
+
+		data, err := json.Marshal(bundle)
+		require.NoError(t, err)
+
+		request, err := http.NewRequest(http.MethodPost, apiURL, bytes.NewReader(data))
+		require.NoError(t, err)
+
+		request.Header.Set(model.HeaderAuth, client1.AuthType+" "+client1.AuthToken)
+
+		response, err := http.DefaultClient.Do(request)
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			require.NoError(t, response.Body.Close())
+		})
+
+		return response
+	}
+
+	t.Run("unsupported version", func(t *testing.T) {
+		th.Reset(t)
+		sysadmin := th.SetupSysadmin(t, team)
+
+		response := sendRequest(t, sysadmin, backupBundle{Version: backupBundleVersion + 1})
+		assert.Equal(t, http.StatusBadRequest, response.StatusCode)
+	})
+
+	t.Run("invalid settings", func(t *testing.T) {
+		th.Reset(t)
+		sysadmin := th.SetupSysadmin(t, team)
+
+		response := sendRequest(t, sysadmin, backupBundle{
+			Version:  backupBundleVersion,
+			Settings: map[string]any{"tenantid": 1},
+		})
+		assert.Equal(t, http.StatusBadRequest, response.StatusCode)
+	})
+}
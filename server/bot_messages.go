@@ -3,6 +3,7 @@ package main
 import (
 	"fmt"
 	"strings"
+	"unicode/utf8"
 
 	"github.com/mattermost/mattermost/server/public/model"
 	"github.com/pkg/errors"
@@ -110,6 +111,9 @@ func (p *Plugin) makeWelcomeMessageWithNotificationActionPost() *model.Post {
 	}
 }
 
+// maxNotificationMessageRunes is the longest notification message Mattermost accepts in a post.
+const maxNotificationMessageRunes = model.PostMessageMaxRunesV2
+
 // formatNotificationMessage formats the message about a notification of a chat received on Teams.
 func formatNotificationMessage(actorDisplayName string, chatTopic string, chatSize int, chatLink string, message string, attachmentCount int, skippedFileAttachments int) string {
 	message = strings.TrimSpace(message)
@@ -142,7 +146,9 @@ func formatNotificationMessage(actorDisplayName string, chatTopic string, chatSi
 	}
 
 	// Handle the message itself
+	quoteIndex := -1
 	if len(message) > 0 {
+		quoteIndex = len(messageComponents)
 		messageComponents = append(messageComponents,
 			fmt.Sprintf("> %s", strings.ReplaceAll(message, "\n", "\n> ")),
 		)
@@ -156,20 +162,36 @@ func formatNotificationMessage(actorDisplayName string, chatTopic string, chatSi
 
 	formattedMessage := strings.Join(messageComponents, "\n")
 
+	// Mattermost rejects posts over the maximum message size, so cut the quoted message short
+	// rather than dropping the notification altogether.
+	if overflow := utf8.RuneCountInString(formattedMessage) - maxNotificationMessageRunes; overflow > 0 && quoteIndex >= 0 {
+		truncatedNote := fmt.Sprintf("\n*This message was too long to display in full. [View the full message in MS Teams](%s).*", chatLink)
+		quote := []rune(messageComponents[quoteIndex])
+		keep := len(quote) - overflow - utf8.RuneCountInString(truncatedNote) - len("\n>")
+		if keep < 0 {
+			keep = 0
+		}
+		messageComponents[quoteIndex] = strings.TrimRight(string(quote[:keep]), " \n>") + "…\n" + truncatedNote
+		formattedMessage = strings.Join(messageComponents, "\n")
+	}
+
 	return formattedMessage
 }
 
-// notifyMessage sends the given receipient a notification of a chat received on Teams.
-func (p *Plugin) notifyChat(recipientUserID string, actorDisplayName string, chatTopic string, chatSize int, chatLink string, message string, fileIds model.StringArray, skippedFileAttachments int) {
+// notifyChat sends the given receipient a notification of a chat received on Teams.
+func (p *Plugin) notifyChat(recipientUserID string, actorDisplayName string, chatTopic string, chatSize int, chatLink string, message string, fileIds model.StringArray, linkPreviews []*model.SlackAttachment, skippedFileAttachments int) error {
 	formattedMessage := formatNotificationMessage(actorDisplayName, chatTopic, chatSize, chatLink, message, len(fileIds), skippedFileAttachments)
 	if formattedMessage == "" {
-		return
+		return nil
 	}
 
-	if err := p.botSendDirectPost(recipientUserID, &model.Post{
+	post := &model.Post{
 		Message: formattedMessage,
 		FileIds: fileIds,
-	}); err != nil {
-		p.GetAPI().LogWarn("Failed to send notification message", "user_id", recipientUserID, "error", err)
 	}
+	if len(linkPreviews) > 0 {
+		model.ParseSlackAttachment(post, linkPreviews)
+	}
+
+	return p.botSendDirectPost(recipientUserID, post)
 }
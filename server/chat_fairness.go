@@ -0,0 +1,194 @@
+package main
+
+import (
+	"sync"
+	"time"
+
+	"github.com/mattermost/mattermost-plugin-msteams/server/msteams"
+)
+
+const (
+	// chatFairnessWindow is the period over which activities are counted per chat.
+	chatFairnessWindow = 1 * time.Minute
+
+	// chatFairnessMaxShare is the largest fraction of the MS Graph request budget that a single
+	// chat may consume within the window.
+	chatFairnessMaxShare = 0.25
+
+	// graphRequestsPerActivity estimates the number of MS Graph requests needed to process a
+	// created chat message activity: fetching the chat, the message and member presence.
+	graphRequestsPerActivity = 3
+
+	// maxDeferredActivitiesPerChat caps the activities deferred for a single chat, so that a chat
+	// flooding the handler cannot grow the memory used without bound.
+	maxDeferredActivitiesPerChat = 100
+
+	// maxDeferredActivities caps the activities deferred across all chats.
+	maxDeferredActivities = activityQueueSize
+)
+
+// chatFairness counts activities per chat over a fixed window, so that a single busy chat cannot
+// monopolize the tenant's MS Graph request budget.
+type chatFairness struct {
+	lock        sync.Mutex
+	windowStart time.Time
+	counts      map[string]int
+}
+
+// delay reports how long another activity for the given chat must wait before being processed,
+// given the tenant-wide limit of MS Graph requests per second: zero if it may be processed now,
+// in which case it is counted, or else until the current window ends. A non-positive limit never
+// delays activities.
+func (f *chatFairness) delay(chatID string, graphRequestsPerSecond int, now time.Time) time.Duration {
+	if graphRequestsPerSecond <= 0 {
+		return 0
+	}
+
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	if f.counts == nil || now.Sub(f.windowStart) >= chatFairnessWindow {
+		f.counts = make(map[string]int)
+		f.windowStart = now
+	}
+
+	maxPerChat := int(float64(graphRequestsPerSecond) * chatFairnessWindow.Seconds() * chatFairnessMaxShare / graphRequestsPerActivity)
+
+	if f.counts[chatID] >= maxPerChat {
+		return f.windowStart.Add(chatFairnessWindow).Sub(now)
+	}
+
+	f.counts[chatID]++
+	return 0
+}
+
+// deferredActivity is an activity waiting to be queued again.
+type deferredActivity struct {
+	activity msteams.Activity
+	readyAt  time.Time
+}
+
+// deferredChat holds the activities deferred for a chat, queued again in the order they were
+// deferred by a single timer.
+type deferredChat struct {
+	activities []deferredActivity
+	timer      *time.Timer
+}
+
+// deferredActivityKey returns the key under which an activity is deferred: its chat, or its
+// resource for activities about anything else.
+func deferredActivityKey(activity msteams.Activity) string {
+	if chatID := msteams.GetResourceIds(activity.Resource).ChatID; chatID != "" {
+		return chatID
+	}
+
+	return activity.Resource
+}
+
+// deferActivity queues the activity again once the delay has elapsed, and after the activities
+// deferred before it for the same chat. It returns false, leaving the activity to the caller, when
+// too many activities are deferred already. Activities still deferred when the handler stops are
+// checkpointed along with the queue.
+func (ah *ActivityHandler) deferActivity(activity msteams.Activity, delay time.Duration) bool {
+	key := deferredActivityKey(activity)
+
+	ah.deferredLock.Lock()
+	defer ah.deferredLock.Unlock()
+
+	if ah.deferred == nil {
+		ah.deferred = make(map[string]*deferredChat)
+	}
+
+	chat := ah.deferred[key]
+	if ah.deferredCount >= maxDeferredActivities || (chat != nil && len(chat.activities) >= maxDeferredActivitiesPerChat) {
+		return false
+	}
+	if chat == nil {
+		chat = &deferredChat{}
+		ah.deferred[key] = chat
+	}
+
+	chat.activities = append(chat.activities, deferredActivity{activity: activity, readyAt: time.Now().Add(delay)})
+	ah.deferredCount++
+	if activity.Attempts > 0 {
+		ah.deferredRetryCount++
+	}
+
+	// Activities deferred behind others wait for the timer already set for the chat.
+	if chat.timer == nil {
+		chat.timer = time.AfterFunc(delay, func() { ah.requeueDeferredActivities(key, chat) })
+	}
+
+	return true
+}
+
+// requeueDeferredActivities queues the deferred activities of a chat that are ready, in the order
+// they were deferred, then waits for the next one to be ready, or for room if the queue is full.
+func (ah *ActivityHandler) requeueDeferredActivities(key string, chat *deferredChat) {
+	ah.deferredLock.Lock()
+	defer ah.deferredLock.Unlock()
+
+	// The activities are gone if they were checkpointed when the handler stopped.
+	if ah.deferred[key] != chat {
+		return
+	}
+
+	for len(chat.activities) > 0 {
+		next := chat.activities[0]
+		if wait := time.Until(next.readyAt); wait > 0 {
+			chat.timer = time.AfterFunc(wait, func() { ah.requeueDeferredActivities(key, chat) })
+			return
+		}
+
+		if !ah.enqueue(next.activity) {
+			chat.timer = time.AfterFunc(activityQueueRetryAfter, func() { ah.requeueDeferredActivities(key, chat) })
+			return
+		}
+		chat.activities = chat.activities[1:]
+		ah.deferredCount--
+		if next.activity.Attempts > 0 {
+			ah.deferredRetryCount--
+		}
+	}
+
+	delete(ah.deferred, key)
+}
+
+// deferredActivityCount returns the number of activities waiting to be queued again.
+func (ah *ActivityHandler) deferredActivityCount() int {
+	ah.deferredLock.Lock()
+	defer ah.deferredLock.Unlock()
+
+	return ah.deferredCount
+}
+
+// deferredActivityCounts returns the number of activities waiting to be queued again, separating
+// those deferred for fairness from failed ones waiting to be retried.
+func (ah *ActivityHandler) deferredActivityCounts() (int, int) {
+	ah.deferredLock.Lock()
+	defer ah.deferredLock.Unlock()
+
+	return ah.deferredCount - ah.deferredRetryCount, ah.deferredRetryCount
+}
+
+// takeDeferredActivities removes and returns the deferred activities, in the order they were
+// deferred for each chat, so they are no longer queued again.
+func (ah *ActivityHandler) takeDeferredActivities() []msteams.Activity {
+	ah.deferredLock.Lock()
+	defer ah.deferredLock.Unlock()
+
+	activities := make([]msteams.Activity, 0, ah.deferredCount)
+	for _, chat := range ah.deferred {
+		if chat.timer != nil {
+			chat.timer.Stop()
+		}
+		for _, deferred := range chat.activities {
+			activities = append(activities, deferred.activity)
+		}
+	}
+	ah.deferred = nil
+	ah.deferredCount = 0
+	ah.deferredRetryCount = 0
+
+	return activities
+}
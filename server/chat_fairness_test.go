@@ -0,0 +1,90 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mattermost/mattermost-plugin-msteams/server/msteams"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChatFairness(t *testing.T) {
+	now := time.Now()
+
+	t.Run("unlimited", func(t *testing.T) {
+		f := &chatFairness{}
+		for i := 0; i < 1000; i++ {
+			assert.Zero(t, f.delay("chat-id", 0, now))
+		}
+	})
+
+	t.Run("busy chat delayed without affecting others", func(t *testing.T) {
+		f := &chatFairness{}
+
+		// 4 requests per second allows 4 * 60 * 0.25 / 3 = 20 activities per chat per minute.
+		for i := 0; i < 20; i++ {
+			assert.Zero(t, f.delay("busy-chat-id", 4, now))
+		}
+		assert.Equal(t, chatFairnessWindow, f.delay("busy-chat-id", 4, now))
+		assert.Zero(t, f.delay("other-chat-id", 4, now))
+	})
+
+	t.Run("window resets", func(t *testing.T) {
+		f := &chatFairness{}
+		for i := 0; i < 20; i++ {
+			assert.Zero(t, f.delay("chat-id", 4, now))
+		}
+		assert.Equal(t, chatFairnessWindow/2, f.delay("chat-id", 4, now.Add(chatFairnessWindow/2)))
+		assert.Zero(t, f.delay("chat-id", 4, now.Add(chatFairnessWindow)))
+	})
+}
+
+func TestDeferActivity(t *testing.T) {
+	th := setupTestHelper(t)
+
+	t.Run("requeued in order for each chat", func(t *testing.T) {
+		ah := NewActivityHandler(th.p)
+
+		// Activities deferred for less time still wait for those deferred before them.
+		require.True(t, ah.deferActivity(msteams.Activity{Resource: "chats('chat-id')/messages('1')"}, 50*time.Millisecond))
+		require.True(t, ah.deferActivity(msteams.Activity{Resource: "chats('chat-id')/messages('2')"}, 0))
+		require.True(t, ah.deferActivity(msteams.Activity{Resource: "chats('chat-id')/messages('3')"}, 0))
+		assert.Equal(t, 3, ah.deferredActivityCount())
+
+		require.Eventually(t, func() bool { return len(ah.queue) == 3 }, time.Second, 10*time.Millisecond)
+		assert.Equal(t, "chats('chat-id')/messages('1')", (<-ah.queue).Resource)
+		assert.Equal(t, "chats('chat-id')/messages('2')", (<-ah.queue).Resource)
+		assert.Equal(t, "chats('chat-id')/messages('3')", (<-ah.queue).Resource)
+		assert.Zero(t, ah.deferredActivityCount())
+	})
+
+	t.Run("capped per chat", func(t *testing.T) {
+		ah := NewActivityHandler(th.p)
+
+		for i := 0; i < maxDeferredActivitiesPerChat; i++ {
+			require.True(t, ah.deferActivity(msteams.Activity{Resource: "chats('busy-chat-id')/messages('1')"}, time.Hour))
+		}
+		assert.False(t, ah.deferActivity(msteams.Activity{Resource: "chats('busy-chat-id')/messages('1')"}, time.Hour))
+		assert.True(t, ah.deferActivity(msteams.Activity{Resource: "chats('other-chat-id')/messages('1')"}, time.Hour))
+
+		assert.Len(t, ah.takeDeferredActivities(), maxDeferredActivitiesPerChat+1)
+		assert.Zero(t, ah.deferredActivityCount())
+	})
+
+	t.Run("retries counted apart", func(t *testing.T) {
+		ah := NewActivityHandler(th.p)
+
+		require.True(t, ah.deferActivity(msteams.Activity{Resource: "chats('chat-id')/messages('1')"}, time.Hour))
+		require.True(t, ah.deferActivity(msteams.Activity{Resource: "chats('chat-id')/messages('2')", Attempts: 1}, time.Hour))
+
+		deferred, retrying := ah.deferredActivityCounts()
+		assert.Equal(t, 1, deferred)
+		assert.Equal(t, 1, retrying)
+
+		ah.takeDeferredActivities()
+		deferred, retrying = ah.deferredActivityCounts()
+		assert.Zero(t, deferred)
+		assert.Zero(t, retrying)
+	})
+}
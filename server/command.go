@@ -9,15 +9,24 @@ import (
 	"github.com/mattermost/mattermost/server/public/pluginapi/experimental/command"
 )
 
-const msteamsCommand = "msteams"
+const defaultCommandTrigger = "msteamssync"
 
-func (p *Plugin) createCommand() *model.Command {
+// getCommandTrigger returns the configured slash command trigger, without the leading slash.
+func (p *Plugin) getCommandTrigger() string {
+	if trigger := p.getConfiguration().CommandTrigger; trigger != "" {
+		return trigger
+	}
+
+	return defaultCommandTrigger
+}
+
+func (p *Plugin) createCommand(trigger string) *model.Command {
 	iconData, err := command.GetIconData(p.API, "assets/icon.svg")
 	if err != nil {
 		p.API.LogWarn("Unable to get the MS Teams icon for the slash command")
 	}
 
-	autoCompleteData := getAutocompleteData()
+	autoCompleteData := getAutocompleteData(trigger)
 	p.subCommandsMutex.Lock()
 	defer p.subCommandsMutex.Unlock()
 	p.subCommands = make([]string, 0, len(autoCompleteData.SubCommands))
@@ -26,7 +35,7 @@ func (p *Plugin) createCommand() *model.Command {
 	}
 
 	return &model.Command{
-		Trigger:              msteamsCommand,
+		Trigger:              trigger,
 		AutoComplete:         true,
 		AutoCompleteDesc:     "Manage the MS Teams Integration with Mattermost",
 		AutoCompleteHint:     "[command]",
@@ -59,8 +68,8 @@ func (p *Plugin) sendBotEphemeralPost(userID, channelID, message string) {
 	})
 }
 
-func getAutocompleteData() *model.AutocompleteData {
-	cmd := model.NewAutocompleteData(msteamsCommand, "[command]", "Manage MS Teams")
+func getAutocompleteData(trigger string) *model.AutocompleteData {
+	cmd := model.NewAutocompleteData(trigger, "[command]", "Manage MS Teams")
 
 	connect := model.NewAutocompleteData("connect", "", "Connect your Mattermost account to your MS Teams account")
 	cmd.AddCommand(connect)
@@ -79,6 +88,23 @@ func getAutocompleteData() *model.AutocompleteData {
 	})
 	cmd.AddCommand(notifications)
 
+	permissions := model.NewAutocompleteData("permissions", "", "Check the API permissions requested by the MS Teams app registration")
+	permissions.RoleID = model.SystemAdminRoleId
+	cmd.AddCommand(permissions)
+
+	feature := model.NewAutocompleteData("feature", "", "Manage the feature flags gating parts of the sync")
+	feature.RoleID = model.SystemAdminRoleId
+	featureList := model.NewAutocompleteData("list", "", "List the feature flags")
+	featureList.RoleID = model.SystemAdminRoleId
+	feature.AddCommand(featureList)
+	featureEnable := model.NewAutocompleteData("enable", "[flag] [@username|team]", "Enable a feature for everyone, a user or the members of a team")
+	featureEnable.RoleID = model.SystemAdminRoleId
+	feature.AddCommand(featureEnable)
+	featureDisable := model.NewAutocompleteData("disable", "[flag] [@username|team]", "Disable a feature for everyone, or stop targeting a user or team")
+	featureDisable.RoleID = model.SystemAdminRoleId
+	feature.AddCommand(featureDisable)
+	cmd.AddCommand(feature)
+
 	return cmd
 }
 
@@ -94,7 +120,7 @@ func (p *Plugin) ExecuteCommand(_ *plugin.Context, args *model.CommandArgs) (*mo
 		parameters = split[2:]
 	}
 
-	if command != "/"+msteamsCommand {
+	if command != "/"+p.getCommandTrigger() {
 		return &model.CommandResponse{}, nil
 	}
 
@@ -114,6 +140,14 @@ func (p *Plugin) ExecuteCommand(_ *plugin.Context, args *model.CommandArgs) (*mo
 		return p.executeNotificationsCommand(args, parameters)
 	}
 
+	if action == "permissions" {
+		return p.executePermissionsCommand(args)
+	}
+
+	if action == "feature" {
+		return p.executeFeatureCommand(args, parameters)
+	}
+
 	p.subCommandsMutex.RLock()
 	list := strings.Join(p.subCommands, ", ")
 	p.subCommandsMutex.RUnlock()
@@ -125,7 +159,7 @@ func (p *Plugin) executeConnectCommand(args *model.CommandArgs) (*model.CommandR
 		return p.cmdError(args, "You are already connected to MS Teams. Please disconnect your account first before connecting again.")
 	}
 
-	genericErrorMessage := "Error in trying to connect the account, please try again."
+	genericErrorMessage := "Unable to check whether your account can be connected. " + storeErrorRemediation
 
 	hasRightToConnect, err := p.UserHasRightToConnect(args.UserId)
 	if err != nil {
@@ -165,9 +199,12 @@ func (p *Plugin) executeDisconnectCommand(args *model.CommandArgs) (*model.Comma
 
 	err = p.store.SetUserInfo(args.UserId, teamsUserID, nil)
 	if err != nil {
+		p.audit(auditEventUserDisconnected, args.UserId, auditStatusFail, "teams_user_id", teamsUserID)
 		return p.cmdSuccess(args, fmt.Sprintf("Error: unable to disconnect your account, %s", err.Error()))
 	}
 
+	p.audit(auditEventUserDisconnected, args.UserId, auditStatusSuccess, "teams_user_id", teamsUserID)
+
 	p.API.PublishWebSocketEvent(WSEventUserDisconnected, map[string]any{}, &model.WebsocketBroadcast{
 		UserId: args.UserId,
 	})
@@ -202,7 +239,7 @@ func (p *Plugin) executeNotificationsCommand(args *model.CommandArgs, parameters
 		return p.cmdError(args, "Error: Unable to get the connection status")
 	}
 	if !isConnected {
-		return p.cmdSuccess(args, "Error: Your account is not connected to Teams. To use this feature, please connect your account with `/msteams connect`.")
+		return p.cmdSuccess(args, "Error: Your account is not connected to Teams. To use this feature, please connect your account with `/"+p.getCommandTrigger()+" connect`.")
 	}
 
 	notificationPreferenceEnabled := p.getNotificationPreference(args.UserId)
@@ -235,3 +272,18 @@ func (p *Plugin) executeNotificationsCommand(args *model.CommandArgs, parameters
 
 	return p.cmdSuccess(args, parameters[0]+" is not a valid argument.")
 }
+
+func (p *Plugin) executePermissionsCommand(args *model.CommandArgs) (*model.CommandResponse, *model.AppError) {
+	if !p.API.HasPermissionTo(args.UserId, model.PermissionManageSystem) {
+		return p.cmdError(args, "Unable to execute the command, only system admins have access to execute this command.")
+	}
+
+	app, err := p.GetClientForApp().GetApp(p.getConfiguration().ClientID)
+	if err != nil {
+		p.API.LogWarn("Failed to get app credentials", "error", err.Error())
+		return p.cmdError(args, "Unable to get the MS Teams application. "+remediationForError(err))
+	}
+
+	missingPermissions, redundantResourceAccess := p.checkPermissions(app)
+	return p.cmdSuccess(args, formatPermissionsReport(missingPermissions, redundantResourceAccess))
+}
@@ -163,7 +163,7 @@ func TestGetAutocompleteData(t *testing.T) {
 		{
 			description: "Successfully get all auto complete data",
 			autocompleteData: &model.AutocompleteData{
-				Trigger:   "msteams",
+				Trigger:   "msteamssync",
 				Hint:      "[command]",
 				HelpText:  "Manage MS Teams",
 				RoleID:    model.SystemUserRoleId,
@@ -219,12 +219,50 @@ func TestGetAutocompleteData(t *testing.T) {
 						},
 						SubCommands: []*model.AutocompleteData{},
 					},
+					{
+						Trigger:     "permissions",
+						HelpText:    "Check the API permissions requested by the MS Teams app registration",
+						RoleID:      model.SystemAdminRoleId,
+						Arguments:   []*model.AutocompleteArg{},
+						SubCommands: []*model.AutocompleteData{},
+					},
+					{
+						Trigger:   "feature",
+						HelpText:  "Manage the feature flags gating parts of the sync",
+						RoleID:    model.SystemAdminRoleId,
+						Arguments: []*model.AutocompleteArg{},
+						SubCommands: []*model.AutocompleteData{
+							{
+								Trigger:     "list",
+								HelpText:    "List the feature flags",
+								RoleID:      model.SystemAdminRoleId,
+								Arguments:   []*model.AutocompleteArg{},
+								SubCommands: []*model.AutocompleteData{},
+							},
+							{
+								Trigger:     "enable",
+								Hint:        "[flag] [@username|team]",
+								HelpText:    "Enable a feature for everyone, a user or the members of a team",
+								RoleID:      model.SystemAdminRoleId,
+								Arguments:   []*model.AutocompleteArg{},
+								SubCommands: []*model.AutocompleteData{},
+							},
+							{
+								Trigger:     "disable",
+								Hint:        "[flag] [@username|team]",
+								HelpText:    "Disable a feature for everyone, or stop targeting a user or team",
+								RoleID:      model.SystemAdminRoleId,
+								Arguments:   []*model.AutocompleteArg{},
+								SubCommands: []*model.AutocompleteData{},
+							},
+						},
+					},
 				},
 			},
 		},
 	} {
 		t.Run(testCase.description, func(t *testing.T) {
-			autocompleteData := getAutocompleteData()
+			autocompleteData := getAutocompleteData(defaultCommandTrigger)
 			assert.Equal(t, testCase.autocompleteData, autocompleteData)
 		})
 	}
@@ -323,7 +361,7 @@ func TestNotificationCommand(t *testing.T) {
 				commandResponse, appErr := th.p.executeNotificationsCommand(args, []string{subCommand})
 				require.Nil(t, appErr)
 				assertNoCommandResponse(t, commandResponse)
-				assertEphemeralResponse(th, t, args, "Error: Your account is not connected to Teams. To use this feature, please connect your account with `/msteams connect`.")
+				assertEphemeralResponse(th, t, args, "Error: Your account is not connected to Teams. To use this feature, please connect your account with `/msteamssync connect`.")
 			})
 		}
 	})
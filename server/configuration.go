@@ -2,8 +2,11 @@ package main
 
 import (
 	"encoding/json"
+	"net/url"
 	"reflect"
+	"regexp"
 	"strings"
+	"time"
 
 	"github.com/pkg/errors"
 )
@@ -32,6 +35,25 @@ type configuration struct {
 	ConnectedUsersRestricted        bool   `json:"connectedUsersRestricted"`
 	ConnectedUsersMaxPendingInvites int    `json:"connectedUsersMaxPendingInvites"`
 	DisableCheckCredentials         bool   `json:"internalDisableCheckCredentials"`
+	SyncDebugLogging                bool   `json:"syncDebugLogging"`
+	SyncDebugLoggingMinutes         int    `json:"syncDebugLoggingMinutes"`
+	SyncDebugLoggingChatIDs         string `json:"syncDebugLoggingChatIds"`
+	SyncDebugLoggingContent         bool   `json:"syncDebugLoggingContent"`
+	CommandTrigger                  string `json:"commandTrigger"`
+	NotificationLatencySLOSeconds   int    `json:"notificationLatencySloSeconds"`
+	BlockedFileTypes                string `json:"blockedFileTypes"`
+	FileScanURL                     string `json:"fileScanUrl"`
+	GraphRequestsPerSecond          int    `json:"graphRequestsPerSecond"`
+	ObserveOnly                     bool   `json:"observeOnly"`
+	WebhookIPAllowlist              bool   `json:"webhookIpAllowlist"`
+	RedactionPatterns               string `json:"redactionPatterns"`
+
+	// syncDebugLoggingEnabledAt is the time sync debug logging was last enabled, starting the
+	// window configured by SyncDebugLoggingMinutes.
+	syncDebugLoggingEnabledAt time.Time
+
+	// redactionRegexps are the compiled RedactionPatterns.
+	redactionRegexps []*regexp.Regexp
 }
 
 func (c *configuration) ProcessConfiguration() {
@@ -46,6 +68,22 @@ func (c *configuration) ProcessConfiguration() {
 	if c.BufferSizeForFileStreaming <= 0 {
 		c.BufferSizeForFileStreaming = 20
 	}
+	if c.GraphRequestsPerSecond < 0 {
+		c.GraphRequestsPerSecond = 0
+	}
+	if c.NotificationLatencySLOSeconds < 0 {
+		c.NotificationLatencySLOSeconds = 0
+	}
+	if c.SyncDebugLoggingMinutes < 0 {
+		c.SyncDebugLoggingMinutes = 0
+	}
+	c.SyncDebugLoggingChatIDs = strings.TrimSpace(c.SyncDebugLoggingChatIDs)
+	c.BlockedFileTypes = strings.TrimSpace(c.BlockedFileTypes)
+	c.FileScanURL = strings.TrimSpace(c.FileScanURL)
+	c.CommandTrigger = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(c.CommandTrigger), "/"))
+	if c.CommandTrigger == "" {
+		c.CommandTrigger = defaultCommandTrigger
+	}
 }
 
 func (p *Plugin) validateConfiguration(configuration *configuration) error {
@@ -65,10 +103,44 @@ func (p *Plugin) validateConfiguration(configuration *configuration) error {
 	if configuration.WebhookSecret == "" {
 		return errors.New("webhook secret should not be empty")
 	}
+	if configuration.FileScanURL != "" {
+		if parsed, err := url.Parse(configuration.FileScanURL); err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") {
+			return errors.New("file scan URL should be an http or https URL")
+		}
+	}
+	if strings.ContainsAny(configuration.CommandTrigger, " \t\n/") {
+		return errors.New("command trigger should be a single word")
+	}
+
+	redactionRegexps, err := compileRedactionPatterns(configuration.RedactionPatterns)
+	if err != nil {
+		return err
+	}
+	configuration.redactionRegexps = redactionRegexps
 
 	return nil
 }
 
+// compileRedactionPatterns compiles the redaction patterns, given one regular expression per
+// line, ignoring blank lines.
+func compileRedactionPatterns(patterns string) ([]*regexp.Regexp, error) {
+	var regexps []*regexp.Regexp
+	for i, pattern := range strings.Split(patterns, "\n") {
+		pattern = strings.TrimSpace(pattern)
+		if pattern == "" {
+			continue
+		}
+
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, errors.Wrapf(err, "redaction pattern on line %d is invalid", i+1)
+		}
+		regexps = append(regexps, re)
+	}
+
+	return regexps, nil
+}
+
 // Clone shallow copies the configuration. Your implementation may require a deep copy if
 // your configuration has reference types.
 func (c *configuration) Clone() *configuration {
@@ -144,12 +216,60 @@ func (p *Plugin) OnConfigurationChange() error {
 		return err
 	}
 
+	// Preserve the start of the sync debug logging window across unrelated configuration changes
+	// and restarts. Every node clears it when logging is disabled, including while the plugin was
+	// stopped, so the first node to see logging enabled again starts a new one.
+	if previous := p.getConfiguration(); configuration.SyncDebugLogging {
+		if previous.SyncDebugLogging && !previous.syncDebugLoggingEnabledAt.IsZero() {
+			configuration.syncDebugLoggingEnabledAt = previous.syncDebugLoggingEnabledAt
+		} else {
+			configuration.syncDebugLoggingEnabledAt = p.syncDebugLoggingStart()
+		}
+	} else if previous.SyncDebugLogging || p.store == nil {
+		if appErr := p.API.KVDelete(syncDebugLoggingEnabledAtKey); appErr != nil {
+			p.API.LogWarn("Unable to clear the sync debug logging window", "error", appErr.Error())
+		}
+	}
+
+	previous := p.getConfiguration()
 	p.setConfiguration(configuration)
 
 	// Only restart the application if the OnActivate is already executed
 	if p.store != nil {
+		if !configuration.requiresRestart(previous) {
+			p.applyLiveSettings()
+			p.API.LogDebug("Applied configuration changes without restarting")
+			return nil
+		}
+
+		p.audit(auditEventConfigurationRestart, auditActorSystem, auditStatusSuccess)
 		go p.restart()
 	}
 
 	return nil
 }
+
+// requiresRestart reports whether any of the settings only read when the plugin starts differ
+// from the previous configuration. Other settings are read as they are used, or applied by
+// applyLiveSettings, so changing them leaves the MS Graph clients and subscriptions untouched.
+func (c *configuration) requiresRestart(previous *configuration) bool {
+	return c.TenantID != previous.TenantID ||
+		c.ClientID != previous.ClientID ||
+		c.ClientSecret != previous.ClientSecret ||
+		c.EncryptionKey != previous.EncryptionKey ||
+		c.EvaluationAPI != previous.EvaluationAPI ||
+		c.WebhookSecret != previous.WebhookSecret ||
+		c.WebhookIPAllowlist != previous.WebhookIPAllowlist ||
+		c.DisableCheckCredentials != previous.DisableCheckCredentials ||
+		c.CommandTrigger != previous.CommandTrigger
+}
+
+// applyLiveSettings applies the settings that take effect without restarting the plugin.
+func (p *Plugin) applyLiveSettings() {
+	configuration := p.getConfiguration()
+
+	p.graphRateLimiter.SetRate(float64(configuration.GraphRequestsPerSecond), configuration.GraphRequestsPerSecond)
+
+	p.metricsService.ObserveConnectedUsersLimit(int64(configuration.ConnectedUsersAllowed))
+	p.metricsService.ObservePendingInvitesLimit(int64(configuration.ConnectedUsersMaxPendingInvites))
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfigurationRequiresRestart(t *testing.T) {
+	previous := &configuration{
+		TenantID:               "tenant-id",
+		ClientID:               "client-id",
+		ClientSecret:           "client-secret",
+		EncryptionKey:          "encryption-key",
+		WebhookSecret:          "webhook-secret",
+		CommandTrigger:         "msteamssync",
+		GraphRequestsPerSecond: 10,
+	}
+
+	for _, testCase := range []struct {
+		Name     string
+		Change   func(c *configuration)
+		Expected bool
+	}{
+		{"no change", func(c *configuration) {}, false},
+		{"rate limit", func(c *configuration) { c.GraphRequestsPerSecond = 20 }, false},
+		{"observe only", func(c *configuration) { c.ObserveOnly = true }, false},
+		{"blocked file types", func(c *configuration) { c.BlockedFileTypes = "exe" }, false},
+		{"redaction patterns", func(c *configuration) { c.RedactionPatterns = "secret" }, false},
+		{"connected users allowed", func(c *configuration) { c.ConnectedUsersAllowed = 100 }, false},
+		{"client secret", func(c *configuration) { c.ClientSecret = "new-client-secret" }, true},
+		{"webhook secret", func(c *configuration) { c.WebhookSecret = "new-webhook-secret" }, true},
+		{"webhook IP allowlist", func(c *configuration) { c.WebhookIPAllowlist = true }, true},
+		{"command trigger", func(c *configuration) { c.CommandTrigger = "teams" }, true},
+	} {
+		t.Run(testCase.Name, func(t *testing.T) {
+			changed := previous.Clone()
+			testCase.Change(changed)
+			assert.Equal(t, testCase.Expected, changed.requiresRestart(previous))
+		})
+	}
+}
+
+func TestCompileRedactionPatterns(t *testing.T) {
+	regexps, err := compileRedactionPatterns("")
+	require.NoError(t, err)
+	assert.Empty(t, regexps)
+
+	regexps, err = compileRedactionPatterns("\\b\\d{16}\\b\n\n  internal\\.example\\.com  \n")
+	require.NoError(t, err)
+	assert.Len(t, regexps, 2)
+
+	_, err = compileRedactionPatterns("valid\n(unclosed")
+	require.ErrorContains(t, err, "line 2")
+}
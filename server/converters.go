@@ -1,57 +1,57 @@
 package main
 
 import (
+	"encoding/json"
 	"fmt"
 	"strings"
 	"time"
 
-	"github.com/mattermost/mattermost-plugin-msteams/server/markdown"
+	"github.com/mattermost/mattermost-plugin-msteams/server/emoji"
 	"github.com/mattermost/mattermost-plugin-msteams/server/msteams/clientmodels"
 	"github.com/mattermost/mattermost/server/public/model"
 	"golang.org/x/net/html"
 )
 
-const hostedContentsStr = "hostedContents"
+const (
+	hostedContentsStr       = "hostedContents"
+	contentTypeAdaptiveCard = "application/vnd.microsoft.card.adaptive"
 
-func (ah *ActivityHandler) msgToPost(channelID, senderID string, msg *clientmodels.Message, chat *clientmodels.Chat, existingFileIDs []string) (*model.Post, int, bool) {
-	text := ah.handleMentions(msg)
-	text = ah.handleEmojis(text)
-	var embeddedImages []clientmodels.Attachment
-	text, embeddedImages = ah.handleImages(text)
-	msg.Attachments = append(msg.Attachments, embeddedImages...)
-	text = markdown.ConvertToMD(text)
-	props := make(map[string]interface{})
-	rootID := ""
+	// contentTypeHostedImage identifies images embedded in the message body, including stickers,
+	// whose content is hosted by MS Teams alongside the message.
+	contentTypeHostedImage = "hostedImage"
 
-	if msg.ReplyToID != "" {
-		rootInfo, _ := ah.plugin.GetStore().GetPostInfoByMSTeamsID(msg.ChatID+msg.ChannelID, msg.ReplyToID)
-		if rootInfo != nil {
-			rootID = rootInfo.MattermostID
-		}
-	}
-
-	newText, attachments, parentID, skippedFileAttachments, errorFound := ah.handleAttachments(channelID, senderID, text, msg, chat, existingFileIDs)
-	text = newText
+	itemTypeGiphy = "http://schema.skype.com/Giphy"
+)
 
-	if parentID != "" {
-		rootID = parentID
+func (ah *ActivityHandler) msgToPost(logger *activityLogger, channelID, senderID, recipientID string, msg *clientmodels.Message, chat *clientmodels.Chat, existingFileIDs []string) (*model.Post, int, bool) {
+	conversion := &messageConversion{
+		logger:          logger,
+		channelID:       channelID,
+		senderID:        senderID,
+		recipientID:     recipientID,
+		msg:             msg,
+		chat:            chat,
+		existingFileIDs: existingFileIDs,
 	}
+	ah.runMessageTransformers(conversion)
 
-	if rootID == "" && msg.Subject != "" {
-		text = "## " + msg.Subject + "\n" + text
+	props := make(map[string]interface{})
+	post := &model.Post{UserId: senderID, ChannelId: channelID, Message: conversion.text, Props: props, RootId: conversion.rootID, CreateAt: msg.CreateAt.UnixNano() / int64(time.Millisecond)}
+	post.FileIds = conversion.fileIDs
+	if len(conversion.linkPreviews) > 0 {
+		model.ParseSlackAttachment(post, conversion.linkPreviews)
 	}
-
-	post := &model.Post{UserId: senderID, ChannelId: channelID, Message: text, Props: props, RootId: rootID, CreateAt: msg.CreateAt.UnixNano() / int64(time.Millisecond)}
-	post.FileIds = attachments
 	post.AddProp("msteams_sync_"+ah.plugin.GetBotUserID(), true)
 
 	if senderID == ah.plugin.GetBotUserID() {
 		post.AddProp("from_webhook", "true")
 	}
-	return post, skippedFileAttachments, errorFound
+	return post, conversion.skippedFileAttachments, conversion.errorFound
 }
 
-func (ah *ActivityHandler) handleMentions(msg *clientmodels.Message) string {
+// handleMentions converts the mentions in the given message, resolving mentioned users to their
+// Mattermost username only when resolveUsers is set.
+func (ah *ActivityHandler) handleMentions(logger *activityLogger, msg *clientmodels.Message, resolveUsers bool) string {
 	// Teams sometimes translates an at-mention for a user like `Miguel De La Cruz` into four
 	// discrete mentions. This seems broken, but at least easy to distinguish from genuinely
 	// adjacent notifications as a result of injected &nbsp; between each mention. Find
@@ -90,16 +90,18 @@ func (ah *ActivityHandler) handleMentions(msg *clientmodels.Message) string {
 	for _, mention := range msg.Mentions {
 		mmMention := ""
 		switch {
+		case mention.UserID != "" && !resolveUsers:
+			// Keep the mentioned text, as for users that cannot be resolved.
 		case mention.UserID != "":
 			mmUserID, err := ah.plugin.GetStore().TeamsToMattermostUserID(mention.UserID)
 			if err != nil {
-				ah.plugin.GetAPI().LogWarn("Unable to get MM user ID from Teams user ID", "teams_user_id", mention.UserID, "error", err.Error())
+				logger.LogWarn("Unable to get MM user ID from Teams user ID", "teams_user_id", mention.UserID, "error", err.Error())
 				continue
 			}
 
 			mmUser, getErr := ah.plugin.GetAPI().GetUser(mmUserID)
 			if getErr != nil {
-				ah.plugin.GetAPI().LogWarn("Unable to get MM user details", "user_id", mmUserID, "error", getErr.DetailedError)
+				logger.LogWarn("Unable to get MM user details", "user_id", mmUserID, "error", getErr.DetailedError)
 				continue
 			}
 
@@ -120,7 +122,7 @@ func (ah *ActivityHandler) handleMentions(msg *clientmodels.Message) string {
 	return msg.Text
 }
 
-func (ah *ActivityHandler) handleEmojis(text string) string {
+func (ah *ActivityHandler) handleEmojis(logger *activityLogger, text string) string {
 	emojisData := strings.Split(text, "</emoji>")
 
 	for idx, emojiData := range emojisData {
@@ -133,16 +135,27 @@ func (ah *ActivityHandler) handleEmojis(text string) string {
 			emojiData = emojiData[emojiIdx:] + "</emoji>"
 			doc, err := html.Parse(strings.NewReader(emojiData))
 			if err != nil {
-				ah.plugin.GetAPI().LogWarn("Unable to parse emoji data", "emoji_data", emojiData, "error", err.Error())
+				logger.LogWarn("Unable to parse emoji data", "emoji_data", emojiData, "error", err.Error())
 				continue
 			}
 
+			var id, alt string
 			for _, a := range doc.FirstChild.FirstChild.NextSibling.FirstChild.Attr {
-				if a.Key == "alt" {
-					text = strings.Replace(text, emojiData, a.Val, 1)
-					break
+				switch a.Key {
+				case "id":
+					id = a.Val
+				case "alt":
+					alt = a.Val
 				}
 			}
+
+			// Prefer the Unicode emoji, resolving emoticons Teams renders as text, and fall back
+			// to the alt text otherwise.
+			if unicode, ok := emoji.UnicodeFromTeamsEmoticon(id, alt); ok {
+				text = strings.Replace(text, emojiData, unicode, 1)
+			} else if alt != "" {
+				text = strings.Replace(text, emojiData, alt, 1)
+			}
 		}
 	}
 
@@ -154,7 +167,8 @@ func (ah *ActivityHandler) handleImages(text string) (string, []clientmodels.Att
 	var attachments []clientmodels.Attachment
 	for _, imageURL := range imageURLs {
 		attachments = append(attachments, clientmodels.Attachment{
-			ContentURL: imageURL,
+			ContentType: contentTypeHostedImage,
+			ContentURL:  imageURL,
 		})
 	}
 
@@ -163,12 +177,39 @@ func (ah *ActivityHandler) handleImages(text string) (string, []clientmodels.Att
 			return ""
 		}
 
+		// Render giphys as the underlying GIF, which is publicly hosted. The tag is kept as HTML,
+		// since markdown written here would be escaped when the message is converted to markdown.
+		if attrs := getTagAttributes(s); attrs["itemtype"] == itemTypeGiphy && attrs["src"] != "" {
+			alt := attrs["alt"]
+			if alt == "" {
+				alt = "GIF"
+			}
+			return fmt.Sprintf(`<img src="%s" alt="%s">`, html.EscapeString(attrs["src"]), html.EscapeString(alt))
+		}
+
 		return s
 	})
 
 	return text, attachments
 }
 
+// getTagAttributes returns the attributes of the first HTML tag in the given text.
+func getTagAttributes(text string) map[string]string {
+	tokenizer := html.NewTokenizer(strings.NewReader(text))
+	for {
+		switch tokenizer.Next() {
+		case html.ErrorToken:
+			return nil
+		case html.StartTagToken, html.SelfClosingTagToken:
+			attrs := make(map[string]string)
+			for _, a := range tokenizer.Token().Attr {
+				attrs[a.Key] = a.Val
+			}
+			return attrs
+		}
+	}
+}
+
 func getImageTagsFromHTML(text string) []string {
 	tokenizer := html.NewTokenizer(strings.NewReader(text))
 	var images []string
@@ -177,7 +218,7 @@ func getImageTagsFromHTML(text string) []string {
 		switch {
 		case token == html.ErrorToken:
 			return images
-		case token == html.StartTagToken:
+		case token == html.StartTagToken, token == html.SelfClosingTagToken:
 			if t := tokenizer.Token(); t.Data == "img" {
 				for _, a := range t.Attr {
 					if a.Key == "src" && strings.Contains(a.Val, hostedContentsStr) {
@@ -189,3 +230,158 @@ func getImageTagsFromHTML(text string) []string {
 		}
 	}
 }
+
+// adaptiveCardElement is the subset of an adaptive card element used to render link previews and
+// praise.
+type adaptiveCardElement struct {
+	Type            string                `json:"type"`
+	Text            string                `json:"text"`
+	Weight          string                `json:"weight"`
+	URL             string                `json:"url"`
+	Items           []adaptiveCardElement `json:"items"`
+	BackgroundImage *struct {
+		URL string `json:"url"`
+	} `json:"backgroundImage"`
+}
+
+// handleLinkPreviews extracts the link previews rendered by Teams for URLs in a message, which
+// arrive as adaptive cards opening the previewed URL. It returns the remaining attachments and the
+// previews converted to Mattermost message attachments.
+func handleLinkPreviews(attachments []clientmodels.Attachment) ([]clientmodels.Attachment, []*model.SlackAttachment) {
+	var remaining []clientmodels.Attachment
+	var linkPreviews []*model.SlackAttachment
+	for _, a := range attachments {
+		if a.ContentType != contentTypeAdaptiveCard {
+			remaining = append(remaining, a)
+			continue
+		}
+
+		linkPreview := getLinkPreview(a.Content)
+		if linkPreview == nil {
+			remaining = append(remaining, a)
+			continue
+		}
+
+		linkPreviews = append(linkPreviews, linkPreview)
+	}
+
+	return remaining, linkPreviews
+}
+
+// getLinkPreview converts an adaptive card into a Mattermost message attachment, returning nil
+// if the card isn't a link preview.
+func getLinkPreview(content string) *model.SlackAttachment {
+	var card struct {
+		Body         []adaptiveCardElement `json:"body"`
+		SelectAction *struct {
+			Type string `json:"type"`
+			URL  string `json:"url"`
+		} `json:"selectAction"`
+	}
+	if err := json.Unmarshal([]byte(content), &card); err != nil {
+		return nil
+	}
+	if card.SelectAction == nil || card.SelectAction.Type != "Action.OpenUrl" || card.SelectAction.URL == "" {
+		return nil
+	}
+
+	title, texts, imageURL := collectAdaptiveCardContent(card.Body)
+	linkPreview := &model.SlackAttachment{
+		Title:     title,
+		TitleLink: card.SelectAction.URL,
+		Text:      strings.Join(texts, "\n"),
+		ThumbURL:  imageURL,
+	}
+	if linkPreview.Title == "" {
+		linkPreview.Title = linkPreview.TitleLink
+	}
+	linkPreview.Fallback = linkPreview.Title
+
+	return linkPreview
+}
+
+// collectAdaptiveCardContent walks the elements of an adaptive card, returning its first bolder
+// text as a title, the other texts, and the first image, if any.
+func collectAdaptiveCardContent(body []adaptiveCardElement) (title string, texts []string, imageURL string) {
+	var walk func(elements []adaptiveCardElement)
+	walk = func(elements []adaptiveCardElement) {
+		for _, element := range elements {
+			if element.BackgroundImage != nil && imageURL == "" {
+				imageURL = element.BackgroundImage.URL
+			}
+
+			switch element.Type {
+			case "TextBlock":
+				text := strings.TrimSpace(element.Text)
+				if text == "" {
+					continue
+				}
+				if title == "" && strings.EqualFold(element.Weight, "bolder") {
+					title = text
+				} else {
+					texts = append(texts, text)
+				}
+			case "Image":
+				if imageURL == "" {
+					imageURL = element.URL
+				}
+			}
+
+			walk(element.Items)
+		}
+	}
+	walk(body)
+
+	return title, texts, imageURL
+}
+
+// handlePraiseCards extracts the praise sent in Teams, which arrives as an adaptive card showing
+// a badge image, rendering it as a Mattermost message attachment instead of ignoring it. It
+// returns the remaining attachments and the rendered praise.
+func handlePraiseCards(attachments []clientmodels.Attachment) ([]clientmodels.Attachment, []*model.SlackAttachment) {
+	var remaining []clientmodels.Attachment
+	var praise []*model.SlackAttachment
+	for _, a := range attachments {
+		if a.ContentType != contentTypeAdaptiveCard {
+			remaining = append(remaining, a)
+			continue
+		}
+
+		praiseCard := getPraise(a.Content)
+		if praiseCard == nil {
+			remaining = append(remaining, a)
+			continue
+		}
+
+		praise = append(praise, praiseCard)
+	}
+
+	return remaining, praise
+}
+
+// getPraise converts an adaptive card into a Mattermost message attachment, returning nil if the
+// card isn't praise. Praise is recognised by its badge, the only image Teams hosts under a praise
+// path.
+func getPraise(content string) *model.SlackAttachment {
+	var card struct {
+		Body []adaptiveCardElement `json:"body"`
+	}
+	if err := json.Unmarshal([]byte(content), &card); err != nil {
+		return nil
+	}
+
+	title, texts, imageURL := collectAdaptiveCardContent(card.Body)
+	if !strings.Contains(strings.ToLower(imageURL), "/praise") {
+		return nil
+	}
+
+	praise := &model.SlackAttachment{
+		Pretext:  ":trophy: Praise",
+		Title:    title,
+		Text:     strings.Join(texts, "\n"),
+		ThumbURL: imageURL,
+	}
+	praise.Fallback = strings.TrimSpace("Praise: " + title)
+
+	return praise
+}
@@ -44,9 +44,62 @@ func TestMsgToPost(t *testing.T) {
 			CreateAt: message.CreateAt.UnixNano() / int64(time.Millisecond),
 		}
 
-		actualPost, _, _ := th.p.activityHandler.msgToPost(channel.Id, sender.Id, message, nil, []string{})
+		actualPost, _, _ := th.p.activityHandler.msgToPost(th.p.activityHandler.newActivityLogger(model.NewId()), channel.Id, sender.Id, "", message, nil, []string{})
 		assert.Equal(t, expectedPost, actualPost)
 	})
+
+	t.Run("link preview converted for each recipient", func(t *testing.T) {
+		th.Reset(t)
+
+		sender := th.SetupUser(t, team)
+		channel := th.SetupPublicChannel(t, team)
+
+		message := &clientmodels.Message{
+			Text: `<attachment id="preview-id"></attachment>`,
+			Attachments: []clientmodels.Attachment{
+				{
+					ID:          "preview-id",
+					ContentType: "application/vnd.microsoft.card.adaptive",
+					Content:     `{"type": "AdaptiveCard", "body": [{"text": "Title", "weight": "bolder", "type": "TextBlock"}], "selectAction": {"url": "https://example.com/article", "type": "Action.OpenUrl"}}`,
+				},
+			},
+			CreateAt: time.Now(),
+		}
+
+		for i := 0; i < 2; i++ {
+			actualPost, _, _ := th.p.activityHandler.msgToPost(th.p.activityHandler.newActivityLogger(model.NewId()), channel.Id, sender.Id, "", message, nil, []string{})
+			assert.Empty(t, actualPost.Message)
+			assert.Len(t, actualPost.Attachments(), 1)
+		}
+		assert.Len(t, message.Attachments, 1)
+	})
+
+	t.Run("multi-word mention converted for each recipient", func(t *testing.T) {
+		th.Reset(t)
+
+		sender := th.SetupUser(t, team)
+		channel := th.SetupPublicChannel(t, team)
+
+		mentioned := th.SetupUser(t, team)
+		th.ConnectUser(t, mentioned.Id)
+
+		message := &clientmodels.Message{
+			Text: `hello <at id="0">Miguel</at>&nbsp;<at id="1">de</at>&nbsp;<at id="2">la</at>&nbsp;<at id="3">Cruz</at>`,
+			Mentions: []clientmodels.Mention{
+				{ID: 0, UserID: "t" + mentioned.Id, MentionedText: "Miguel"},
+				{ID: 1, UserID: "t" + mentioned.Id, MentionedText: "de"},
+				{ID: 2, UserID: "t" + mentioned.Id, MentionedText: "la"},
+				{ID: 3, UserID: "t" + mentioned.Id, MentionedText: "Cruz"},
+			},
+			CreateAt: time.Now(),
+		}
+
+		for i := 0; i < 2; i++ {
+			actualPost, _, _ := th.p.activityHandler.msgToPost(th.p.activityHandler.newActivityLogger(model.NewId()), channel.Id, sender.Id, "", message, nil, []string{})
+			assert.Equal(t, "hello @"+mentioned.Username, actualPost.Message)
+		}
+		assert.Equal(t, "Miguel", message.Mentions[0].MentionedText)
+	})
 }
 
 func TestHandleMentions(t *testing.T) {
@@ -61,7 +114,7 @@ func TestHandleMentions(t *testing.T) {
 		}
 		expectedMessage := "mockMessage"
 
-		actualMessage := th.p.activityHandler.handleMentions(message)
+		actualMessage := th.p.activityHandler.handleMentions(th.p.activityHandler.newActivityLogger(model.NewId()), message, true)
 		assert.Equal(t, expectedMessage, actualMessage)
 	})
 
@@ -79,7 +132,7 @@ func TestHandleMentions(t *testing.T) {
 		}
 		expectedMessage := "mockMessage @all"
 
-		actualMessage := th.p.activityHandler.handleMentions(message)
+		actualMessage := th.p.activityHandler.handleMentions(th.p.activityHandler.newActivityLogger(model.NewId()), message, true)
 		assert.Equal(t, expectedMessage, actualMessage)
 	})
 
@@ -98,7 +151,7 @@ func TestHandleMentions(t *testing.T) {
 		}
 		expectedMessage := `mockMessage <at id="0">mockMentionedText</at>`
 
-		actualMessage := th.p.activityHandler.handleMentions(message)
+		actualMessage := th.p.activityHandler.handleMentions(th.p.activityHandler.newActivityLogger(model.NewId()), message, true)
 		assert.Equal(t, expectedMessage, actualMessage)
 	})
 
@@ -128,7 +181,7 @@ func TestHandleMentions(t *testing.T) {
 		}
 		expectedMessage := "hello @" + user1.Username + " from @" + user2.Username
 
-		actualMessage := th.p.activityHandler.handleMentions(message)
+		actualMessage := th.p.activityHandler.handleMentions(th.p.activityHandler.newActivityLogger(model.NewId()), message, true)
 		assert.Equal(t, expectedMessage, actualMessage)
 	})
 
@@ -166,7 +219,7 @@ func TestHandleMentions(t *testing.T) {
 
 		expectedMessage := "hello @" + user1.Username
 
-		actualMessage := th.p.activityHandler.handleMentions(message)
+		actualMessage := th.p.activityHandler.handleMentions(th.p.activityHandler.newActivityLogger(model.NewId()), message, true)
 		assert.Equal(t, expectedMessage, actualMessage)
 	})
 
@@ -203,7 +256,7 @@ func TestHandleMentions(t *testing.T) {
 
 		expectedMessage := `hello <at id="0">Miguel de la Cruz</at>`
 
-		actualMessage := th.p.activityHandler.handleMentions(message)
+		actualMessage := th.p.activityHandler.handleMentions(th.p.activityHandler.newActivityLogger(model.NewId()), message, true)
 		assert.Equal(t, expectedMessage, actualMessage)
 	})
 
@@ -261,7 +314,7 @@ func TestHandleMentions(t *testing.T) {
 
 		expectedMessage := "hello @" + user1.Username + "@" + user1.Username
 
-		actualMessage := th.p.activityHandler.handleMentions(message)
+		actualMessage := th.p.activityHandler.handleMentions(th.p.activityHandler.newActivityLogger(model.NewId()), message, true)
 		assert.Equal(t, expectedMessage, actualMessage)
 	})
 }
@@ -299,12 +352,243 @@ func TestHandleEmojis(t *testing.T) {
 			text:         `<div><div> hi   <emoji id="lipssealed" alt="🤫" title=""></emoji> hello  <emoji id="1f61b_facewithtongue" alt="😛" title=""></emoji> hey    </div></div>`,
 			expectedText: "<div><div> hi   🤫 hello  😛 hey    </div></div>",
 		},
+		{
+			description:  "Text with emoji rendered as text by Teams",
+			text:         `<div><div>hi <emoji id="cool" alt="(cool)" title=""></emoji><emoji id="1f61b_facewithtongue" title=""></emoji></div></div>`,
+			expectedText: "<div><div>hi 😎😛</div></div>",
+		},
+		{
+			description:  "Text with unknown emoji",
+			text:         `<div><div>hi <emoji id="somethingnew" alt="(somethingnew)" title=""></emoji></div></div>`,
+			expectedText: "<div><div>hi (somethingnew)</div></div>",
+		},
 	} {
 		t.Run(testCase.description, func(t *testing.T) {
 			th.Reset(t)
 
-			actualText := th.p.activityHandler.handleEmojis(testCase.text)
+			actualText := th.p.activityHandler.handleEmojis(th.p.activityHandler.newActivityLogger(model.NewId()), testCase.text)
 			assert.Equal(t, actualText, testCase.expectedText)
 		})
 	}
 }
+
+func TestHandleLinkPreviews(t *testing.T) {
+	linkPreviewContent := `{
+  "type": "AdaptiveCard",
+  "body": [
+    {
+      "items": [{"text": " ", "type": "TextBlock"}],
+      "backgroundImage": {"url": "https://example.com/image.jpg"},
+      "type": "Container"
+    },
+    {"size": "medium", "text": "Looking At Cute Animal Pictures At Work", "weight": "bolder", "type": "TextBlock"},
+    {"isSubtle": true, "size": "small", "text": "The Huffington Post", "type": "TextBlock"},
+    {"isSubtle": true, "size": "small", "text": "Perusing cute animal slideshows may make you a better employee", "type": "TextBlock"}
+  ],
+  "version": "1.4",
+  "selectAction": {"url": "https://example.com/article", "type": "Action.OpenUrl"}
+}`
+
+	for _, testCase := range []struct {
+		description          string
+		attachments          []clientmodels.Attachment
+		expectedAttachments  []clientmodels.Attachment
+		expectedLinkPreviews []*model.SlackAttachment
+	}{
+		{
+			description:          "No attachments",
+			attachments:          nil,
+			expectedAttachments:  nil,
+			expectedLinkPreviews: nil,
+		},
+		{
+			description: "Link preview",
+			attachments: []clientmodels.Attachment{
+				{ContentType: "reference", Name: "file.png"},
+				{ContentType: "application/vnd.microsoft.card.adaptive", Content: linkPreviewContent},
+			},
+			expectedAttachments: []clientmodels.Attachment{
+				{ContentType: "reference", Name: "file.png"},
+			},
+			expectedLinkPreviews: []*model.SlackAttachment{
+				{
+					Fallback:  "Looking At Cute Animal Pictures At Work",
+					Title:     "Looking At Cute Animal Pictures At Work",
+					TitleLink: "https://example.com/article",
+					Text:      "The Huffington Post\nPerusing cute animal slideshows may make you a better employee",
+					ThumbURL:  "https://example.com/image.jpg",
+				},
+			},
+		},
+		{
+			description: "Link preview without a title",
+			attachments: []clientmodels.Attachment{
+				{ContentType: "application/vnd.microsoft.card.adaptive", Content: `{"body": [{"type": "Image", "url": "https://example.com/image.jpg"}], "selectAction": {"url": "https://example.com/article", "type": "Action.OpenUrl"}}`},
+			},
+			expectedAttachments: nil,
+			expectedLinkPreviews: []*model.SlackAttachment{
+				{
+					Fallback:  "https://example.com/article",
+					Title:     "https://example.com/article",
+					TitleLink: "https://example.com/article",
+					ThumbURL:  "https://example.com/image.jpg",
+				},
+			},
+		},
+		{
+			description: "Adaptive card that isn't a link preview",
+			attachments: []clientmodels.Attachment{
+				{ContentType: "application/vnd.microsoft.card.adaptive", Content: `{"body": [{"type": "TextBlock", "text": "Approve?"}]}`},
+			},
+			expectedAttachments: []clientmodels.Attachment{
+				{ContentType: "application/vnd.microsoft.card.adaptive", Content: `{"body": [{"type": "TextBlock", "text": "Approve?"}]}`},
+			},
+			expectedLinkPreviews: nil,
+		},
+		{
+			description: "Invalid adaptive card",
+			attachments: []clientmodels.Attachment{
+				{ContentType: "application/vnd.microsoft.card.adaptive", Content: "Invalid JSON"},
+			},
+			expectedAttachments: []clientmodels.Attachment{
+				{ContentType: "application/vnd.microsoft.card.adaptive", Content: "Invalid JSON"},
+			},
+			expectedLinkPreviews: nil,
+		},
+	} {
+		t.Run(testCase.description, func(t *testing.T) {
+			actualAttachments, actualLinkPreviews := handleLinkPreviews(testCase.attachments)
+			assert.Equal(t, testCase.expectedAttachments, actualAttachments)
+			assert.Equal(t, testCase.expectedLinkPreviews, actualLinkPreviews)
+		})
+	}
+}
+
+func TestHandlePraiseCards(t *testing.T) {
+	praiseContent := `{
+  "type": "AdaptiveCard",
+  "body": [
+    {
+      "type": "Container",
+      "items": [
+        {"type": "Image", "url": "https://statics.teams.cdn.office.net/evergreen-assets/praise/v1/kudos.png"},
+        {"type": "TextBlock", "text": "Kudos", "weight": "bolder"},
+        {"type": "TextBlock", "text": "Sender Name to Recipient Name"},
+        {"type": "TextBlock", "text": "Thanks for shipping the release!"}
+      ]
+    }
+  ],
+  "version": "1.2"
+}`
+
+	for _, testCase := range []struct {
+		description         string
+		attachments         []clientmodels.Attachment
+		expectedAttachments []clientmodels.Attachment
+		expectedPraise      []*model.SlackAttachment
+	}{
+		{
+			description: "Praise",
+			attachments: []clientmodels.Attachment{
+				{ContentType: "reference", Name: "file.png"},
+				{ContentType: "application/vnd.microsoft.card.adaptive", Content: praiseContent},
+			},
+			expectedAttachments: []clientmodels.Attachment{
+				{ContentType: "reference", Name: "file.png"},
+			},
+			expectedPraise: []*model.SlackAttachment{
+				{
+					Fallback: "Praise: Kudos",
+					Pretext:  ":trophy: Praise",
+					Title:    "Kudos",
+					Text:     "Sender Name to Recipient Name\nThanks for shipping the release!",
+					ThumbURL: "https://statics.teams.cdn.office.net/evergreen-assets/praise/v1/kudos.png",
+				},
+			},
+		},
+		{
+			description: "Adaptive card that isn't praise",
+			attachments: []clientmodels.Attachment{
+				{ContentType: "application/vnd.microsoft.card.adaptive", Content: `{"body": [{"type": "Image", "url": "https://example.com/image.png"}, {"type": "TextBlock", "text": "Approve?"}]}`},
+			},
+			expectedAttachments: []clientmodels.Attachment{
+				{ContentType: "application/vnd.microsoft.card.adaptive", Content: `{"body": [{"type": "Image", "url": "https://example.com/image.png"}, {"type": "TextBlock", "text": "Approve?"}]}`},
+			},
+			expectedPraise: nil,
+		},
+		{
+			description: "Invalid adaptive card",
+			attachments: []clientmodels.Attachment{
+				{ContentType: "application/vnd.microsoft.card.adaptive", Content: "Invalid JSON"},
+			},
+			expectedAttachments: []clientmodels.Attachment{
+				{ContentType: "application/vnd.microsoft.card.adaptive", Content: "Invalid JSON"},
+			},
+			expectedPraise: nil,
+		},
+	} {
+		t.Run(testCase.description, func(t *testing.T) {
+			actualAttachments, actualPraise := handlePraiseCards(testCase.attachments)
+			assert.Equal(t, testCase.expectedAttachments, actualAttachments)
+			assert.Equal(t, testCase.expectedPraise, actualPraise)
+		})
+	}
+}
+
+func TestHandleImages(t *testing.T) {
+	th := setupTestHelper(t)
+
+	hostedURL := "https://graph.microsoft.com/v1.0/chats/chat-id/messages/message-id/hostedContents/content-id/$value"
+
+	for _, testCase := range []struct {
+		description         string
+		text                string
+		expectedText        string
+		expectedAttachments []clientmodels.Attachment
+	}{
+		{
+			description:  "Text without images",
+			text:         "<p>hi</p>",
+			expectedText: "<p>hi</p>",
+		},
+		{
+			description:  "Hosted image",
+			text:         `<p>look <img src="` + hostedURL + `" alt="image"></p>`,
+			expectedText: "<p>look </p>",
+			expectedAttachments: []clientmodels.Attachment{
+				{ContentType: contentTypeHostedImage, ContentURL: hostedURL},
+			},
+		},
+		{
+			description:  "Sticker",
+			text:         `<img src="` + hostedURL + `" alt="Sticker" itemtype="http://schema.skype.com/AMSImage" />`,
+			expectedText: "",
+			expectedAttachments: []clientmodels.Attachment{
+				{ContentType: contentTypeHostedImage, ContentURL: hostedURL},
+			},
+		},
+		{
+			description:  "Giphy",
+			text:         `<p><img src="https://media.giphy.com/media/abc/giphy.gif" alt="Happy Dance" itemtype="http://schema.skype.com/Giphy"></p>`,
+			expectedText: `<p><img src="https://media.giphy.com/media/abc/giphy.gif" alt="Happy Dance"></p>`,
+		},
+		{
+			description:  "Giphy without alt text",
+			text:         `<img src="https://media.giphy.com/media/abc/giphy.gif" itemtype="http://schema.skype.com/Giphy">`,
+			expectedText: `<img src="https://media.giphy.com/media/abc/giphy.gif" alt="GIF">`,
+		},
+		{
+			description:  "External image",
+			text:         `<img src="https://example.com/image.png">`,
+			expectedText: `<img src="https://example.com/image.png">`,
+		},
+	} {
+		t.Run(testCase.description, func(t *testing.T) {
+			th.Reset(t)
+
+			actualText, actualAttachments := th.p.activityHandler.handleImages(testCase.text)
+			assert.Equal(t, testCase.expectedText, actualText)
+			assert.Equal(t, testCase.expectedAttachments, actualAttachments)
+		})
+	}
+}
@@ -171,6 +171,39 @@ func (p *Plugin) checkCredentials() {
 	}
 }
 
+// permissionsConsentNote ends the permissions report, which cannot tell whether the permissions
+// were actually granted.
+const permissionsConsentNote = "\n\nAdmin consent is not checked: confirm in the Azure portal that it was granted for every required permission."
+
+// formatPermissionsReport describes the outcome of checking the application's API permissions
+// for a system admin. Only the permissions requested by the app registration are checked, not
+// whether an admin consented to them.
+func formatPermissionsReport(missing []expectedPermission, redundant []clientmodels.ResourceAccess) string {
+	if len(missing) == 0 && len(redundant) == 0 {
+		return "The MS Teams app registration requests exactly the required API permissions." + permissionsConsentNote
+	}
+
+	var report strings.Builder
+	if len(missing) == 0 {
+		report.WriteString("The MS Teams app registration requests all the required API permissions.")
+	} else {
+		report.WriteString("The MS Teams app registration does not request the following required API permissions, so notifications may fail until they are added and granted admin consent:")
+		for _, permission := range missing {
+			fmt.Fprintf(&report, "\n- %s: %s", describeResourceAccessType(permission.ResourceAccess), permission.Name)
+		}
+	}
+
+	if len(redundant) > 0 {
+		report.WriteString("\n\nThe following API permissions are requested but not required, and can be removed:")
+		for _, resourceAccess := range redundant {
+			fmt.Fprintf(&report, "\n- %s: %s", describeResourceAccessType(resourceAccess), resourceAccess.ID)
+		}
+	}
+
+	report.WriteString(permissionsConsentNote)
+	return report.String()
+}
+
 func (p *Plugin) checkPermissions(app *clientmodels.App) ([]expectedPermission, []clientmodels.ResourceAccess) {
 	// Build a map and log what we find at the same time.
 	actualRequiredResources := make(map[string]clientmodels.ResourceAccess)
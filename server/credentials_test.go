@@ -23,6 +23,41 @@ func TestDescribeResourceAccessType(t *testing.T) {
 	}))
 }
 
+func TestFormatPermissionsReport(t *testing.T) {
+	t.Run("exact permissions", func(t *testing.T) {
+		assert.Equal(t, "The MS Teams app registration requests exactly the required API permissions."+permissionsConsentNote, formatPermissionsReport(nil, nil))
+	})
+
+	t.Run("missing and redundant permissions", func(t *testing.T) {
+		missing := []expectedPermission{
+			{
+				Name: "https://graph.microsoft.com/Chat.Read.All",
+				ResourceAccess: clientmodels.ResourceAccess{
+					ID:   "6b7d71aa-70aa-4810-a8d9-5d9fb2830017",
+					Type: ResourceAccessTypeRole,
+				},
+			},
+		}
+		redundant := []clientmodels.ResourceAccess{
+			{ID: "redundant-id", Type: ResourceAccessTypeScope},
+		}
+
+		assert.Equal(t, `The MS Teams app registration does not request the following required API permissions, so notifications may fail until they are added and granted admin consent:
+- Role (Application): https://graph.microsoft.com/Chat.Read.All
+
+The following API permissions are requested but not required, and can be removed:
+- Scope (Delegated): redundant-id`+permissionsConsentNote, formatPermissionsReport(missing, redundant))
+	})
+
+	t.Run("only redundant permissions", func(t *testing.T) {
+		report := formatPermissionsReport(nil, []clientmodels.ResourceAccess{{ID: "redundant-id", Type: ResourceAccessTypeScope}})
+		assert.Equal(t, `The MS Teams app registration requests all the required API permissions.
+
+The following API permissions are requested but not required, and can be removed:
+- Scope (Delegated): redundant-id`+permissionsConsentNote, report)
+	})
+}
+
 func TestCheckPermissions(t *testing.T) {
 	th := setupTestHelper(t)
 
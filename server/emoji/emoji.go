@@ -0,0 +1,146 @@
+// Package emoji translates between the emoji representations used by MS Teams, namely Unicode
+// characters, emoticon ids and reaction types, and Mattermost emoji names.
+package emoji
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/mattermost/mattermost/server/public/model"
+)
+
+// variationSelector is the invisible code point requesting an emoji presentation, included
+// inconsistently by clients and thus ignored when matching emoji.
+const variationSelector = "\ufe0f"
+
+// teamsReactions maps the legacy MS Teams reaction types, also used as emoticon ids, to Mattermost
+// emoji names. Newer Teams clients use Unicode emoji instead, handled through the system emoji
+// table.
+var teamsReactions = map[string]string{
+	"like":            "+1",
+	"heart":           "heart",
+	"laugh":           "laughing",
+	"surprised":       "open_mouth",
+	"sad":             "cry",
+	"angry":           "angry",
+	"checkmarkbutton": "white_check_mark",
+}
+
+// teamsEmoticons maps the legacy MS Teams emoticon ids that don't embed their code point to
+// Mattermost emoji names.
+var teamsEmoticons = map[string]string{
+	"smile":      "slightly_smiling_face",
+	"wink":       "wink",
+	"cool":       "sunglasses",
+	"cry":        "sob",
+	"kiss":       "kissing_heart",
+	"tongueout":  "stuck_out_tongue",
+	"lipssealed": "shushing_face",
+	"yes":        "+1",
+	"no":         "-1",
+	"clap":       "clap",
+	"think":      "thinking_face",
+	"party":      "partying_face",
+}
+
+var (
+	nameToUnicode map[string]string
+	unicodeToName map[string]string
+)
+
+func init() {
+	nameToUnicode = make(map[string]string, len(model.SystemEmojis))
+	unicodeToName = make(map[string]string, len(model.SystemEmojis))
+	for name, codePoints := range model.SystemEmojis {
+		unicode, ok := decodeCodePoints(codePoints)
+		if !ok {
+			continue
+		}
+		nameToUnicode[name] = unicode
+
+		key := normalize(unicode)
+		if existing, ok := unicodeToName[key]; !ok || preferName(name, existing) {
+			unicodeToName[key] = name
+		}
+	}
+}
+
+// decodeCodePoints converts a dash-separated list of hexadecimal code points, as used by the
+// Mattermost system emoji table, into the corresponding string.
+func decodeCodePoints(codePoints string) (string, bool) {
+	var sb strings.Builder
+	for _, codePoint := range strings.Split(codePoints, "-") {
+		r, err := strconv.ParseUint(codePoint, 16, 32)
+		if err != nil {
+			return "", false
+		}
+		sb.WriteRune(rune(r))
+	}
+
+	return sb.String(), true
+}
+
+// preferName decides which of two aliases for the same emoji to use, favouring the shorter,
+// more conventional name and falling back to alphabetical order for determinism.
+func preferName(candidate, existing string) bool {
+	if len(candidate) != len(existing) {
+		return len(candidate) < len(existing)
+	}
+
+	return sort.StringsAreSorted([]string{candidate, existing})
+}
+
+func normalize(unicode string) string {
+	return strings.ReplaceAll(strings.TrimSpace(unicode), variationSelector, "")
+}
+
+// NameFromUnicode returns the Mattermost emoji name for the given Unicode emoji.
+func NameFromUnicode(unicode string) (string, bool) {
+	name, ok := unicodeToName[normalize(unicode)]
+	return name, ok
+}
+
+// NameFromTeamsEmoticon returns the Mattermost emoji name for an emoticon embedded in an MS Teams
+// message, given its id (e.g. "1f61b_facewithtongue" or "lipssealed") and alt text.
+func NameFromTeamsEmoticon(id, alt string) (string, bool) {
+	if name, ok := NameFromUnicode(alt); ok {
+		return name, true
+	}
+
+	if codePoints, _, found := strings.Cut(id, "_"); found {
+		if unicode, ok := decodeCodePoints(codePoints); ok {
+			if name, ok := NameFromUnicode(unicode); ok {
+				return name, true
+			}
+		}
+	}
+
+	if name, ok := teamsEmoticons[strings.ToLower(id)]; ok {
+		return name, true
+	}
+
+	if name, ok := teamsReactions[strings.ToLower(id)]; ok {
+		return name, true
+	}
+
+	return NameFromUnicode(id)
+}
+
+// UnicodeFromTeamsEmoticon returns the Unicode emoji for an emoticon embedded in an MS Teams
+// message, using its alt text when Teams provides one and resolving its id otherwise. Unicode is
+// preferred over emoji names in posts, since it needs no escaping and renders in push and email
+// notifications too.
+func UnicodeFromTeamsEmoticon(id, alt string) (string, bool) {
+	if _, ok := NameFromUnicode(alt); ok {
+		return alt, true
+	}
+
+	name, ok := NameFromTeamsEmoticon(id, alt)
+	if !ok {
+		return "", false
+	}
+
+	unicode, ok := nameToUnicode[name]
+	return unicode, ok
+}
@@ -0,0 +1,88 @@
+package emoji
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNameFromUnicode(t *testing.T) {
+	for _, testCase := range []struct {
+		description  string
+		unicode      string
+		expectedName string
+		expectedOK   bool
+	}{
+		{description: "Simple emoji", unicode: "😀", expectedName: "grinning", expectedOK: true},
+		{description: "Emoji with multiple aliases prefers the shortest", unicode: "👍", expectedName: "+1", expectedOK: true},
+		{description: "Emoji with aliases of equal length is deterministic", unicode: "😆", expectedName: "laughing", expectedOK: true},
+		{description: "Emoji with variation selector", unicode: "❤️", expectedName: "heart", expectedOK: true},
+		{description: "Emoji without variation selector", unicode: "❤", expectedName: "heart", expectedOK: true},
+		{description: "Multi code point emoji", unicode: "🇺🇸", expectedName: "us", expectedOK: true},
+		{description: "Surrounding whitespace", unicode: " 🤫 ", expectedName: "shushing_face", expectedOK: true},
+		{description: "Not an emoji", unicode: "a", expectedName: "", expectedOK: false},
+		{description: "Empty", unicode: "", expectedName: "", expectedOK: false},
+	} {
+		t.Run(testCase.description, func(t *testing.T) {
+			name, ok := NameFromUnicode(testCase.unicode)
+			assert.Equal(t, testCase.expectedOK, ok)
+			assert.Equal(t, testCase.expectedName, name)
+		})
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	for _, unicode := range []string{"😀", "😂", "👍", "👎", "❤️", "🎉", "🔥", "✅", "🙏", "😢", "😮", "😠", "👀", "🚀", "💯"} {
+		t.Run(unicode, func(t *testing.T) {
+			name, ok := NameFromUnicode(unicode)
+			assert.True(t, ok)
+			assert.Equal(t, unicode, nameToUnicode[name])
+		})
+	}
+}
+
+func TestNameFromTeamsEmoticon(t *testing.T) {
+	for _, testCase := range []struct {
+		description  string
+		id           string
+		alt          string
+		expectedName string
+		expectedOK   bool
+	}{
+		{description: "Resolved from alt text", id: "lipssealed", alt: "🤫", expectedName: "shushing_face", expectedOK: true},
+		{description: "Resolved from code point id", id: "1f61b_facewithtongue", alt: "", expectedName: "stuck_out_tongue", expectedOK: true},
+		{description: "Resolved from legacy id", id: "cool", alt: "", expectedName: "sunglasses", expectedOK: true},
+		{description: "Resolved from reaction id", id: "like", alt: "", expectedName: "+1", expectedOK: true},
+		{description: "Resolved from reaction id ignoring case", id: "CheckmarkButton", alt: "", expectedName: "white_check_mark", expectedOK: true},
+		{description: "Resolved from unicode id", id: "🚀", alt: "", expectedName: "rocket", expectedOK: true},
+		{description: "Unknown", id: "somethingnew", alt: "", expectedName: "", expectedOK: false},
+	} {
+		t.Run(testCase.description, func(t *testing.T) {
+			name, ok := NameFromTeamsEmoticon(testCase.id, testCase.alt)
+			assert.Equal(t, testCase.expectedOK, ok)
+			assert.Equal(t, testCase.expectedName, name)
+		})
+	}
+}
+
+func TestUnicodeFromTeamsEmoticon(t *testing.T) {
+	for _, testCase := range []struct {
+		description     string
+		id              string
+		alt             string
+		expectedUnicode string
+		expectedOK      bool
+	}{
+		{description: "Alt text kept as is", id: "lipssealed", alt: "🤫", expectedUnicode: "🤫", expectedOK: true},
+		{description: "Alt text with variation selector kept as is", id: "heart", alt: "❤️", expectedUnicode: "❤️", expectedOK: true},
+		{description: "Resolved from code point id", id: "1f61b_facewithtongue", alt: "", expectedUnicode: "😛", expectedOK: true},
+		{description: "Resolved from legacy id", id: "cool", alt: "(cool)", expectedUnicode: "😎", expectedOK: true},
+		{description: "Unknown", id: "somethingnew", alt: "(somethingnew)", expectedUnicode: "", expectedOK: false},
+	} {
+		t.Run(testCase.description, func(t *testing.T) {
+			unicode, ok := UnicodeFromTeamsEmoticon(testCase.id, testCase.alt)
+			assert.Equal(t, testCase.expectedOK, ok)
+			assert.Equal(t, testCase.expectedUnicode, unicode)
+		})
+	}
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/mattermost/mattermost-plugin-msteams/server/msteams"
+)
+
+// remediationForError describes, for the user, what went wrong talking to MS Teams and how to
+// resolve it.
+func remediationForError(err error) string {
+	switch msteams.CategorizeError(err) {
+	case msteams.ErrorCategoryAuth:
+		return "Microsoft did not accept your sign-in. Please sign in to Microsoft Teams again, and contact your system administrator if the problem persists."
+	case msteams.ErrorCategoryPermission:
+		return "Microsoft Teams denied access. Please ask your system administrator to check the permissions granted to the application in Microsoft Entra ID."
+	case msteams.ErrorCategoryThrottling:
+		return "Microsoft Teams is limiting requests right now. Please wait a few minutes and try again."
+	case msteams.ErrorCategoryNotFound:
+		return "The requested item could not be found in Microsoft Teams. It may have been deleted, or you may no longer have access to it."
+	case msteams.ErrorCategoryConversion:
+		return "Microsoft Teams returned an unexpected response. Please contact your system administrator."
+	default:
+		return "Something went wrong while contacting Microsoft Teams. Please try again, and contact your system administrator if the problem persists."
+	}
+}
+
+// storeErrorRemediation is reported when the plugin cannot read or write its own data, which
+// retrying rarely resolves.
+const storeErrorRemediation = "The plugin could not access its stored data. Please contact your system administrator, who can find the details in the server logs."
+
+// httpStatusForError returns the status code with which to report an error from MS Teams to
+// the caller of a REST endpoint.
+func httpStatusForError(err error) int {
+	switch msteams.CategorizeError(err) {
+	case msteams.ErrorCategoryAuth:
+		return http.StatusUnauthorized
+	case msteams.ErrorCategoryPermission:
+		return http.StatusForbidden
+	case msteams.ErrorCategoryThrottling:
+		return http.StatusTooManyRequests
+	case msteams.ErrorCategoryNotFound:
+		return http.StatusNotFound
+	case msteams.ErrorCategoryConversion:
+		return http.StatusBadGateway
+	default:
+		return http.StatusInternalServerError
+	}
+}
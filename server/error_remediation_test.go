@@ -0,0 +1,30 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/mattermost/mattermost-plugin-msteams/server/msteams"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHTTPStatusForError(t *testing.T) {
+	for _, testCase := range []struct {
+		Name     string
+		Err      error
+		Expected int
+	}{
+		{"unknown error", errors.New("connection reset"), http.StatusInternalServerError},
+		{"oauth error", errors.New(`oauth2: "invalid_grant"`), http.StatusUnauthorized},
+		{"forbidden", &msteams.GraphAPIError{StatusCode: http.StatusForbidden}, http.StatusForbidden},
+		{"not found", &msteams.GraphAPIError{StatusCode: http.StatusNotFound}, http.StatusNotFound},
+		{"throttled", &msteams.GraphAPIError{StatusCode: http.StatusTooManyRequests}, http.StatusTooManyRequests},
+		{"conversion", &msteams.ConversionError{Message: "received empty user ID from MS Graph"}, http.StatusBadGateway},
+	} {
+		t.Run(testCase.Name, func(t *testing.T) {
+			assert.Equal(t, testCase.Expected, httpStatusForError(testCase.Err))
+			assert.NotEmpty(t, remediationForError(testCase.Err))
+		})
+	}
+}
@@ -0,0 +1,331 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/pkg/errors"
+)
+
+const (
+	featureFlagsKey = "feature_flags"
+
+	featureFlagFileAttachments = "file_attachments"
+	featureFlagPraise          = "praise"
+
+	// featureFlagsCacheTTL bounds how long a node keeps using the feature flags and team
+	// memberships it read, so changes made on another node apply within this delay.
+	featureFlagsCacheTTL = time.Minute
+)
+
+// featureFlag gates a part of the sync, so it can be piloted by some users or teams before being
+// enabled for everyone.
+type featureFlag struct {
+	name           string
+	description    string
+	defaultEnabled bool
+}
+
+var featureFlags = []featureFlag{
+	{featureFlagFileAttachments, "Deliver the files attached to chat messages", true},
+	{featureFlagPraise, "Render praise sent in chats", true},
+}
+
+func getFeatureFlag(name string) (featureFlag, bool) {
+	for _, flag := range featureFlags {
+		if flag.name == name {
+			return flag, true
+		}
+	}
+
+	return featureFlag{}, false
+}
+
+// FeatureFlagState overrides the default of a feature flag. A flag disabled for everyone remains
+// enabled for the targeted users and the members of the targeted teams.
+type FeatureFlagState struct {
+	Enabled *bool    `json:"enabled,omitempty"`
+	UserIDs []string `json:"user_ids,omitempty"`
+	TeamIDs []string `json:"team_ids,omitempty"`
+}
+
+func (s *FeatureFlagState) isEnabled(flag featureFlag) bool {
+	if s.Enabled != nil {
+		return *s.Enabled
+	}
+
+	return flag.defaultEnabled
+}
+
+func (p *Plugin) getFeatureFlagStates() (map[string]*FeatureFlagState, error) {
+	states := make(map[string]*FeatureFlagState)
+	if err := p.apiClient.KV.Get(featureFlagsKey, &states); err != nil {
+		return nil, errors.Wrap(err, "failed to get the feature flags")
+	}
+
+	return states, nil
+}
+
+// featureFlagsCache holds the feature flag states and the team memberships checked against them,
+// read at most once per featureFlagsCacheTTL on the notification path.
+type featureFlagsCache struct {
+	mutex       sync.Mutex
+	states      map[string]*FeatureFlagState
+	teamMembers map[string]bool
+	loadedAt    time.Time
+}
+
+// invalidate makes the next lookup read the feature flags again.
+func (c *featureFlagsCache) invalidate() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.states = nil
+}
+
+// getCachedFeatureFlagStates returns the feature flag states, reading them again once the cached
+// ones have expired.
+func (p *Plugin) getCachedFeatureFlagStates() (map[string]*FeatureFlagState, error) {
+	p.featureFlagsCache.mutex.Lock()
+	defer p.featureFlagsCache.mutex.Unlock()
+
+	if p.featureFlagsCache.states != nil && time.Since(p.featureFlagsCache.loadedAt) < featureFlagsCacheTTL {
+		return p.featureFlagsCache.states, nil
+	}
+
+	states, err := p.getFeatureFlagStates()
+	if err != nil {
+		return nil, err
+	}
+
+	p.featureFlagsCache.states = states
+	p.featureFlagsCache.teamMembers = make(map[string]bool)
+	p.featureFlagsCache.loadedAt = time.Now()
+
+	return states, nil
+}
+
+// isCachedTeamMember reports whether the given user is an active member of the given team,
+// caching the answer along with the feature flag states.
+func (p *Plugin) isCachedTeamMember(teamID, userID string) bool {
+	key := teamID + ":" + userID
+
+	p.featureFlagsCache.mutex.Lock()
+	isMember, ok := p.featureFlagsCache.teamMembers[key]
+	p.featureFlagsCache.mutex.Unlock()
+	if ok {
+		return isMember
+	}
+
+	member, appErr := p.API.GetTeamMember(teamID, userID)
+	isMember = appErr == nil && member.DeleteAt == 0
+
+	p.featureFlagsCache.mutex.Lock()
+	if p.featureFlagsCache.teamMembers != nil {
+		p.featureFlagsCache.teamMembers[key] = isMember
+	}
+	p.featureFlagsCache.mutex.Unlock()
+
+	return isMember
+}
+
+// updateFeatureFlagState atomically changes the state of the given feature flag.
+func (p *Plugin) updateFeatureFlagState(name string, update func(state *FeatureFlagState)) error {
+	defer p.featureFlagsCache.invalidate()
+
+	return p.apiClient.KV.SetAtomicWithRetries(featureFlagsKey, func(oldValue []byte) (any, error) {
+		states := make(map[string]*FeatureFlagState)
+		if oldValue != nil {
+			if err := json.Unmarshal(oldValue, &states); err != nil {
+				return nil, errors.Wrap(err, "failed to decode the feature flags")
+			}
+		}
+
+		state := states[name]
+		if state == nil {
+			state = &FeatureFlagState{}
+			states[name] = state
+		}
+		update(state)
+
+		return json.Marshal(states)
+	})
+}
+
+// isFeatureEnabled reports whether the given feature is enabled for the given user. Unknown flags
+// are disabled, and flags whose state cannot be read fall back to their default. The states are
+// cached, so changes made on other nodes may take up to featureFlagsCacheTTL to apply.
+func (p *Plugin) isFeatureEnabled(name, userID string) bool {
+	flag, ok := getFeatureFlag(name)
+	if !ok {
+		return false
+	}
+
+	states, err := p.getCachedFeatureFlagStates()
+	if err != nil {
+		p.API.LogWarn("Unable to get the feature flags", "error", err.Error())
+		return flag.defaultEnabled
+	}
+
+	state := states[name]
+	if state == nil {
+		return flag.defaultEnabled
+	}
+	if state.isEnabled(flag) {
+		return true
+	}
+
+	for _, targetUserID := range state.UserIDs {
+		if targetUserID == userID {
+			return true
+		}
+	}
+	for _, teamID := range state.TeamIDs {
+		if p.isCachedTeamMember(teamID, userID) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func addTarget(targets []string, target string) []string {
+	for _, existing := range targets {
+		if existing == target {
+			return targets
+		}
+	}
+
+	return append(targets, target)
+}
+
+func removeTarget(targets []string, target string) []string {
+	remaining := []string{}
+	for _, existing := range targets {
+		if existing != target {
+			remaining = append(remaining, existing)
+		}
+	}
+
+	return remaining
+}
+
+func (p *Plugin) executeFeatureCommand(args *model.CommandArgs, parameters []string) (*model.CommandResponse, *model.AppError) {
+	if !p.API.HasPermissionTo(args.UserId, model.PermissionManageSystem) {
+		return p.cmdError(args, "Unable to execute the command, only system admins have access to execute this command.")
+	}
+
+	if len(parameters) == 0 || parameters[0] == "list" {
+		return p.executeFeatureListCommand(args)
+	}
+
+	action := parameters[0]
+	if (action != "enable" && action != "disable") || len(parameters) < 2 || len(parameters) > 3 {
+		return p.cmdError(args, "Invalid feature command. Use `list`, `enable <flag> [@username|team]` or `disable <flag> [@username|team]`.")
+	}
+
+	flag, ok := getFeatureFlag(parameters[1])
+	if !ok {
+		return p.cmdError(args, fmt.Sprintf("Unknown feature flag `%s`.", parameters[1]))
+	}
+
+	enable := action == "enable"
+	var update func(state *FeatureFlagState)
+	var description string
+	switch {
+	case len(parameters) == 2:
+		update = func(state *FeatureFlagState) {
+			state.Enabled = model.NewBool(enable)
+		}
+		description = "everyone"
+	case strings.HasPrefix(parameters[2], "@"):
+		username := strings.TrimPrefix(parameters[2], "@")
+		user, appErr := p.API.GetUserByUsername(username)
+		if appErr != nil {
+			return p.cmdError(args, fmt.Sprintf("Unable to find the user `%s`.", parameters[2]))
+		}
+		update = func(state *FeatureFlagState) {
+			if enable {
+				state.UserIDs = addTarget(state.UserIDs, user.Id)
+			} else {
+				state.UserIDs = removeTarget(state.UserIDs, user.Id)
+			}
+		}
+		description = "@" + user.Username
+	default:
+		team, appErr := p.API.GetTeamByName(parameters[2])
+		if appErr != nil {
+			return p.cmdError(args, fmt.Sprintf("Unable to find the team `%s`.", parameters[2]))
+		}
+		update = func(state *FeatureFlagState) {
+			if enable {
+				state.TeamIDs = addTarget(state.TeamIDs, team.Id)
+			} else {
+				state.TeamIDs = removeTarget(state.TeamIDs, team.Id)
+			}
+		}
+		description = "the members of " + team.DisplayName
+	}
+
+	if err := p.updateFeatureFlagState(flag.name, update); err != nil {
+		p.API.LogWarn("Unable to update the feature flag", "flag", flag.name, "error", err.Error())
+		return p.cmdError(args, "Unable to update the feature flag. "+storeErrorRemediation)
+	}
+
+	p.API.LogInfo("Feature flag updated", "flag", flag.name, "enabled", enable, "target", description, "user_id", args.UserId)
+
+	if enable {
+		return p.cmdSuccess(args, fmt.Sprintf("Feature `%s` enabled for %s.", flag.name, description))
+	}
+	if len(parameters) == 2 {
+		return p.cmdSuccess(args, fmt.Sprintf("Feature `%s` disabled for everyone, except the users and teams it is still enabled for.", flag.name))
+	}
+	// Targets only opt in to a flag disabled for everyone: they cannot opt out of it.
+	return p.cmdSuccess(args, fmt.Sprintf("Feature `%s` no longer specifically enabled for %s, who follow the setting for everyone.", flag.name, description))
+}
+
+func (p *Plugin) executeFeatureListCommand(args *model.CommandArgs) (*model.CommandResponse, *model.AppError) {
+	states, err := p.getFeatureFlagStates()
+	if err != nil {
+		p.API.LogWarn("Unable to get the feature flags", "error", err.Error())
+		return p.cmdError(args, "Unable to get the feature flags. "+storeErrorRemediation)
+	}
+
+	lines := []string{
+		"| Feature | Description | Everyone | Also enabled for |",
+		"| --- | --- | --- | --- |",
+	}
+	for _, flag := range featureFlags {
+		state := states[flag.name]
+		if state == nil {
+			state = &FeatureFlagState{}
+		}
+
+		everyone := "Disabled"
+		if state.isEnabled(flag) {
+			everyone = "Enabled"
+		}
+
+		var targets []string
+		for _, userID := range state.UserIDs {
+			if user, appErr := p.API.GetUser(userID); appErr == nil {
+				targets = append(targets, "@"+user.Username)
+			}
+		}
+		for _, teamID := range state.TeamIDs {
+			if team, appErr := p.API.GetTeam(teamID); appErr == nil {
+				targets = append(targets, team.Name)
+			}
+		}
+		sort.Strings(targets)
+
+		lines = append(lines, fmt.Sprintf("| %s | %s | %s | %s |", flag.name, flag.description, everyone, strings.Join(targets, ", ")))
+	}
+
+	return p.cmdSuccess(args, strings.Join(lines, "\n"))
+}
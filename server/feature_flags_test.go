@@ -0,0 +1,92 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFeatureFlagStateIsEnabled(t *testing.T) {
+	enabledByDefault := featureFlag{name: "enabled", defaultEnabled: true}
+	disabledByDefault := featureFlag{name: "disabled"}
+
+	assert.True(t, (&FeatureFlagState{}).isEnabled(enabledByDefault))
+	assert.False(t, (&FeatureFlagState{}).isEnabled(disabledByDefault))
+	assert.False(t, (&FeatureFlagState{Enabled: model.NewBool(false)}).isEnabled(enabledByDefault))
+	assert.True(t, (&FeatureFlagState{Enabled: model.NewBool(true)}).isEnabled(disabledByDefault))
+}
+
+func TestIsFeatureEnabled(t *testing.T) {
+	th := setupTestHelper(t)
+	team := th.SetupTeam(t)
+	otherTeam := th.SetupTeam(t)
+	user := th.SetupUser(t, team)
+	otherUser := th.SetupUser(t, otherTeam)
+
+	reset := func(t *testing.T) {
+		t.Helper()
+		require.Nil(t, th.p.API.KVDelete(featureFlagsKey))
+		th.p.featureFlagsCache.invalidate()
+	}
+	t.Cleanup(func() { reset(t) })
+
+	t.Run("unknown flag", func(t *testing.T) {
+		reset(t)
+		assert.False(t, th.p.isFeatureEnabled("unknown", user.Id))
+	})
+
+	t.Run("default", func(t *testing.T) {
+		reset(t)
+		assert.True(t, th.p.isFeatureEnabled(featureFlagFileAttachments, user.Id))
+	})
+
+	t.Run("disabled for everyone", func(t *testing.T) {
+		reset(t)
+		require.NoError(t, th.p.updateFeatureFlagState(featureFlagFileAttachments, func(state *FeatureFlagState) {
+			state.Enabled = model.NewBool(false)
+		}))
+		assert.False(t, th.p.isFeatureEnabled(featureFlagFileAttachments, user.Id))
+	})
+
+	t.Run("piloted by a user", func(t *testing.T) {
+		reset(t)
+		require.NoError(t, th.p.updateFeatureFlagState(featureFlagFileAttachments, func(state *FeatureFlagState) {
+			state.Enabled = model.NewBool(false)
+			state.UserIDs = addTarget(state.UserIDs, user.Id)
+		}))
+		assert.True(t, th.p.isFeatureEnabled(featureFlagFileAttachments, user.Id))
+		assert.False(t, th.p.isFeatureEnabled(featureFlagFileAttachments, otherUser.Id))
+	})
+
+	t.Run("piloted by a team", func(t *testing.T) {
+		reset(t)
+		require.NoError(t, th.p.updateFeatureFlagState(featureFlagFileAttachments, func(state *FeatureFlagState) {
+			state.Enabled = model.NewBool(false)
+			state.TeamIDs = addTarget(state.TeamIDs, otherTeam.Id)
+		}))
+		assert.False(t, th.p.isFeatureEnabled(featureFlagFileAttachments, user.Id))
+		assert.True(t, th.p.isFeatureEnabled(featureFlagFileAttachments, otherUser.Id))
+	})
+
+	t.Run("cached until expired or updated", func(t *testing.T) {
+		reset(t)
+		assert.True(t, th.p.isFeatureEnabled(featureFlagFileAttachments, user.Id))
+
+		_, err := th.p.apiClient.KV.Set(featureFlagsKey, map[string]*FeatureFlagState{
+			featureFlagFileAttachments: {Enabled: model.NewBool(false)},
+		})
+		require.NoError(t, err)
+		assert.True(t, th.p.isFeatureEnabled(featureFlagFileAttachments, user.Id))
+
+		th.p.featureFlagsCache.loadedAt = time.Now().Add(-featureFlagsCacheTTL)
+		assert.False(t, th.p.isFeatureEnabled(featureFlagFileAttachments, user.Id))
+
+		require.NoError(t, th.p.updateFeatureFlagState(featureFlagFileAttachments, func(state *FeatureFlagState) {
+			state.Enabled = model.NewBool(true)
+		}))
+		assert.True(t, th.p.isFeatureEnabled(featureFlagFileAttachments, user.Id))
+	})
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/url"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"github.com/mattermost/mattermost-plugin-msteams/server/msteams"
+	"github.com/pkg/errors"
+)
+
+const fileScanTimeout = 30 * time.Second
+
+var fileScanHTTPClient = &http.Client{Timeout: fileScanTimeout}
+
+// scanFile submits the given file to the configured scanning endpoint, returning true if the file
+// is clean. The endpoint receives the raw file as the request body, and the percent-encoded file
+// name in the X-File-Name header. It must respond with 200 OK for a clean file or 403 Forbidden for
+// an infected one, optionally describing the finding in the response body. Any other response is
+// treated as an error.
+func scanFile(scanURL, fileName string, content io.Reader) (bool, string, error) {
+	req, err := http.NewRequest(http.MethodPost, scanURL, content)
+	if err != nil {
+		return false, "", errors.Wrap(err, "failed to create file scan request")
+	}
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set("X-File-Name", url.PathEscape(fileName))
+
+	resp, err := fileScanHTTPClient.Do(req)
+	if err != nil {
+		return false, "", errors.Wrap(err, "failed to submit file for scanning")
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	reason := strings.TrimSpace(string(body))
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return true, "", nil
+	case http.StatusForbidden:
+		return false, reason, nil
+	default:
+		return false, "", errors.Errorf("unexpected file scan response status %d", resp.StatusCode)
+	}
+}
+
+// checkFileScan scans the given file if a scanning endpoint is configured, returning true if the
+// file may be uploaded. Files that fail the scan, or that cannot be scanned, are quarantined: they
+// are never uploaded, and the event is recorded in the audit log.
+func (ah *ActivityHandler) checkFileScan(logger *activityLogger, fileName string, content io.Reader) bool {
+	scanURL := ah.plugin.getConfiguration().FileScanURL
+	if scanURL == "" {
+		return true
+	}
+
+	clean, reason, err := scanFile(scanURL, fileName, content)
+	if err != nil {
+		logger.LogWarn("Failed to scan file from MS Teams, quarantining", "filename", fileName, "error", err.Error())
+		ah.plugin.audit(auditEventFileQuarantined, auditActorSystem, auditStatusFail, "filename", fileName, "error", err.Error())
+		return false
+	}
+
+	if !clean {
+		logger.LogWarn("File from MS Teams failed scan, quarantining", "filename", fileName, "reason", reason)
+		ah.plugin.audit(auditEventFileQuarantined, auditActorSystem, auditStatusSuccess, "filename", fileName, "reason", reason)
+		return false
+	}
+
+	return true
+}
+
+// checkStreamedFileScan scans a file too large to download completely, streaming it from MS Teams
+// to the scanning endpoint instead of reading it into memory. Clean files are downloaded again to
+// be uploaded.
+func (ah *ActivityHandler) checkStreamedFileScan(logger *activityLogger, fileName, downloadURL string, client msteams.Client) bool {
+	pipeReader, pipeWriter := io.Pipe()
+	// Stop the download if the scanning endpoint doesn't read the whole file.
+	defer pipeReader.Close()
+
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				ah.plugin.GetMetrics().ObserveGoroutineFailure()
+				logger.LogError("Recovering from panic", "panic", r, "stack", string(debug.Stack()))
+			}
+		}()
+
+		client.GetFileContentStream(downloadURL, pipeWriter, int64(ah.plugin.GetBufferSizeForStreaming()*1024*1024))
+	}()
+
+	return ah.checkFileScan(logger, fileName, pipeReader)
+}
+
+// appendQuarantinedFileNotice appends a notice in place of a file that failed scanning.
+func appendQuarantinedFileNotice(text, fileName string) string {
+	notice := "_The file \"" + fileName + "\" was quarantined by your system administrator's file scanning policy._"
+	if strings.TrimSpace(text) == "" {
+		return notice
+	}
+
+	return text + "\n" + notice
+}
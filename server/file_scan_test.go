@@ -0,0 +1,60 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScanFile(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if r.Header.Get("X-File-Name") != "file.txt" {
+			body = []byte("name " + r.Header.Get("X-File-Name"))
+		}
+
+		switch string(body) {
+		case "name r%C3%A9sum%C3%A9%0A.txt":
+			w.WriteHeader(http.StatusOK)
+		case "clean":
+			w.WriteHeader(http.StatusOK)
+		case "infected":
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = w.Write([]byte("Eicar-Test-Signature"))
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	t.Run("clean file", func(t *testing.T) {
+		clean, reason, err := scanFile(server.URL, "file.txt", strings.NewReader("clean"))
+		require.NoError(t, err)
+		assert.True(t, clean)
+		assert.Empty(t, reason)
+	})
+
+	t.Run("infected file", func(t *testing.T) {
+		clean, reason, err := scanFile(server.URL, "file.txt", strings.NewReader("infected"))
+		require.NoError(t, err)
+		assert.False(t, clean)
+		assert.Equal(t, "Eicar-Test-Signature", reason)
+	})
+
+	t.Run("scanner error", func(t *testing.T) {
+		clean, _, err := scanFile(server.URL, "file.txt", strings.NewReader("other"))
+		require.Error(t, err)
+		assert.False(t, clean)
+	})
+
+	t.Run("file name encoded", func(t *testing.T) {
+		clean, _, err := scanFile(server.URL, "résumé\n.txt", strings.NewReader("clean"))
+		require.NoError(t, err)
+		assert.True(t, clean)
+	})
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mattermost/mattermost-plugin-msteams/server/metrics"
+	"github.com/mattermost/mattermost/server/public/model"
+)
+
+const graphAPIFamilyOther = "other"
+
+// graphAPIFamilies maps fragments of the MS Graph client method names to the API family they
+// belong to. The first match wins, so more specific fragments come first.
+var graphAPIFamilies = []struct {
+	fragment string
+	family   string
+}{
+	{"Subscri", "subscriptions"},
+	{"Connect", "auth"},
+	{"RefreshToken", "auth"},
+	{"Presence", "presence"},
+	{"File", "files"},
+	{"CodeSnippet", "files"},
+	{"Message", "messages"},
+	{"Reply", "messages"},
+	{"Reaction", "messages"},
+	{"Chat", "chats"},
+	{"Team", "teams"},
+	{"Channel", "teams"},
+	{"User", "users"},
+	{"GetMe", "users"},
+	{"GetMyID", "users"},
+	{"App", "apps"},
+}
+
+// graphAPIFamily returns the API family of an MS Graph client method, as named by the timer
+// layer, e.g. "Client.GetChat".
+func graphAPIFamily(method string) string {
+	method = strings.TrimPrefix(method, "Client.")
+	for _, candidate := range graphAPIFamilies {
+		if strings.Contains(method, candidate.fragment) {
+			return candidate.family
+		}
+	}
+
+	return graphAPIFamilyOther
+}
+
+type GraphUsageStats struct {
+	Family    string `json:"family"`
+	Requests  int64  `json:"requests"`
+	Failures  int64  `json:"failures"`
+	Throttled int64  `json:"throttled"`
+}
+
+// graphUsage accounts for the MS Graph requests made by this server since the plugin started.
+type graphUsage struct {
+	mutex    sync.Mutex
+	since    time.Time
+	families map[string]*GraphUsageStats
+}
+
+func newGraphUsage() *graphUsage {
+	return &graphUsage{
+		since:    time.Now(),
+		families: make(map[string]*GraphUsageStats),
+	}
+}
+
+func (u *graphUsage) record(method, success, statusCode string) {
+	family := graphAPIFamily(method)
+
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+
+	stats, ok := u.families[family]
+	if !ok {
+		stats = &GraphUsageStats{Family: family}
+		u.families[family] = stats
+	}
+
+	stats.Requests++
+	if success != "true" {
+		stats.Failures++
+	}
+	if statusCode == strconv.Itoa(http.StatusTooManyRequests) {
+		stats.Throttled++
+	}
+}
+
+// snapshot returns a copy of the usage of each API family, sorted by family.
+func (u *graphUsage) snapshot() (time.Time, []GraphUsageStats) {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+
+	families := make([]GraphUsageStats, 0, len(u.families))
+	for _, stats := range u.families {
+		families = append(families, *stats)
+	}
+	sort.Slice(families, func(i, j int) bool {
+		return families[i].Family < families[j].Family
+	})
+
+	return u.since, families
+}
+
+// graphUsageMetrics records the MS Graph requests observed by the client timer layer into the
+// usage accounting before passing them on to the metrics service.
+type graphUsageMetrics struct {
+	metrics.Metrics
+	usage *graphUsage
+}
+
+func (m *graphUsageMetrics) ObserveMSGraphClientMethodDuration(method, success, statusCode string, elapsed float64) {
+	m.usage.record(method, success, statusCode)
+	m.Metrics.ObserveMSGraphClientMethodDuration(method, success, statusCode, elapsed)
+}
+
+// graphStats reports the MS Graph requests, failures and throttled responses of each API family
+// since the plugin started on this server.
+func (a *API) graphStats(w http.ResponseWriter, r *http.Request) {
+	userID := r.Header.Get("Mattermost-User-ID")
+
+	if !a.p.API.HasPermissionTo(userID, model.PermissionManageSystem) {
+		a.p.API.LogWarn("Insufficient permissions", "user_id", userID)
+		http.Error(w, "not able to authorize the user", http.StatusForbidden)
+		return
+	}
+
+	since, families := a.p.graphUsage.snapshot()
+
+	a.returnJSON(w, struct {
+		Since    int64             `json:"since"`
+		Families []GraphUsageStats `json:"families"`
+	}{
+		Since:    since.UnixMilli(),
+		Families: families,
+	})
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGraphAPIFamily(t *testing.T) {
+	for _, testCase := range []struct {
+		Method   string
+		Expected string
+	}{
+		{"Client.Connect", "auth"},
+		{"Client.RefreshToken", "auth"},
+		{"Client.GetChat", "chats"},
+		{"Client.CreateOrGetChatForUsers", "chats"},
+		{"Client.GetChatMessage", "messages"},
+		{"Client.GetReply", "messages"},
+		{"Client.SetChatReaction", "messages"},
+		{"Client.SubscribeToUserChats", "subscriptions"},
+		{"Client.RefreshSubscription", "subscriptions"},
+		{"Client.GetPresencesForUsers", "presence"},
+		{"Client.GetHostedFileContent", "files"},
+		{"Client.GetCodeSnippet", "files"},
+		{"Client.GetTeam", "teams"},
+		{"Client.ListChannels", "teams"},
+		{"Client.ListTeamMembers", "teams"},
+		{"Client.GetUserAvatar", "users"},
+		{"Client.GetMyID", "users"},
+		{"Client.GetApp", "apps"},
+		{"Client.Unknown", "other"},
+	} {
+		t.Run(testCase.Method, func(t *testing.T) {
+			assert.Equal(t, testCase.Expected, graphAPIFamily(testCase.Method))
+		})
+	}
+}
+
+func TestGraphUsage(t *testing.T) {
+	usage := newGraphUsage()
+	usage.record("Client.GetChat", "true", "2XX")
+	usage.record("Client.GetChatMessage", "true", "2XX")
+	usage.record("Client.GetChatMessage", "false", "429")
+	usage.record("Client.GetChatMessage", "false", "404")
+
+	_, families := usage.snapshot()
+	assert.Equal(t, []GraphUsageStats{
+		{Family: "chats", Requests: 1},
+		{Family: "messages", Requests: 3, Failures: 2, Throttled: 1},
+	}, families)
+}
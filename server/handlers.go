@@ -2,20 +2,19 @@ package main
 
 import (
 	"database/sql"
-	"errors"
+	"fmt"
 	"regexp"
 	"strings"
 	"sync"
 	"time"
 
-	"github.com/enescakir/emoji"
 	"github.com/mattermost/mattermost-plugin-msteams/server/metrics"
 	"github.com/mattermost/mattermost-plugin-msteams/server/msteams"
 	"github.com/mattermost/mattermost-plugin-msteams/server/msteams/clientmodels"
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/mattermost/mattermost/server/public/plugin"
 )
 
-var emojisReverseMap map[string]string
-
 var attachRE = regexp.MustCompile(`<attachment id=.*?attachment>`)
 var imageRE = regexp.MustCompile(`<img .*?>`)
 
@@ -23,41 +22,53 @@ const (
 	numberOfWorkers             = 50
 	activityQueueSize           = 5000
 	maxFileAttachmentsSupported = 10
+
+	// activityQueueBackpressureThreshold is the queue length from which change notifications are
+	// deferred back to MS Graph, which retries them later, rather than risking a full queue.
+	activityQueueBackpressureThreshold = activityQueueSize * 9 / 10
+	activityQueueRetryAfter            = 30 * time.Second
+
+	// observeActivityQueueFrequency is how often the age of the oldest queued activity and the
+	// number of deferred activities are reported.
+	observeActivityQueueFrequency = 15 * time.Second
 )
 
 type ActivityHandler struct {
 	plugin               *Plugin
 	queue                chan msteams.Activity
+	queuedAtLock         sync.Mutex
+	queuedAt             []time.Time
 	quit                 chan bool
 	workersWaitGroup     sync.WaitGroup
 	IgnorePluginHooksMap sync.Map
 	lastUpdateAtMap      sync.Map
+	latencySLO           latencySLOTracker
+	chatFairness         chatFairness
+	deferredLock         sync.Mutex
+	deferred             map[string]*deferredChat
+	deferredCount        int
+	deferredRetryCount   int
+	messageTransformers  []messageTransformer
 }
 
 func NewActivityHandler(plugin *Plugin) *ActivityHandler {
-	// Initialize the emoji translator
-	emojisReverseMap = map[string]string{}
-	for alias, unicode := range emoji.Map() {
-		emojisReverseMap[unicode] = strings.Replace(alias, ":", "", 2)
-	}
-	emojisReverseMap["like"] = "+1"
-	emojisReverseMap["sad"] = "cry"
-	emojisReverseMap["angry"] = "angry"
-	emojisReverseMap["laugh"] = "laughing"
-	emojisReverseMap["heart"] = "heart"
-	emojisReverseMap["surprised"] = "open_mouth"
-	emojisReverseMap["checkmarkbutton"] = "white_check_mark"
-
 	return &ActivityHandler{
-		plugin: plugin,
-		queue:  make(chan msteams.Activity, activityQueueSize),
-		quit:   make(chan bool),
+		plugin:              plugin,
+		queue:               make(chan msteams.Activity, activityQueueSize),
+		quit:                make(chan bool),
+		messageTransformers: defaultMessageTransformers(),
 	}
 }
 
 func (ah *ActivityHandler) Start() {
 	ah.quit = make(chan bool)
 
+	ah.deferredLock.Lock()
+	ah.deferred = make(map[string]*deferredChat)
+	ah.deferredCount = 0
+	ah.deferredRetryCount = 0
+	ah.deferredLock.Unlock()
+
 	// This is constant for now, but report it as a metric to future proof dashboards.
 	ah.plugin.GetMetrics().ObserveChangeEventQueueCapacity(activityQueueSize)
 
@@ -66,7 +77,9 @@ func (ah *ActivityHandler) Start() {
 		for {
 			select {
 			case activity := <-ah.queue:
+				ah.dequeued()
 				ah.plugin.GetMetrics().DecrementChangeEventQueueLength(activity.ChangeType)
+				ah.plugin.GetMetrics().ObserveChangeEventQueueWaitTime(activity.ChangeType, time.Since(activity.ReceivedAt).Seconds())
 				ah.handleActivity(activity)
 			case <-ah.quit:
 				// we have received a signal to stop
@@ -104,6 +117,19 @@ func (ah *ActivityHandler) Start() {
 		}
 	}
 
+	doObserveQueue := func() {
+		ticker := time.NewTicker(observeActivityQueueFrequency)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				ah.observeQueue()
+			case <-ah.quit:
+				return
+			}
+		}
+	}
+
 	// isQuitting informs the recovery handler if the shutdown is intentional
 	isQuitting := func() bool {
 		select {
@@ -122,23 +148,121 @@ func (ah *ActivityHandler) Start() {
 	}
 	ah.workersWaitGroup.Add(1)
 	startWorker(logError, ah.plugin.GetMetrics(), isQuitting, doStartLastActivityAt, doQuit)
+	ah.workersWaitGroup.Add(1)
+	startWorker(logError, ah.plugin.GetMetrics(), isQuitting, doObserveQueue, doQuit)
+
+	// Resume in the background, so as not to delay the activation on a busy key value store.
+	go ah.resumeFromCheckpoints()
 }
 
 func (ah *ActivityHandler) Stop() {
 	close(ah.quit)
 	ah.workersWaitGroup.Wait()
+	ah.checkpointQueue()
+}
+
+// activityLogger logs on behalf of the processing of a single activity, tagging every entry with
+// the activity's correlation ID so the steps of a multi-step failure can be traced.
+type activityLogger struct {
+	api           plugin.API
+	correlationID string
+}
+
+func (ah *ActivityHandler) newActivityLogger(correlationID string) *activityLogger {
+	return &activityLogger{
+		api:           ah.plugin.GetAPI(),
+		correlationID: correlationID,
+	}
+}
+
+func (l *activityLogger) withCorrelationID(keyValuePairs []any) []any {
+	return append(keyValuePairs, "correlation_id", l.correlationID)
+}
+
+func (l *activityLogger) LogDebug(msg string, keyValuePairs ...any) {
+	l.api.LogDebug(msg, l.withCorrelationID(keyValuePairs)...)
+}
+
+func (l *activityLogger) LogInfo(msg string, keyValuePairs ...any) {
+	l.api.LogInfo(msg, l.withCorrelationID(keyValuePairs)...)
+}
+
+func (l *activityLogger) LogWarn(msg string, keyValuePairs ...any) {
+	l.api.LogWarn(msg, l.withCorrelationID(keyValuePairs)...)
+}
+
+func (l *activityLogger) LogError(msg string, keyValuePairs ...any) {
+	l.api.LogError(msg, l.withCorrelationID(keyValuePairs)...)
 }
 
 func (ah *ActivityHandler) Handle(activity msteams.Activity) error {
+	if activity.CorrelationID == "" {
+		activity.CorrelationID = model.NewId()
+	}
+	activity.ReceivedAt = time.Now()
+
+	if !ah.enqueue(activity) {
+		ah.plugin.GetMetrics().ObserveChangeEventQueueRejected()
+		return fmt.Errorf("activity queue size full (correlation_id %s)", activity.CorrelationID)
+	}
+
+	ah.newActivityLogger(activity.CorrelationID).LogDebug("Received activity", "change_type", activity.ChangeType, "subscription_id", activity.SubscriptionID)
+
+	return nil
+}
+
+// enqueue queues the given activity unless the queue is full, recording when it was queued.
+func (ah *ActivityHandler) enqueue(activity msteams.Activity) bool {
+	ah.queuedAtLock.Lock()
+	defer ah.queuedAtLock.Unlock()
+
 	select {
 	case ah.queue <- activity:
+		ah.queuedAt = append(ah.queuedAt, time.Now())
 		ah.plugin.GetMetrics().IncrementChangeEventQueueLength(activity.ChangeType)
+		return true
 	default:
-		ah.plugin.GetMetrics().ObserveChangeEventQueueRejected()
-		return errors.New("activity queue size full")
+		return false
 	}
+}
 
-	return nil
+// dequeued records that the oldest queued activity was taken from the queue.
+func (ah *ActivityHandler) dequeued() {
+	ah.queuedAtLock.Lock()
+	defer ah.queuedAtLock.Unlock()
+
+	if len(ah.queuedAt) > 0 {
+		ah.queuedAt = ah.queuedAt[1:]
+	}
+}
+
+// oldestQueuedActivityAge returns how long the oldest queued activity has been waiting, or zero if
+// the queue is empty.
+func (ah *ActivityHandler) oldestQueuedActivityAge(now time.Time) time.Duration {
+	ah.queuedAtLock.Lock()
+	defer ah.queuedAtLock.Unlock()
+
+	if len(ah.queuedAt) == 0 {
+		return 0
+	}
+
+	return now.Sub(ah.queuedAt[0])
+}
+
+// observeQueue reports the age of the oldest queued activity, and the activities deferred for
+// fairness or waiting to be retried.
+func (ah *ActivityHandler) observeQueue() {
+	ah.plugin.GetMetrics().ObserveChangeEventQueueOldestItemAge(ah.oldestQueuedActivityAge(time.Now()).Seconds())
+
+	deferred, retrying := ah.deferredActivityCounts()
+	ah.plugin.GetMetrics().ObserveChangeEventDeferredLength(metrics.DeferredReasonChatFairness, int64(deferred))
+	ah.plugin.GetMetrics().ObserveChangeEventDeferredLength(metrics.DeferredReasonRetry, int64(retrying))
+}
+
+// isUnderBackpressure reports whether the activities queued, deferred or waiting to be retried are
+// too many to accept more change notifications for now.
+func (ah *ActivityHandler) isUnderBackpressure() bool {
+	return len(ah.queue)+ah.deferredActivityCount() >= activityQueueBackpressureThreshold
 }
 
 func (ah *ActivityHandler) HandleLifecycleEvent(event msteams.Activity) {
@@ -179,35 +303,74 @@ func (ah *ActivityHandler) handleActivity(activity msteams.Activity) {
 	done := ah.plugin.GetMetrics().ObserveWorker(metrics.WorkerActivityHandler)
 	defer done()
 
+	start := time.Now()
+	resourceKind := activityResourceKind(activity.Resource)
+	logger := ah.newActivityLogger(activity.CorrelationID)
 	activityIds := msteams.GetResourceIds(activity.Resource)
 
+	// Keep a single busy chat from starving the others of the shared MS Graph request budget.
+	if activity.ChangeType == "created" && activityIds.ChatID != "" {
+		if delay := ah.chatFairness.delay(activityIds.ChatID, ah.plugin.getConfiguration().GraphRequestsPerSecond, time.Now()); delay > 0 {
+			if !ah.deferActivity(activity, delay) {
+				logger.LogWarn("Dropping activity for chat with too many deferred activities", "chat_id", activityIds.ChatID)
+				ah.plugin.GetMetrics().ObserveChangeEvent(activity.ChangeType, resourceKind, metrics.DiscardedReasonDeferredLimitReached)
+				return
+			}
+			logger.LogDebug("Deferring activity for chat exceeding its share of MS Graph requests", "chat_id", activityIds.ChatID, "delay", delay.String())
+			return
+		}
+	}
+
 	var discardedReason string
 	switch activity.ChangeType {
 	case "created":
-		discardedReason = ah.handleCreatedActivity(activityIds)
+		discardedReason = ah.handleCreatedActivity(logger, activityIds)
 	case "updated":
 		discardedReason = metrics.DiscardedReasonNotificationsOnly
 	case "deleted":
 		discardedReason = metrics.DiscardedReasonNotificationsOnly
 	default:
 		discardedReason = metrics.DiscardedReasonInvalidChangeType
-		ah.plugin.GetAPI().LogWarn("Unsupported change type", "change_type", activity.ChangeType)
+		logger.LogWarn("Unsupported change type", "change_type", activity.ChangeType)
+	}
+
+	if quarantinedReasons[discardedReason] {
+		ah.retryOrQuarantineActivity(logger, activity, discardedReason)
 	}
 
-	ah.plugin.GetMetrics().ObserveChangeEvent(activity.ChangeType, discardedReason)
+	logger.LogDebug("Processed activity", "change_type", activity.ChangeType, "resource_kind", resourceKind, "discarded_reason", discardedReason)
+	ah.plugin.GetMetrics().ObserveChangeEvent(activity.ChangeType, resourceKind, discardedReason)
+	ah.plugin.GetMetrics().ObserveChangeEventProcessingTime(activity.ChangeType, resourceKind, time.Since(start).Seconds())
+}
+
+// activityResourceKind classifies the resource an activity is about, e.g.
+// "chats('19:id')/messages('1')", for metrics.
+func activityResourceKind(resource string) string {
+	switch {
+	case strings.Contains(resource, "/members"):
+		return metrics.ResourceKindMembership
+	case strings.HasPrefix(resource, "chats(") && strings.Contains(resource, "/messages("):
+		return metrics.ResourceKindChatMessage
+	case strings.HasPrefix(resource, "teams(") && strings.Contains(resource, "/messages("):
+		return metrics.ResourceKindChannelMessage
+	default:
+		return metrics.ResourceKindUnknown
+	}
 }
 
 // handleCreatedActivity handles subscription change events of the created type, i.e. new messages.
-func (ah *ActivityHandler) handleCreatedActivity(activityIds clientmodels.ActivityIds) string {
+func (ah *ActivityHandler) handleCreatedActivity(logger *activityLogger, activityIds clientmodels.ActivityIds) string {
 	// We're only handling chats at that time.
 	if activityIds.ChatID == "" {
 		return metrics.DiscardedReasonChannelNotificationsUnsupported
 	}
 
 	// Use the application client to resolve the chat metadata.
+	start := time.Now()
 	chat, err := ah.plugin.GetClientForApp().GetChat(activityIds.ChatID)
+	ah.syncDebugGraphRequest(logger, activityIds.ChatID, "GetChat", start, err)
 	if err != nil || chat == nil {
-		ah.plugin.GetAPI().LogWarn("Failed to get chat", "chat_id", activityIds.ChatID, "error", err)
+		logger.LogWarn("Failed to get chat", "chat_id", activityIds.ChatID, "error", err)
 		return metrics.DiscardedReasonUnableToGetTeamsData
 	}
 
@@ -224,17 +387,22 @@ func (ah *ActivityHandler) handleCreatedActivity(activityIds clientmodels.Activi
 	}
 
 	// Fetch the message itself.
+	start = time.Now()
 	msg, err := client.GetChatMessage(chat.ID, activityIds.MessageID)
+	ah.syncDebugGraphRequest(logger, chat.ID, "GetChatMessage", start, err)
 	if err != nil {
-		ah.plugin.GetAPI().LogWarn("Failed to get message from chat", "chat_id", chat.ID, "message_id", activityIds.MessageID, "error", err)
+		logger.LogWarn("Failed to get message from chat", "chat_id", chat.ID, "message_id", activityIds.MessageID, "error", err)
 		return metrics.DiscardedReasonUnableToGetTeamsData
 	}
 
-	// Skip messages without a user, if this ever happens.
-	if msg.UserID == "" {
+	logger.LogDebug("Fetched chat message", "chat_id", chat.ID, "message_id", msg.ID)
+	ah.syncDebugLog(logger, chat.ID, "Received message", syncDebugMessageInput(msg, ah.plugin.getConfiguration().SyncDebugLoggingContent)...)
+
+	// Skip messages sent by neither a user nor an application, such as system events.
+	if msg.UserID == "" && msg.ApplicationID == "" {
 		return metrics.DiscardedReasonNotUserEvent
 	}
 
 	// Finally, process the notification of the chat message received.
-	return ah.handleCreatedActivityNotification(msg, chat)
+	return ah.handleCreatedActivityNotification(logger, msg, chat)
 }
@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/mattermost/mattermost-plugin-msteams/server/metrics"
+	"github.com/mattermost/mattermost-plugin-msteams/server/msteams"
 	"github.com/mattermost/mattermost-plugin-msteams/server/msteams/clientmodels"
 	"github.com/mattermost/mattermost/server/public/model"
 	"github.com/stretchr/testify/assert"
@@ -24,7 +25,7 @@ func TestHandleCreatedActivity(t *testing.T) {
 			ChannelID: model.NewId(),
 		}
 
-		discardReason := th.p.activityHandler.handleCreatedActivity(activityIds)
+		discardReason := th.p.activityHandler.handleCreatedActivity(th.p.activityHandler.newActivityLogger(model.NewId()), activityIds)
 		assert.Equal(t, metrics.DiscardedReasonChannelNotificationsUnsupported, discardReason)
 	})
 
@@ -37,7 +38,7 @@ func TestHandleCreatedActivity(t *testing.T) {
 
 		th.appClientMock.On("GetChat", activityIds.ChatID).Return(nil, errors.New("Error while getting original chat")).Times(1)
 
-		discardReason := th.p.activityHandler.handleCreatedActivity(activityIds)
+		discardReason := th.p.activityHandler.handleCreatedActivity(th.p.activityHandler.newActivityLogger(model.NewId()), activityIds)
 		assert.Equal(t, metrics.DiscardedReasonUnableToGetTeamsData, discardReason)
 	})
 
@@ -64,7 +65,7 @@ func TestHandleCreatedActivity(t *testing.T) {
 			},
 		}, nil).Times(1)
 
-		discardReason := th.p.activityHandler.handleCreatedActivity(activityIds)
+		discardReason := th.p.activityHandler.handleCreatedActivity(th.p.activityHandler.newActivityLogger(model.NewId()), activityIds)
 		assert.Equal(t, metrics.DiscardedReasonNoConnectedUser, discardReason)
 	})
 
@@ -95,7 +96,7 @@ func TestHandleCreatedActivity(t *testing.T) {
 		}, nil).Times(1)
 		th.clientMock.On("GetChatMessage", activityIds.ChatID, activityIds.MessageID).Return(nil, errors.New("failed to get chat message")).Times(1)
 
-		discardReason := th.p.activityHandler.handleCreatedActivity(activityIds)
+		discardReason := th.p.activityHandler.handleCreatedActivity(th.p.activityHandler.newActivityLogger(model.NewId()), activityIds)
 		assert.Equal(t, metrics.DiscardedReasonUnableToGetTeamsData, discardReason)
 	})
 
@@ -126,7 +127,7 @@ func TestHandleCreatedActivity(t *testing.T) {
 		}, nil).Times(1)
 		th.clientMock.On("GetChatMessage", activityIds.ChatID, activityIds.MessageID).Return(&clientmodels.Message{}, nil).Times(1)
 
-		discardReason := th.p.activityHandler.handleCreatedActivity(activityIds)
+		discardReason := th.p.activityHandler.handleCreatedActivity(th.p.activityHandler.newActivityLogger(model.NewId()), activityIds)
 		assert.Equal(t, metrics.DiscardedReasonNotUserEvent, discardReason)
 	})
 
@@ -178,7 +179,7 @@ func TestHandleCreatedActivity(t *testing.T) {
 					"t" + user1.Id: &user1Presence,
 				}, nil).Times(1)
 
-				discardReason := th.p.activityHandler.handleCreatedActivity(activityIds)
+				discardReason := th.p.activityHandler.handleCreatedActivity(th.p.activityHandler.newActivityLogger(model.NewId()), activityIds)
 				assert.Equal(t, metrics.DiscardedReasonNone, discardReason)
 
 				if params.NotificationPref && !params.OnlineInTeams {
@@ -248,7 +249,7 @@ func TestHandleCreatedActivity(t *testing.T) {
 					// no presence for user3: should always get the message
 				}, nil).Times(1)
 
-				discardReason := th.p.activityHandler.handleCreatedActivity(activityIds)
+				discardReason := th.p.activityHandler.handleCreatedActivity(th.p.activityHandler.newActivityLogger(model.NewId()), activityIds)
 				assert.Equal(t, metrics.DiscardedReasonNone, discardReason)
 
 				if params.NotificationPref && !params.OnlineInTeams {
@@ -262,4 +263,99 @@ func TestHandleCreatedActivity(t *testing.T) {
 			})
 		})
 	})
+
+	t.Run("observe only", func(t *testing.T) {
+		th.Reset(t)
+		th.setPluginConfigurationTemporarily(t, func(c *configuration) {
+			c.ObserveOnly = true
+		})
+
+		senderUser := th.SetupUser(t, team)
+		th.ConnectUser(t, senderUser.Id)
+
+		user1 := th.SetupUser(t, team)
+		th.ConnectUser(t, user1.Id)
+
+		botUser, err := th.p.apiClient.User.Get(th.p.botUserID)
+		require.NoError(t, err)
+
+		activityIds := clientmodels.ActivityIds{
+			ChatID:    "chat_id",
+			MessageID: "message_id",
+		}
+
+		mockTeams := newMockTeamsHelper(th)
+		mockTeams.registerChat(activityIds.ChatID, []*model.User{user1, senderUser})
+		mockTeams.registerChatMessage(activityIds.ChatID, activityIds.MessageID, senderUser, "message")
+
+		th.appClientMock.On("GetPresencesForUsers", []string{"t" + user1.Id}).Return(map[string]*clientmodels.Presence{}, nil).Times(1)
+
+		discardReason := th.p.activityHandler.handleCreatedActivity(th.p.activityHandler.newActivityLogger(model.NewId()), activityIds)
+		assert.Equal(t, metrics.DiscardedReasonNone, discardReason)
+
+		th.assertNoDMFromUser(t, botUser.Id, user1.Id, model.GetMillisForTime(time.Now().Add(-5*time.Second)))
+	})
+}
+
+func TestOldestQueuedActivityAge(t *testing.T) {
+	th := setupTestHelper(t)
+	ah := NewActivityHandler(th.p)
+
+	now := time.Now()
+	assert.Zero(t, ah.oldestQueuedActivityAge(now))
+
+	require.True(t, ah.enqueue(msteams.Activity{Resource: "chats('chat-id')/messages('1')"}))
+	time.Sleep(10 * time.Millisecond)
+	require.True(t, ah.enqueue(msteams.Activity{Resource: "chats('chat-id')/messages('2')"}))
+
+	later := time.Now().Add(time.Minute)
+	oldestAge := ah.oldestQueuedActivityAge(later)
+	assert.Greater(t, oldestAge, time.Minute)
+
+	<-ah.queue
+	ah.dequeued()
+	assert.Less(t, ah.oldestQueuedActivityAge(later), oldestAge)
+
+	<-ah.queue
+	ah.dequeued()
+	assert.Zero(t, ah.oldestQueuedActivityAge(time.Now()))
+}
+
+func TestActivityHandlerBackpressure(t *testing.T) {
+	ah := &ActivityHandler{queue: make(chan msteams.Activity, activityQueueSize)}
+	for i := 0; i < activityQueueBackpressureThreshold-1; i++ {
+		ah.queue <- msteams.Activity{}
+	}
+	assert.False(t, ah.isUnderBackpressure())
+
+	ah.queue <- msteams.Activity{}
+	assert.True(t, ah.isUnderBackpressure())
+
+	t.Run("deferred activities count", func(t *testing.T) {
+		ah := &ActivityHandler{queue: make(chan msteams.Activity, activityQueueSize)}
+		for i := 0; i < activityQueueBackpressureThreshold-1; i++ {
+			ah.queue <- msteams.Activity{}
+		}
+		require.True(t, ah.deferActivity(msteams.Activity{Resource: "chats('chat-id')/messages('1')"}, time.Hour))
+		assert.True(t, ah.isUnderBackpressure())
+		ah.takeDeferredActivities()
+	})
+}
+
+func TestActivityResourceKind(t *testing.T) {
+	for _, testCase := range []struct {
+		Resource string
+		Expected string
+	}{
+		{"chats('19:chat-id@unq.gbl.spaces')/messages('1677774058185')", metrics.ResourceKindChatMessage},
+		{"teams('team-id')/channels('19:channel-id@thread.tacv2')/messages('1677774058185')", metrics.ResourceKindChannelMessage},
+		{"teams('team-id')/channels('19:channel-id@thread.tacv2')/messages('1677774058185')/replies('1677774058186')", metrics.ResourceKindChannelMessage},
+		{"chats('19:chat-id@unq.gbl.spaces')/members('member-id')", metrics.ResourceKindMembership},
+		{"users('user-id')", metrics.ResourceKindUnknown},
+		{"", metrics.ResourceKindUnknown},
+	} {
+		t.Run(testCase.Resource, func(t *testing.T) {
+			assert.Equal(t, testCase.Expected, activityResourceKind(testCase.Resource))
+		})
+	}
 }
@@ -132,6 +132,8 @@ func (th *testHelper) clearDatabase(t *testing.T) {
 
 	_, err = db.Exec("DELETE FROM msteamssync_links")
 	require.NoError(t, err)
+	_, err = db.Exec("DELETE FROM msteamssync_message_stats")
+	require.NoError(t, err)
 	_, err = db.Exec("DELETE FROM msteamssync_invited_users")
 	require.NoError(t, err)
 	_, err = db.Exec("DELETE FROM msteamssync_posts")
@@ -164,6 +166,9 @@ func (th *testHelper) Reset(t *testing.T) *testHelper {
 		return clientMock
 	}
 	th.p.monitor.client = th.p.msteamsAppClient
+	th.p.monitor.checkNotificationURL = func(string) (string, error) {
+		return "", nil
+	}
 
 	var err error
 	th.metricsSnapshot, err = th.p.metricsService.GetRegistry().Gather()
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"fmt"
+	"runtime/debug"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/mattermost/mattermost-plugin-msteams/server/metrics"
+	"github.com/mattermost/mattermost/server/public/plugin"
+	"github.com/mattermost/mattermost/server/public/pluginapi/cluster"
+	"github.com/pkg/errors"
+)
+
+const jobLastRunKeyPrefix = "job_last_run_"
+
+// JobScheduler runs the plugin's periodic background jobs.
+//
+// Jobs are scheduled on all plugin instances in a cluster, but only one instance runs a given job
+// at a time. The time each job last completed is persisted in the KV store so that it survives
+// restarts and can be inspected.
+type JobScheduler struct {
+	api     plugin.API
+	metrics metrics.Metrics
+
+	jobsLock sync.Mutex
+	jobs     map[string]*cluster.Job
+}
+
+// NewJobScheduler creates a new JobScheduler with no registered jobs.
+func NewJobScheduler(api plugin.API, metrics metrics.Metrics) *JobScheduler {
+	return &JobScheduler{
+		api:     api,
+		metrics: metrics,
+		jobs:    make(map[string]*cluster.Job),
+	}
+}
+
+// Register schedules the given callback to run at the given rounded interval, replacing any job
+// previously registered with the same name. If runNow is true, the callback is also run
+// immediately in the background.
+func (s *JobScheduler) Register(name string, interval time.Duration, runNow bool, callback func()) error {
+	s.Unregister(name)
+
+	run := func() {
+		s.run(name, callback)
+	}
+
+	job, err := cluster.Schedule(s.api, name, cluster.MakeWaitForRoundedInterval(interval), run)
+	if err != nil {
+		return errors.Wrapf(err, "failed to schedule job %s", name)
+	}
+
+	s.jobsLock.Lock()
+	s.jobs[name] = job
+	s.jobsLock.Unlock()
+
+	if runNow {
+		go run()
+	}
+
+	return nil
+}
+
+// Unregister stops the job with the given name, if registered.
+func (s *JobScheduler) Unregister(name string) {
+	s.jobsLock.Lock()
+	job := s.jobs[name]
+	delete(s.jobs, name)
+	s.jobsLock.Unlock()
+
+	if job == nil {
+		return
+	}
+
+	if err := job.Close(); err != nil {
+		s.api.LogError("Failed to close background job", "job", name, "error", err)
+	}
+}
+
+// Close stops all registered jobs.
+func (s *JobScheduler) Close() {
+	s.jobsLock.Lock()
+	names := make([]string, 0, len(s.jobs))
+	for name := range s.jobs {
+		names = append(names, name)
+	}
+	s.jobsLock.Unlock()
+
+	for _, name := range names {
+		s.Unregister(name)
+	}
+}
+
+// LastRun returns the time the job with the given name last completed, or the zero time if it
+// has never run.
+func (s *JobScheduler) LastRun(name string) (time.Time, error) {
+	data, appErr := s.api.KVGet(jobLastRunKeyPrefix + name)
+	if appErr != nil {
+		return time.Time{}, errors.Wrapf(appErr, "failed to get last run of job %s", name)
+	}
+	if data == nil {
+		return time.Time{}, nil
+	}
+
+	lastRun, err := strconv.ParseInt(string(data), 10, 64)
+	if err != nil {
+		return time.Time{}, errors.Wrapf(err, "failed to parse last run of job %s", name)
+	}
+
+	return time.UnixMilli(lastRun), nil
+}
+
+// run invokes the callback of the given job, recovering from any panic and recording the time it
+// completed.
+func (s *JobScheduler) run(name string, callback func()) {
+	defer func() {
+		if r := recover(); r != nil {
+			s.metrics.ObserveGoroutineFailure()
+			s.api.LogError("Recovering from panic", "job", name, "panic", r, "stack", string(debug.Stack()))
+		}
+	}()
+
+	callback()
+
+	lastRun := fmt.Sprintf("%d", time.Now().UnixMilli())
+	if appErr := s.api.KVSet(jobLastRunKeyPrefix+name, []byte(lastRun)); appErr != nil {
+		s.api.LogWarn("Failed to store the last run of job", "job", name, "error", appErr)
+	}
+}
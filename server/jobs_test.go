@@ -0,0 +1,48 @@
+package main
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJobScheduler(t *testing.T) {
+	th := setupTestHelper(t)
+
+	t.Run("never run", func(t *testing.T) {
+		lastRun, err := th.p.jobs.LastRun("test_job_never_run")
+		require.NoError(t, err)
+		assert.True(t, lastRun.IsZero())
+	})
+
+	t.Run("run now records last run", func(t *testing.T) {
+		var runs int32
+		err := th.p.jobs.Register("test_job_run_now", time.Hour, true, func() {
+			atomic.AddInt32(&runs, 1)
+		})
+		require.NoError(t, err)
+		t.Cleanup(func() { th.p.jobs.Unregister("test_job_run_now") })
+
+		assert.Eventually(t, func() bool {
+			lastRun, err := th.p.jobs.LastRun("test_job_run_now")
+			return err == nil && !lastRun.IsZero()
+		}, 5*time.Second, 50*time.Millisecond)
+		assert.EqualValues(t, 1, atomic.LoadInt32(&runs))
+	})
+
+	t.Run("panicking job does not record last run", func(t *testing.T) {
+		err := th.p.jobs.Register("test_job_panic", time.Hour, true, func() {
+			panic("test panic")
+		})
+		require.NoError(t, err)
+		t.Cleanup(func() { th.p.jobs.Unregister("test_job_panic") })
+
+		time.Sleep(500 * time.Millisecond)
+		lastRun, err := th.p.jobs.LastRun("test_job_panic")
+		require.NoError(t, err)
+		assert.True(t, lastRun.IsZero())
+	})
+}
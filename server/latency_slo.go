@@ -0,0 +1,113 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/mattermost/mattermost-plugin-msteams/server/metrics"
+	"github.com/mattermost/mattermost/server/public/model"
+)
+
+const (
+	// latencySLOSustainedPeriod is how long every notification must exceed the latency SLO
+	// before system admins are alerted.
+	latencySLOSustainedPeriod = 10 * time.Minute
+
+	// latencySLOAlertInterval is the minimum time between repeated alerts for a continuing breach.
+	latencySLOAlertInterval = 1 * time.Hour
+)
+
+// latencySLOTracker detects sustained breaches of the notification latency SLO.
+type latencySLOTracker struct {
+	lock           sync.Mutex
+	breachingSince time.Time
+	lastAlertAt    time.Time
+}
+
+// observe records the latency of a notification, returning true if system admins should now be
+// alerted to a sustained breach of the given SLO.
+func (t *latencySLOTracker) observe(latency, slo time.Duration, now time.Time) bool {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if latency <= slo {
+		t.breachingSince = time.Time{}
+		return false
+	}
+
+	if t.breachingSince.IsZero() {
+		t.breachingSince = now
+	}
+
+	if now.Sub(t.breachingSince) < latencySLOSustainedPeriod {
+		return false
+	}
+
+	if !t.lastAlertAt.IsZero() && now.Sub(t.lastAlertAt) < latencySLOAlertInterval {
+		return false
+	}
+
+	t.lastAlertAt = now
+	return true
+}
+
+// observeNotificationLatency records the delay between a message being created in MS Teams and the
+// corresponding notification being posted, alerting system admins on a sustained SLO breach.
+func (ah *ActivityHandler) observeNotificationLatency(logger *activityLogger, createAt time.Time) {
+	latency := time.Since(createAt)
+	ah.plugin.GetMetrics().ObserveMessageDelay(metrics.ActionCreated, metrics.ActionSourceMSTeams, true, latency)
+
+	sloSeconds := ah.plugin.getConfiguration().NotificationLatencySLOSeconds
+	if sloSeconds <= 0 {
+		return
+	}
+
+	slo := time.Duration(sloSeconds) * time.Second
+	if !ah.latencySLO.observe(latency, slo, time.Now()) {
+		return
+	}
+
+	logger.LogWarn("Notification latency SLO breached", "latency", latency.String(), "slo", slo.String(), "sustained_for", latencySLOSustainedPeriod.String())
+	go ah.plugin.notifyAdminsOfLatencySLOBreach(latency, slo)
+}
+
+// notifyAdminsOfLatencySLOBreach sends each system admin a direct message from the bot describing a
+// sustained breach of the notification latency SLO.
+func (p *Plugin) notifyAdminsOfLatencySLOBreach(latency, slo time.Duration) {
+	admins, err := p.listSystemAdmins()
+	if err != nil {
+		p.API.LogWarn("Failed to list system admins to notify of latency SLO breach", "error", err)
+		return
+	}
+
+	message := fmt.Sprintf(
+		"Chat notifications from MS Teams have been slower than the configured SLO of %s for at least %s. The latest notification was delivered %s after the message was sent.",
+		slo, latencySLOSustainedPeriod, latency.Round(time.Second),
+	)
+	for _, admin := range admins {
+		if err := p.botSendDirectPost(admin.Id, &model.Post{Message: message}); err != nil {
+			p.API.LogWarn("Failed to notify system admin of latency SLO breach", "user_id", admin.Id, "error", err)
+		}
+	}
+}
+
+// listSystemAdmins returns every active system admin, page by page.
+func (p *Plugin) listSystemAdmins() ([]*model.User, error) {
+	var admins []*model.User
+	for page := 0; ; page++ {
+		users, err := p.apiClient.User.List(&model.UserGetOptions{
+			Role:    model.SystemAdminRoleId,
+			Active:  true,
+			Page:    page,
+			PerPage: MaxPerPage,
+		})
+		if err != nil {
+			return nil, err
+		}
+		admins = append(admins, users...)
+		if len(users) < MaxPerPage {
+			return admins, nil
+		}
+	}
+}
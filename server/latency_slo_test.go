@@ -0,0 +1,40 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLatencySLOTracker(t *testing.T) {
+	slo := 30 * time.Second
+	now := time.Now()
+
+	t.Run("within slo", func(t *testing.T) {
+		tracker := &latencySLOTracker{}
+		assert.False(t, tracker.observe(10*time.Second, slo, now))
+		assert.False(t, tracker.observe(10*time.Second, slo, now.Add(latencySLOSustainedPeriod)))
+	})
+
+	t.Run("breach not yet sustained", func(t *testing.T) {
+		tracker := &latencySLOTracker{}
+		assert.False(t, tracker.observe(time.Minute, slo, now))
+		assert.False(t, tracker.observe(time.Minute, slo, now.Add(latencySLOSustainedPeriod/2)))
+	})
+
+	t.Run("sustained breach alerts once per interval", func(t *testing.T) {
+		tracker := &latencySLOTracker{}
+		assert.False(t, tracker.observe(time.Minute, slo, now))
+		assert.True(t, tracker.observe(time.Minute, slo, now.Add(latencySLOSustainedPeriod)))
+		assert.False(t, tracker.observe(time.Minute, slo, now.Add(latencySLOSustainedPeriod+time.Minute)))
+		assert.True(t, tracker.observe(time.Minute, slo, now.Add(latencySLOSustainedPeriod+latencySLOAlertInterval)))
+	})
+
+	t.Run("recovery resets the breach", func(t *testing.T) {
+		tracker := &latencySLOTracker{}
+		assert.False(t, tracker.observe(time.Minute, slo, now))
+		assert.False(t, tracker.observe(time.Second, slo, now.Add(latencySLOSustainedPeriod/2)))
+		assert.False(t, tracker.observe(time.Minute, slo, now.Add(latencySLOSustainedPeriod)))
+	})
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"github.com/mattermost/mattermost-plugin-msteams/server/markdown"
+	"github.com/mattermost/mattermost-plugin-msteams/server/metrics"
+	"github.com/mattermost/mattermost-plugin-msteams/server/msteams/clientmodels"
+	"github.com/mattermost/mattermost/server/public/model"
+)
+
+const (
+	messageTransformerMentions     = "mentions"
+	messageTransformerEmojis       = "emojis"
+	messageTransformerImages       = "images"
+	messageTransformerMarkdown     = "markdown"
+	messageTransformerReply        = "reply"
+	messageTransformerLinkPreviews = "link_previews"
+	messageTransformerPraise       = "praise"
+	messageTransformerAttachments  = "attachments"
+	messageTransformerSubject      = "subject"
+	messageTransformerRedaction    = "redaction"
+
+	messageTransformerFileNameRedaction = "file_name_redaction"
+
+	// redactedText replaces the parts of a message matching a redaction pattern.
+	redactedText = "[redacted]"
+)
+
+// messageConversion is the state of a chat message as it passes through the message
+// transformers on its way to becoming a post.
+type messageConversion struct {
+	logger          *activityLogger
+	channelID       string
+	senderID        string
+	recipientID     string
+	msg             *clientmodels.Message
+	chat            *clientmodels.Chat
+	existingFileIDs []string
+
+	// dryRun converts the message without reaching MS Teams or the store: mentioned users are
+	// not resolved, replies are not threaded, and the attachments are left out, files being
+	// counted as skipped.
+	dryRun bool
+
+	text                   string
+	rootID                 string
+	fileIDs                model.StringArray
+	linkPreviews           []*model.SlackAttachment
+	skippedFileAttachments int
+	errorFound             bool
+}
+
+// messageTransformer is a named stage of the conversion of a chat message into a post.
+type messageTransformer struct {
+	name      string
+	transform func(ah *ActivityHandler, conversion *messageConversion)
+}
+
+// defaultMessageTransformers returns the stages converting a chat message into a post, in the
+// order they run.
+func defaultMessageTransformers() []messageTransformer {
+	return []messageTransformer{
+		{messageTransformerMentions, func(ah *ActivityHandler, c *messageConversion) {
+			c.text = ah.handleMentions(c.logger, c.msg, !c.dryRun)
+		}},
+		{messageTransformerEmojis, func(ah *ActivityHandler, c *messageConversion) {
+			c.text = ah.handleEmojis(c.logger, c.text)
+		}},
+		{messageTransformerImages, func(ah *ActivityHandler, c *messageConversion) {
+			var embeddedImages []clientmodels.Attachment
+			c.text, embeddedImages = ah.handleImages(c.text)
+			c.msg.Attachments = append(c.msg.Attachments, embeddedImages...)
+		}},
+		{messageTransformerMarkdown, func(_ *ActivityHandler, c *messageConversion) {
+			c.text = markdown.ConvertToMD(c.text)
+		}},
+		{messageTransformerReply, func(ah *ActivityHandler, c *messageConversion) {
+			if c.msg.ReplyToID == "" || c.dryRun {
+				return
+			}
+			rootInfo, _ := ah.plugin.GetStore().GetPostInfoByMSTeamsID(c.msg.ChatID+c.msg.ChannelID, c.msg.ReplyToID)
+			if rootInfo != nil {
+				c.rootID = rootInfo.MattermostID
+			}
+		}},
+		{messageTransformerLinkPreviews, func(_ *ActivityHandler, c *messageConversion) {
+			c.msg.Attachments, c.linkPreviews = handleLinkPreviews(c.msg.Attachments)
+		}},
+		{messageTransformerPraise, func(ah *ActivityHandler, c *messageConversion) {
+			if c.recipientID != "" && !ah.plugin.isFeatureEnabled(featureFlagPraise, c.recipientID) {
+				return
+			}
+			var praise []*model.SlackAttachment
+			c.msg.Attachments, praise = handlePraiseCards(c.msg.Attachments)
+			c.linkPreviews = append(c.linkPreviews, praise...)
+		}},
+		{messageTransformerAttachments, func(ah *ActivityHandler, c *messageConversion) {
+			if c.dryRun {
+				c.text = attachRE.ReplaceAllString(c.text, "")
+				_, c.skippedFileAttachments = withholdFileAttachments(c.msg.Attachments)
+				return
+			}
+
+			withheld := 0
+			if c.recipientID != "" && !ah.plugin.isFeatureEnabled(featureFlagFileAttachments, c.recipientID) {
+				c.msg.Attachments, withheld = withholdFileAttachments(c.msg.Attachments)
+				if withheld > 0 {
+					ah.plugin.GetMetrics().ObserveFiles(metrics.ActionCreated, metrics.ActionSourceMSTeams, metrics.DiscardedReasonFeatureDisabled, c.chat != nil, int64(withheld))
+				}
+			}
+
+			var parentID string
+			c.text, c.fileIDs, parentID, c.skippedFileAttachments, c.errorFound = ah.handleAttachments(c.logger, c.channelID, c.senderID, c.text, c.msg, c.chat, c.existingFileIDs)
+			c.skippedFileAttachments += withheld
+			if parentID != "" {
+				c.rootID = parentID
+			}
+		}},
+		{messageTransformerSubject, func(_ *ActivityHandler, c *messageConversion) {
+			if c.rootID == "" && c.msg.Subject != "" {
+				c.text = "## " + c.msg.Subject + "\n" + c.text
+			}
+		}},
+	}
+}
+
+// registerMessageTransformer adds a stage to the conversion of chat messages into posts, running
+// it before the named stage, or last if before is empty or unknown. Transformers must be
+// registered before the activity handler is started.
+func (ah *ActivityHandler) registerMessageTransformer(name string, transform func(ah *ActivityHandler, conversion *messageConversion), before string) {
+	transformer := messageTransformer{name: name, transform: transform}
+	for i, existing := range ah.messageTransformers {
+		if existing.name == before {
+			ah.messageTransformers = append(ah.messageTransformers[:i], append([]messageTransformer{transformer}, ah.messageTransformers[i:]...)...)
+			return
+		}
+	}
+
+	ah.messageTransformers = append(ah.messageTransformers, transformer)
+}
+
+// redactMessage replaces the parts of the converted message matching the configured redaction
+// patterns, including the text of link previews and praise cards.
+func redactMessage(ah *ActivityHandler, c *messageConversion) {
+	for _, re := range ah.plugin.getConfiguration().redactionRegexps {
+		c.text = re.ReplaceAllString(c.text, redactedText)
+		for _, attachment := range c.linkPreviews {
+			attachment.Title = re.ReplaceAllString(attachment.Title, redactedText)
+			attachment.Text = re.ReplaceAllString(attachment.Text, redactedText)
+			attachment.Fallback = re.ReplaceAllString(attachment.Fallback, redactedText)
+			attachment.Pretext = re.ReplaceAllString(attachment.Pretext, redactedText)
+		}
+	}
+}
+
+// redactFileNames replaces the parts of the attached file names matching the configured
+// redaction patterns. It must run before the files are uploaded.
+func redactFileNames(ah *ActivityHandler, c *messageConversion) {
+	for _, re := range ah.plugin.getConfiguration().redactionRegexps {
+		for i := range c.msg.Attachments {
+			c.msg.Attachments[i].Name = re.ReplaceAllString(c.msg.Attachments[i].Name, redactedText)
+		}
+	}
+}
+
+// runMessageTransformers converts a chat message by running each registered stage in order. The
+// message itself is copied first, so converting it again, e.g. for another recipient, starts from
+// the original.
+func (ah *ActivityHandler) runMessageTransformers(conversion *messageConversion) {
+	msg := *conversion.msg
+	msg.Attachments = append([]clientmodels.Attachment(nil), conversion.msg.Attachments...)
+	msg.Mentions = append([]clientmodels.Mention(nil), conversion.msg.Mentions...)
+	conversion.msg = &msg
+
+	for _, transformer := range ah.messageTransformers {
+		transformer.transform(ah, conversion)
+	}
+}
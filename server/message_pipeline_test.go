@@ -0,0 +1,102 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/mattermost/mattermost-plugin-msteams/server/msteams/clientmodels"
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegisterMessageTransformer(t *testing.T) {
+	names := func(ah *ActivityHandler) []string {
+		var result []string
+		for _, transformer := range ah.messageTransformers {
+			result = append(result, transformer.name)
+		}
+		return result
+	}
+	noop := func(_ *ActivityHandler, _ *messageConversion) {}
+
+	t.Run("before a known stage", func(t *testing.T) {
+		ah := &ActivityHandler{messageTransformers: defaultMessageTransformers()}
+		ah.registerMessageTransformer("redaction", noop, messageTransformerAttachments)
+
+		assert.Equal(t, []string{
+			messageTransformerMentions,
+			messageTransformerEmojis,
+			messageTransformerImages,
+			messageTransformerMarkdown,
+			messageTransformerReply,
+			messageTransformerLinkPreviews,
+			messageTransformerPraise,
+			"redaction",
+			messageTransformerAttachments,
+			messageTransformerSubject,
+		}, names(ah))
+	})
+
+	t.Run("last", func(t *testing.T) {
+		ah := &ActivityHandler{messageTransformers: defaultMessageTransformers()}
+		ah.registerMessageTransformer("first", noop, "")
+		ah.registerMessageTransformer("second", noop, "unknown")
+
+		registered := names(ah)
+		assert.Equal(t, []string{"first", "second"}, registered[len(registered)-2:])
+	})
+}
+
+func TestRunMessageTransformers(t *testing.T) {
+	ah := &ActivityHandler{}
+	ah.registerMessageTransformer("exclaim", func(_ *ActivityHandler, c *messageConversion) {
+		c.text = c.msg.Text + "!"
+		c.msg.Attachments = append(c.msg.Attachments, clientmodels.Attachment{ContentType: contentTypeHostedImage})
+	}, "")
+
+	msg := &clientmodels.Message{Text: "hello"}
+	for i := 0; i < 2; i++ {
+		conversion := &messageConversion{msg: msg}
+		ah.runMessageTransformers(conversion)
+
+		assert.Equal(t, "hello!", conversion.text)
+		assert.Len(t, conversion.msg.Attachments, 1)
+	}
+
+	// The original message is left untouched for the next recipient.
+	assert.Empty(t, msg.Attachments)
+}
+
+func TestRedactMessage(t *testing.T) {
+	p := &Plugin{}
+	regexps, err := compileRedactionPatterns(`\b\d{4}-\d{4}-\d{4}-\d{4}\b` + "\n" + `internal\.example\.com`)
+	require.NoError(t, err)
+	p.setConfiguration(&configuration{redactionRegexps: regexps})
+
+	ah := &ActivityHandler{plugin: p}
+	ah.registerMessageTransformer(messageTransformerRedaction, redactMessage, "")
+
+	ah.registerMessageTransformer(messageTransformerFileNameRedaction, redactFileNames, "")
+
+	conversion := &messageConversion{
+		msg: &clientmodels.Message{
+			Attachments: []clientmodels.Attachment{{Name: "internal.example.com.pdf"}},
+		},
+		text: "card 1234-5678-9012-3456 on internal.example.com",
+		linkPreviews: []*model.SlackAttachment{{
+			Title:    "internal.example.com",
+			Text:     "card 1234-5678-9012-3456",
+			Fallback: "https://internal.example.com/page",
+			Pretext:  "from internal.example.com",
+		}},
+	}
+	ah.runMessageTransformers(conversion)
+	assert.Equal(t, "card [redacted] on [redacted]", conversion.text)
+	assert.Equal(t, "[redacted].pdf", conversion.msg.Attachments[0].Name)
+	assert.Equal(t, &model.SlackAttachment{
+		Title:    "[redacted]",
+		Text:     "card [redacted]",
+		Fallback: "https://[redacted]/page",
+		Pretext:  "from [redacted]",
+	}, conversion.linkPreviews[0])
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"io"
+	"net/http"
+
+	"github.com/mattermost/mattermost-plugin-msteams/server/msteams"
+	"github.com/mattermost/mattermost/server/public/model"
+)
+
+// maxMessageFixtureSize caps the size of a replayed message fixture.
+const maxMessageFixtureSize = 4 * 1024 * 1024
+
+// messageReplay is the outcome of converting a message fixture into a post.
+type messageReplay struct {
+	Message                string                   `json:"message"`
+	RootID                 string                   `json:"root_id,omitempty"`
+	Attachments            []*model.SlackAttachment `json:"attachments,omitempty"`
+	SkippedFileAttachments int                      `json:"skipped_file_attachments"`
+}
+
+// replayMessageFixture runs a message fixture through the message conversion as a dry run, so
+// nothing is fetched from MS Teams or the store, nor posted in Mattermost. A fixture is a chatMessage resource
+// as returned by MS Graph, recorded and sanitized of any customer data.
+func (ah *ActivityHandler) replayMessageFixture(fixture []byte) (*messageReplay, error) {
+	msg, err := msteams.ParseChatMessage(fixture)
+	if err != nil {
+		return nil, err
+	}
+
+	conversion := &messageConversion{
+		logger: ah.newActivityLogger(model.NewId()),
+		msg:    msg,
+		dryRun: true,
+	}
+	ah.runMessageTransformers(conversion)
+
+	return &messageReplay{
+		Message:                conversion.text,
+		RootID:                 conversion.rootID,
+		Attachments:            conversion.linkPreviews,
+		SkippedFileAttachments: conversion.skippedFileAttachments,
+	}, nil
+}
+
+// replayMessage converts a posted message fixture and returns the result. It is meant for
+// developers reproducing formatting issues, and is deliberately absent from the System Console.
+func (a *API) replayMessage(w http.ResponseWriter, r *http.Request) {
+	userID := r.Header.Get("Mattermost-User-ID")
+	if userID == "" {
+		a.p.API.LogWarn("Not authorized")
+		http.Error(w, "not authorized", http.StatusUnauthorized)
+		return
+	}
+
+	if !a.p.API.HasPermissionTo(userID, model.PermissionManageSystem) {
+		a.p.API.LogWarn("Insufficient permissions", "user_id", userID)
+		http.Error(w, "not able to authorize the user", http.StatusForbidden)
+		return
+	}
+
+	fixture, err := io.ReadAll(io.LimitReader(r.Body, maxMessageFixtureSize))
+	if err != nil {
+		a.p.API.LogWarn("Unable to read the message fixture", "error", err.Error())
+		http.Error(w, "unable to read the message fixture", http.StatusBadRequest)
+		return
+	}
+
+	replay, err := a.p.activityHandler.replayMessageFixture(fixture)
+	if err != nil {
+		a.p.API.LogWarn("Error parsing message fixture", "error", err.Error())
+		http.Error(w, "error parsing message fixture", http.StatusBadRequest)
+		return
+	}
+
+	a.returnJSON(w, replay)
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var updateGoldenFiles = flag.Bool("update", false, "update the golden files of the message fixtures")
+
+// TestReplayMessageFixtures replays every message fixture, comparing the outcome with its golden
+// file, or rewriting the golden file when running with -update.
+func TestReplayMessageFixtures(t *testing.T) {
+	// The dry run converts the fixtures without reaching MS Teams, the store or the plugin API.
+	ah := &ActivityHandler{plugin: &Plugin{}, messageTransformers: defaultMessageTransformers()}
+
+	fixturePaths, err := filepath.Glob(filepath.Join("testdata", "message_fixtures", "*.json"))
+	require.NoError(t, err)
+	require.NotEmpty(t, fixturePaths)
+
+	for _, fixturePath := range fixturePaths {
+		if strings.HasSuffix(fixturePath, ".golden.json") {
+			continue
+		}
+
+		t.Run(strings.TrimSuffix(filepath.Base(fixturePath), ".json"), func(t *testing.T) {
+			fixture, err := os.ReadFile(fixturePath)
+			require.NoError(t, err)
+
+			replay, err := ah.replayMessageFixture(fixture)
+			require.NoError(t, err)
+
+			var buffer bytes.Buffer
+			encoder := json.NewEncoder(&buffer)
+			encoder.SetEscapeHTML(false)
+			encoder.SetIndent("", "  ")
+			require.NoError(t, encoder.Encode(replay))
+			actual := buffer.Bytes()
+
+			goldenPath := strings.TrimSuffix(fixturePath, ".json") + ".golden.json"
+			if *updateGoldenFiles {
+				require.NoError(t, os.WriteFile(goldenPath, actual, 0600))
+				return
+			}
+
+			expected, err := os.ReadFile(goldenPath)
+			require.NoError(t, err, "missing golden file, run the test with -update to create it")
+			assert.Equal(t, string(expected), string(actual))
+		})
+	}
+}
@@ -33,6 +33,11 @@ const (
 	SubscriptionConnected  = "connected"
 	SubscriptionDeleted    = "deleted"
 
+	ResourceKindChatMessage    = "chat_message"
+	ResourceKindChannelMessage = "channel_message"
+	ResourceKindMembership     = "membership"
+	ResourceKindUnknown        = "unknown"
+
 	DiscardedReasonNone                            = ""
 	DiscardedReasonInvalidChangeType               = "invalid_change_type"
 	DiscardedReasonUnableToGetTeamsData            = "unable_to_get_teams_data" // #nosec  false positive
@@ -40,18 +45,28 @@ const (
 	DiscardedReasonFileLimitReached                = "file_limit_reached"
 	DiscardedReasonEmptyFileID                     = "empty_file_id"
 	DiscardedReasonMaxFileSizeExceeded             = "max_file_size_exceeded"
+	DiscardedReasonBlockedFileType                 = "blocked_file_type"
+	DiscardedReasonFileQuarantined                 = "file_quarantined"
 	DiscardedReasonUnknownLifecycleEvent           = "unknown_lifeycle_event"
 	DiscardedReasonUnusedSubscription              = "unused_subscription"
 	DiscardedReasonExpiredSubscription             = "expired_subscription"
 	DiscardedReasonInvalidWebhookSecret            = "invalid_webhook_secret"
+	DiscardedReasonUntrustedSource                 = "untrusted_source"
 	DiscardedReasonFailedSubscriptionCheck         = "failed_subscription_check"
 	DiscardedReasonFailedToRefresh                 = "failed_to_refresh"
 	DiscardedReasonNotificationsOnly               = "notifications_only"
 	DiscardedReasonChannelNotificationsUnsupported = "channel_notifications_unsupported"
 	DiscardedReasonNoConnectedUser                 = "no_connected_user"
+	DiscardedReasonConversionFailed                = "conversion_failed"
 	DiscardedReasonUserDisabledNotifications       = "user_disabled_notifications"
 	DiscardedReasonUserActiveInTeams               = "user_active_in_teams"
+	DiscardedReasonObserveOnly                     = "observe_only"
+	DiscardedReasonFeatureDisabled                 = "feature_disabled"
 	DiscardedReasonInternalError                   = "internal_error"
+	DiscardedReasonDeferredLimitReached            = "deferred_limit_reached"
+
+	DeferredReasonChatFairness = "chat_fairness"
+	DeferredReasonRetry        = "retry"
 
 	WorkerMonitor          = "monitor"
 	WorkerActivityHandler  = "activity_handler"
@@ -66,8 +81,10 @@ type Metrics interface {
 	IncrementHTTPErrors()
 	ObserveOAuthTokenInvalidated()
 	ObserveChangeEventQueueRejected()
+	ObserveChangeEventQueueBackpressure()
 
-	ObserveChangeEvent(changeType string, discardedReason string)
+	ObserveChangeEvent(changeType, resourceKind, discardedReason string)
+	ObserveChangeEventProcessingTime(changeType, resourceKind string, elapsed float64)
 	ObserveLifecycleEvent(lifecycleEventType, discardedReason string)
 	ObserveMessage(action, source string, isDirectOrGroupMessage bool)
 	ObserveMessageDelay(action, source string, isDirectOrGroupMessage bool, delay time.Duration)
@@ -88,6 +105,9 @@ type Metrics interface {
 	ObserveChangeEventQueueCapacity(count int64)
 	IncrementChangeEventQueueLength(changeType string)
 	DecrementChangeEventQueueLength(changeType string)
+	ObserveChangeEventQueueWaitTime(changeType string, elapsed float64)
+	ObserveChangeEventQueueOldestItemAge(elapsed float64)
+	ObserveChangeEventDeferredLength(reason string, count int64)
 
 	ObserveMSGraphClientMethodDuration(method, success, statusCode string, elapsed float64)
 	ObserveStoreMethodDuration(method, success string, elapsed float64)
@@ -130,6 +150,7 @@ type metrics struct {
 
 	lifecycleEventsTotal     *prometheus.CounterVec
 	changeEventsTotal        *prometheus.CounterVec
+	changeEventTime          *prometheus.HistogramVec
 	messagesTotal            *prometheus.CounterVec
 	messageDelayTime         *prometheus.HistogramVec
 	reactionsTotal           *prometheus.CounterVec
@@ -149,11 +170,15 @@ type metrics struct {
 
 	activeUsersReceiving prometheus.Gauge
 
-	changeEventQueueCapacity      prometheus.Gauge
-	changeEventQueueLength        *prometheus.GaugeVec
-	changeEventQueueRejectedTotal prometheus.Counter
-	activeWorkersTotal            *prometheus.GaugeVec
-	clientSecretEndDateTime       prometheus.Gauge
+	changeEventQueueCapacity          prometheus.Gauge
+	changeEventQueueLength            *prometheus.GaugeVec
+	changeEventQueueRejectedTotal     prometheus.Counter
+	changeEventQueueBackpressureTotal prometheus.Counter
+	changeEventQueueWaitTime          *prometheus.HistogramVec
+	changeEventQueueOldestItemAge     prometheus.Gauge
+	changeEventDeferredLength         *prometheus.GaugeVec
+	activeWorkersTotal                *prometheus.GaugeVec
+	clientSecretEndDateTime           prometheus.Gauge
 
 	storeTime          *prometheus.HistogramVec
 	workersTime        *prometheus.HistogramVec
@@ -253,9 +278,18 @@ func NewMetrics(info InstanceInfo) Metrics {
 		Name:        "change_events_total",
 		Help:        "The total number of MS Teams change events processed.",
 		ConstLabels: additionalLabels,
-	}, []string{"change_type", "discarded_reason"})
+	}, []string{"change_type", "resource_kind", "discarded_reason"})
 	m.registry.MustRegister(m.changeEventsTotal)
 
+	m.changeEventTime = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace:   MetricsNamespace,
+		Subsystem:   MetricsSubsystemEvents,
+		Name:        "change_event_processing_time_seconds",
+		Help:        "Time to process MS Teams change events, once dequeued.",
+		ConstLabels: additionalLabels,
+	}, []string{"change_type", "resource_kind"})
+	m.registry.MustRegister(m.changeEventTime)
+
 	m.lifecycleEventsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
 		Namespace:   MetricsNamespace,
 		Subsystem:   MetricsSubsystemEvents,
@@ -392,6 +426,24 @@ func NewMetrics(info InstanceInfo) Metrics {
 	}, []string{"change_type"})
 	m.registry.MustRegister(m.changeEventQueueLength)
 
+	m.changeEventQueueOldestItemAge = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace:   MetricsNamespace,
+		Subsystem:   MetricsSubsystemApp,
+		Name:        "change_event_queue_oldest_item_age_seconds",
+		Help:        "The time the oldest change event in the queue has been waiting to be handled.",
+		ConstLabels: additionalLabels,
+	})
+	m.registry.MustRegister(m.changeEventQueueOldestItemAge)
+
+	m.changeEventDeferredLength = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace:   MetricsNamespace,
+		Subsystem:   MetricsSubsystemApp,
+		Name:        "change_event_deferred_length",
+		Help:        "The number of change events waiting to be queued again, by the reason they were deferred.",
+		ConstLabels: additionalLabels,
+	}, []string{"reason"})
+	m.registry.MustRegister(m.changeEventDeferredLength)
+
 	m.changeEventQueueRejectedTotal = prometheus.NewCounter(prometheus.CounterOpts{
 		Namespace:   MetricsNamespace,
 		Subsystem:   MetricsSubsystemEvents,
@@ -401,6 +453,24 @@ func NewMetrics(info InstanceInfo) Metrics {
 	})
 	m.registry.MustRegister(m.changeEventQueueRejectedTotal)
 
+	m.changeEventQueueBackpressureTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace:   MetricsNamespace,
+		Subsystem:   MetricsSubsystemEvents,
+		Name:        "change_event_queue_backpressure_total",
+		Help:        "The total number of change event requests deferred back to MS Graph due to the activity queue nearing its capacity.",
+		ConstLabels: additionalLabels,
+	})
+	m.registry.MustRegister(m.changeEventQueueBackpressureTotal)
+
+	m.changeEventQueueWaitTime = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace:   MetricsNamespace,
+		Subsystem:   MetricsSubsystemEvents,
+		Name:        "change_event_queue_wait_time_seconds",
+		Help:        "Time change events spend in the activity queue before being handled.",
+		ConstLabels: additionalLabels,
+	}, []string{"change_type"})
+	m.registry.MustRegister(m.changeEventQueueWaitTime)
+
 	m.msGraphClientTime = prometheus.NewHistogramVec(
 		prometheus.HistogramOpts{
 			Namespace:   MetricsNamespace,
@@ -511,9 +581,15 @@ func (m *metrics) ObserveWhitelistedUsers(count int64) {
 
 // END CONNECT FLOW METRICS
 
-func (m *metrics) ObserveChangeEvent(changeType string, discardedReason string) {
+func (m *metrics) ObserveChangeEvent(changeType, resourceKind, discardedReason string) {
+	if m != nil {
+		m.changeEventsTotal.With(prometheus.Labels{"change_type": changeType, "resource_kind": resourceKind, "discarded_reason": discardedReason}).Inc()
+	}
+}
+
+func (m *metrics) ObserveChangeEventProcessingTime(changeType, resourceKind string, elapsed float64) {
 	if m != nil {
-		m.changeEventsTotal.With(prometheus.Labels{"change_type": changeType, "discarded_reason": discardedReason}).Inc()
+		m.changeEventTime.With(prometheus.Labels{"change_type": changeType, "resource_kind": resourceKind}).Observe(elapsed)
 	}
 }
 
@@ -595,6 +671,12 @@ func (m *metrics) ObserveChangeEventQueueRejected() {
 	}
 }
 
+func (m *metrics) ObserveChangeEventQueueBackpressure() {
+	if m != nil {
+		m.changeEventQueueBackpressureTotal.Inc()
+	}
+}
+
 func (m *metrics) ObserveChangeEventQueueCapacity(count int64) {
 	if m != nil {
 		m.changeEventQueueCapacity.Set(float64(count))
@@ -613,6 +695,24 @@ func (m *metrics) DecrementChangeEventQueueLength(changeType string) {
 	}
 }
 
+func (m *metrics) ObserveChangeEventQueueWaitTime(changeType string, elapsed float64) {
+	if m != nil {
+		m.changeEventQueueWaitTime.With(prometheus.Labels{"change_type": changeType}).Observe(elapsed)
+	}
+}
+
+func (m *metrics) ObserveChangeEventQueueOldestItemAge(elapsed float64) {
+	if m != nil {
+		m.changeEventQueueOldestItemAge.Set(elapsed)
+	}
+}
+
+func (m *metrics) ObserveChangeEventDeferredLength(reason string, count int64) {
+	if m != nil {
+		m.changeEventDeferredLength.With(prometheus.Labels{"reason": reason}).Set(float64(count))
+	}
+}
+
 func (m *metrics) ObserveMSGraphClientMethodDuration(method, success, statusCode string, elapsed float64) {
 	if m != nil {
 		m.msGraphClientTime.With(prometheus.Labels{"method": method, "success": success, "status_code": statusCode}).Observe(elapsed)
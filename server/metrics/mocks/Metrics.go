@@ -70,9 +70,14 @@ func (_m *Metrics) ObserveActiveUsersReceiving(count int64) {
 	_m.Called(count)
 }
 
-// ObserveChangeEvent provides a mock function with given fields: changeType, discardedReason
-func (_m *Metrics) ObserveChangeEvent(changeType string, discardedReason string) {
-	_m.Called(changeType, discardedReason)
+// ObserveChangeEvent provides a mock function with given fields: changeType, resourceKind, discardedReason
+func (_m *Metrics) ObserveChangeEvent(changeType string, resourceKind string, discardedReason string) {
+	_m.Called(changeType, resourceKind, discardedReason)
+}
+
+// ObserveChangeEventQueueBackpressure provides a mock function with given fields:
+func (_m *Metrics) ObserveChangeEventQueueBackpressure() {
+	_m.Called()
 }
 
 // ObserveChangeEventQueueCapacity provides a mock function with given fields: count
@@ -85,6 +90,26 @@ func (_m *Metrics) ObserveChangeEventQueueRejected() {
 	_m.Called()
 }
 
+// ObserveChangeEventProcessingTime provides a mock function with given fields: changeType, resourceKind, elapsed
+func (_m *Metrics) ObserveChangeEventProcessingTime(changeType string, resourceKind string, elapsed float64) {
+	_m.Called(changeType, resourceKind, elapsed)
+}
+
+// ObserveChangeEventQueueWaitTime provides a mock function with given fields: changeType, elapsed
+func (_m *Metrics) ObserveChangeEventQueueWaitTime(changeType string, elapsed float64) {
+	_m.Called(changeType, elapsed)
+}
+
+// ObserveChangeEventQueueOldestItemAge provides a mock function with given fields: elapsed
+func (_m *Metrics) ObserveChangeEventQueueOldestItemAge(elapsed float64) {
+	_m.Called(elapsed)
+}
+
+// ObserveChangeEventDeferredLength provides a mock function with given fields: reason, count
+func (_m *Metrics) ObserveChangeEventDeferredLength(reason string, count int64) {
+	_m.Called(reason, count)
+}
+
 // ObserveClientSecretEndDateTime provides a mock function with given fields: expireDate
 func (_m *Metrics) ObserveClientSecretEndDateTime(expireDate time.Time) {
 	_m.Called(expireDate)
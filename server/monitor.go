@@ -2,14 +2,13 @@ package main
 
 import (
 	"fmt"
-	"runtime/debug"
+	"net/http"
 	"time"
 
 	"github.com/mattermost/mattermost-plugin-msteams/server/metrics"
 	"github.com/mattermost/mattermost-plugin-msteams/server/msteams"
 	"github.com/mattermost/mattermost-plugin-msteams/server/store"
 	"github.com/mattermost/mattermost/server/public/plugin"
-	"github.com/mattermost/mattermost/server/public/pluginapi/cluster"
 )
 
 const monitoringSystemJobName = "monitoring_system"
@@ -23,24 +22,31 @@ type Monitor struct {
 	store            store.Store
 	api              plugin.API
 	metrics          metrics.Metrics
-	job              *cluster.Job
+	jobs             *JobScheduler
 	baseURL          string
 	webhookSecret    string
 	useEvaluationAPI bool
 	startupTime      time.Time
+
+	// checkNotificationURL diagnoses why MS Graph might be unable to reach the notification URL.
+	checkNotificationURL func(baseURL string) (string, error)
 }
 
 // New creates a new instance of the Monitor job.
-func NewMonitor(client msteams.Client, store store.Store, api plugin.API, metrics metrics.Metrics, baseURL string, webhookSecret string, useEvaluationAPI bool) *Monitor {
+func NewMonitor(client msteams.Client, store store.Store, api plugin.API, metrics metrics.Metrics, jobs *JobScheduler, baseURL string, webhookSecret string, useEvaluationAPI bool) *Monitor {
 	return &Monitor{
 		client:           client,
 		store:            store,
 		api:              api,
 		metrics:          metrics,
+		jobs:             jobs,
 		baseURL:          baseURL,
 		webhookSecret:    webhookSecret,
 		useEvaluationAPI: useEvaluationAPI,
 		startupTime:      time.Now(),
+		checkNotificationURL: func(baseURL string) (string, error) {
+			return checkNotificationURL(&http.Client{Timeout: probeNotificationURLTimeout}, baseURL)
+		},
 	}
 }
 
@@ -48,31 +54,17 @@ func NewMonitor(client msteams.Client, store store.Store, api plugin.API, metric
 func (m *Monitor) Start() error {
 	m.api.LogInfo("Starting the msteams sync monitoring system")
 
-	// Close the previous background job if exists.
-	m.Stop()
-
-	job, jobErr := cluster.Schedule(
-		m.api,
-		monitoringSystemJobName,
-		cluster.MakeWaitForRoundedInterval(1*time.Minute),
-		m.runMonitoringSystemJob,
-	)
-	if jobErr != nil {
+	// Registering replaces the previous background job if exists.
+	if jobErr := m.jobs.Register(monitoringSystemJobName, 1*time.Minute, false, m.runMonitoringSystemJob); jobErr != nil {
 		return fmt.Errorf("error in scheduling the monitoring system job. error: %w", jobErr)
 	}
 
-	m.job = job
-
 	return nil
 }
 
 // Stop stops running the Monitor job.
 func (m *Monitor) Stop() {
-	if m.job != nil {
-		if err := m.job.Close(); err != nil {
-			m.api.LogError("Failed to close monitoring system background job", "error", err)
-		}
-	}
+	m.jobs.Unregister(monitoringSystemJobName)
 }
 
 // runMonitoringSystemJob is a callback to trigger the business logic of the Monitor job, being run
@@ -84,13 +76,6 @@ func (m *Monitor) runMonitoringSystemJob() {
 		return
 	}
 
-	defer func() {
-		if r := recover(); r != nil {
-			m.metrics.ObserveGoroutineFailure()
-			m.api.LogError("Recovering from panic", "panic", r, "stack", string(debug.Stack()))
-		}
-	}()
-
 	done := m.metrics.ObserveWorker(metrics.WorkerMonitor)
 	defer done()
 
@@ -24,6 +24,7 @@ import (
 	pluginapi "github.com/mattermost/mattermost/server/public/pluginapi"
 	abstractions "github.com/microsoft/kiota-abstractions-go"
 	"github.com/microsoft/kiota-abstractions-go/serialization"
+	jsonserialization "github.com/microsoft/kiota-serialization-json-go"
 	msgraphsdk "github.com/microsoftgraph/msgraph-sdk-go"
 	msgraphcore "github.com/microsoftgraph/msgraph-sdk-go-core"
 	a "github.com/microsoftgraph/msgraph-sdk-go-core/authentication"
@@ -88,6 +89,16 @@ type Activity struct {
 	SubscriptionID                 string
 	EncryptedContent               *EncryptedContent
 	Content                        []byte
+
+	// CorrelationID identifies the processing of this activity across log entries. It is
+	// assigned on receipt and never sent by MS Teams.
+	CorrelationID string `json:"-"`
+
+	// ReceivedAt is the time the activity was queued for handling.
+	ReceivedAt time.Time `json:"-"`
+
+	// Attempts counts the times the activity was handled and failed.
+	Attempts int `json:"-"`
 }
 
 type EncryptedContent struct {
@@ -160,14 +171,18 @@ func NormalizeGraphAPIError(err error) error {
 		if terr.GetMessage() != nil {
 			graphErr.Message = *terr.GetMessage()
 		}
-		if terr.GetInnerError().GetClientRequestId() != nil {
-			graphErr.ClientRequestID = *terr.GetInnerError().GetClientRequestId()
+		innerErr := terr.GetInnerError()
+		if innerErr == nil {
+			return
+		}
+		if innerErr.GetClientRequestId() != nil {
+			graphErr.ClientRequestID = *innerErr.GetClientRequestId()
 		}
-		if terr.GetInnerError().GetRequestId() != nil {
-			graphErr.RequestID = *terr.GetInnerError().GetRequestId()
+		if innerErr.GetRequestId() != nil {
+			graphErr.RequestID = *innerErr.GetRequestId()
 		}
-		if terr.GetInnerError().GetDate() != nil {
-			graphErr.Timestamp = *terr.GetInnerError().GetDate()
+		if innerErr.GetDate() != nil {
+			graphErr.Timestamp = *innerErr.GetDate()
 		}
 	}
 
@@ -176,6 +191,7 @@ func NormalizeGraphAPIError(err error) error {
 		fillFromMainErrorable(e, graphErr)
 	case *odataerrors.ODataError:
 		fillFromMainErrorable(e.GetErrorEscaped(), graphErr)
+		graphErr.StatusCode = e.ResponseStatusCode
 	default:
 		graphErr.Message = err.Error()
 		if IsOAuthError(err) {
@@ -358,6 +374,27 @@ func (tc *ClientImpl) Connect() error {
 	return nil
 }
 
+// CheckAppCredentials acquires an application token for MS Graph with the given credentials,
+// verifying they are accepted by the tenant without otherwise calling MS Graph.
+func CheckAppCredentials(ctx context.Context, tenantID, clientID, clientSecret string) error {
+	cred, err := azidentity.NewClientSecretCredential(
+		tenantID,
+		clientID,
+		clientSecret,
+		&azidentity.ClientSecretCredentialOptions{
+			ClientOptions: azcore.ClientOptions{
+				Transport: getAuthClient(),
+			},
+		},
+	)
+	if err != nil {
+		return err
+	}
+
+	_, err = cred.GetToken(ctx, policy.TokenRequestOptions{Scopes: TeamsDefaultScopes})
+	return err
+}
+
 func (tc *ClientImpl) GetMyID() (string, error) {
 	requestParameters := &users.UserItemRequestBuilderGetQueryParameters{
 		Select: []string{"id"},
@@ -760,7 +797,7 @@ func (tc *ClientImpl) UpdateMessage(teamID, channelID, parentID, msgID, message
 	}
 
 	if updateMessageRequest == nil {
-		return nil, errors.New("received nil updateMessageRequest from MS Graph")
+		return nil, newConversionError("received nil updateMessageRequest from MS Graph")
 	}
 
 	var getMessageRequest *abstractions.RequestInformation
@@ -777,7 +814,7 @@ func (tc *ClientImpl) UpdateMessage(teamID, channelID, parentID, msgID, message
 	}
 
 	if getMessageRequest == nil {
-		return nil, errors.New("received nil getMessageRequest from MS Graph")
+		return nil, newConversionError("received nil getMessageRequest from MS Graph")
 	}
 
 	batchRequest := msgraphcore.NewBatchRequest(tc.client.GetAdapter())
@@ -828,7 +865,7 @@ func (tc *ClientImpl) UpdateChatMessage(chatID, msgID, message string, mentions
 	}
 
 	if updateMessageRequest == nil {
-		return nil, errors.New("received nil updateMessageRequest from MS Graph")
+		return nil, newConversionError("received nil updateMessageRequest from MS Graph")
 	}
 
 	getMessageRequest, err := tc.client.Chats().ByChatId(chatID).Messages().ByChatMessageId(msgID).ToGetRequestInformation(tc.ctx, nil)
@@ -837,7 +874,7 @@ func (tc *ClientImpl) UpdateChatMessage(chatID, msgID, message string, mentions
 	}
 
 	if getMessageRequest == nil {
-		return nil, errors.New("received nil getMessageRequest from MS Graph")
+		return nil, newConversionError("received nil getMessageRequest from MS Graph")
 	}
 
 	batchRequest := msgraphcore.NewBatchRequest(tc.client.GetAdapter())
@@ -883,10 +920,10 @@ func (tc *ClientImpl) subscribe(baseURL, webhookSecret, resource, changeType, ce
 	}
 
 	if res.GetId() == nil {
-		return nil, errors.New("empty subscription ID received from MS Graph while creating subscription")
+		return nil, newConversionError("empty subscription ID received from MS Graph while creating subscription")
 	}
 	if res.GetExpirationDateTime() == nil {
-		return nil, errors.New("empty subscription expiration time received from MS Graph while creating subscription")
+		return nil, newConversionError("empty subscription expiration time received from MS Graph while creating subscription")
 	}
 
 	return &clientmodels.Subscription{
@@ -1148,6 +1185,41 @@ func (tc *ClientImpl) GetChat(chatID string) (*clientmodels.Chat, error) {
 	return &clientmodels.Chat{ID: chatID, Members: members, Type: chatType, Topic: topic}, nil
 }
 
+// ParseChatMessage converts a chatMessage resource, in the JSON representation returned by MS
+// Graph, into a message.
+func ParseChatMessage(data []byte) (*clientmodels.Message, error) {
+	parseNode, err := jsonserialization.NewJsonParseNode(data)
+	if err != nil {
+		return nil, err
+	}
+
+	parsable, err := parseNode.GetObjectValue(models.CreateChatMessageFromDiscriminatorValue)
+	if err != nil {
+		return nil, err
+	}
+	msg, ok := parsable.(models.ChatMessageable)
+	if !ok || msg == nil {
+		return nil, errors.New("not a chat message")
+	}
+
+	teamID := ""
+	channelID := ""
+	if msg.GetChannelIdentity() != nil {
+		if msg.GetChannelIdentity().GetTeamId() != nil {
+			teamID = *msg.GetChannelIdentity().GetTeamId()
+		}
+		if msg.GetChannelIdentity().GetChannelId() != nil {
+			channelID = *msg.GetChannelIdentity().GetChannelId()
+		}
+	}
+	chatID := ""
+	if msg.GetChatId() != nil {
+		chatID = *msg.GetChatId()
+	}
+
+	return convertToMessage(msg, teamID, channelID, chatID), nil
+}
+
 func convertToMessage(msg models.ChatMessageable, teamID, channelID, chatID string) *clientmodels.Message {
 	userID := ""
 	if msg.GetFrom() != nil && msg.GetFrom().GetUser() != nil && msg.GetFrom().GetUser().GetId() != nil {
@@ -1158,6 +1230,17 @@ func convertToMessage(msg models.ChatMessageable, teamID, channelID, chatID stri
 		userDisplayName = *msg.GetFrom().GetUser().GetDisplayName()
 	}
 
+	applicationID := ""
+	applicationDisplayName := ""
+	if msg.GetFrom() != nil && msg.GetFrom().GetApplication() != nil {
+		if msg.GetFrom().GetApplication().GetId() != nil {
+			applicationID = *msg.GetFrom().GetApplication().GetId()
+		}
+		if msg.GetFrom().GetApplication().GetDisplayName() != nil {
+			applicationDisplayName = *msg.GetFrom().GetApplication().GetDisplayName()
+		}
+	}
+
 	replyTo := ""
 	if msg.GetReplyToId() != nil {
 		replyTo = *msg.GetReplyToId()
@@ -1259,6 +1342,9 @@ func convertToMessage(msg models.ChatMessageable, teamID, channelID, chatID stri
 		Reactions:       reactions,
 		CreateAt:        createAt,
 		LastUpdateAt:    lastUpdateAt,
+
+		ApplicationID:          applicationID,
+		ApplicationDisplayName: applicationDisplayName,
 	}
 }
 
@@ -1406,7 +1492,7 @@ func (tc *ClientImpl) GetUser(userID string) (*clientmodels.User, error) {
 
 	if u.GetId() == nil {
 		tc.logService.Debug("Received empty user ID from MS Graph", "user_id", userID)
-		return nil, errors.New("received empty user ID from MS Graph")
+		return nil, newConversionError("received empty user ID from MS Graph")
 	}
 	user := clientmodels.User{
 		DisplayName: displayName,
@@ -1674,7 +1760,7 @@ func (tc *ClientImpl) CreateChat(chatType models.ChatType, userIDs []string) (*c
 	}
 
 	if chat.GetId() == nil {
-		return nil, errors.New("received empty chat ID from MS Graph while creating chat")
+		return nil, newConversionError("received empty chat ID from MS Graph while creating chat")
 	}
 
 	chatDetails, err := tc.GetChat(*chat.GetId())
@@ -1701,7 +1787,7 @@ func (tc *ClientImpl) SetChatReaction(chatID, messageID, userID, emoji string) (
 	}
 
 	if setReactionRequest == nil {
-		return nil, errors.New("received nil setReactionRequest from MS Graph")
+		return nil, newConversionError("received nil setReactionRequest from MS Graph")
 	}
 
 	getMessageRequest, err := tc.client.Chats().ByChatId(chatID).Messages().ByChatMessageId(messageID).ToGetRequestInformation(tc.ctx, nil)
@@ -1710,7 +1796,7 @@ func (tc *ClientImpl) SetChatReaction(chatID, messageID, userID, emoji string) (
 	}
 
 	if getMessageRequest == nil {
-		return nil, errors.New("received nil getMessageRequest from MS Graph")
+		return nil, newConversionError("received nil getMessageRequest from MS Graph")
 	}
 
 	batchRequest := msgraphcore.NewBatchRequest(tc.client.GetAdapter())
@@ -1758,7 +1844,7 @@ func (tc *ClientImpl) SetReaction(teamID, channelID, parentID, messageID, userID
 	}
 
 	if setReactionRequest == nil {
-		return nil, errors.New("received nil setReactionRequest from MS Graph")
+		return nil, newConversionError("received nil setReactionRequest from MS Graph")
 	}
 
 	var getMessageRequest *abstractions.RequestInformation
@@ -1775,7 +1861,7 @@ func (tc *ClientImpl) SetReaction(teamID, channelID, parentID, messageID, userID
 	}
 
 	if getMessageRequest == nil {
-		return nil, errors.New("received nil getMessageRequest from MS Graph")
+		return nil, newConversionError("received nil getMessageRequest from MS Graph")
 	}
 
 	batchRequest := msgraphcore.NewBatchRequest(tc.client.GetAdapter())
@@ -1810,7 +1896,7 @@ func (tc *ClientImpl) UnsetChatReaction(chatID, messageID, userID, emoji string)
 	}
 
 	if unsetReactionRequest == nil {
-		return nil, errors.New("received nil unsetReactionRequest from MS Graph")
+		return nil, newConversionError("received nil unsetReactionRequest from MS Graph")
 	}
 
 	getMessageRequest, err := tc.client.Chats().ByChatId(chatID).Messages().ByChatMessageId(messageID).ToGetRequestInformation(tc.ctx, nil)
@@ -1819,7 +1905,7 @@ func (tc *ClientImpl) UnsetChatReaction(chatID, messageID, userID, emoji string)
 	}
 
 	if getMessageRequest == nil {
-		return nil, errors.New("received nil getMessageRequest from MS Graph")
+		return nil, newConversionError("received nil getMessageRequest from MS Graph")
 	}
 
 	batchRequest := msgraphcore.NewBatchRequest(tc.client.GetAdapter())
@@ -1867,7 +1953,7 @@ func (tc *ClientImpl) UnsetReaction(teamID, channelID, parentID, messageID, user
 	}
 
 	if unsetReactionRequest == nil {
-		return nil, errors.New("received nil unsetReactionRequest from MS Graph")
+		return nil, newConversionError("received nil unsetReactionRequest from MS Graph")
 	}
 
 	var getMessageRequest *abstractions.RequestInformation
@@ -1884,7 +1970,7 @@ func (tc *ClientImpl) UnsetReaction(teamID, channelID, parentID, messageID, user
 	}
 
 	if getMessageRequest == nil {
-		return nil, errors.New("received nil getMessageRequest from MS Graph")
+		return nil, newConversionError("received nil getMessageRequest from MS Graph")
 	}
 
 	batchRequest := msgraphcore.NewBatchRequest(tc.client.GetAdapter())
@@ -1952,25 +2038,26 @@ func (tc *ClientImpl) ListUsers() ([]clientmodels.User, error) {
 	return users, nil
 }
 
-func (tc *ClientImpl) ListTeams() ([]clientmodels.Team, error) {
+// ListTeams returns a page of the teams joined by the user, starting from the given page token, or
+// from the first page if empty, along with the token of the next page, if any.
+func (tc *ClientImpl) ListTeams(pageToken string) ([]clientmodels.Team, string, error) {
 	requestParameters := &users.ItemJoinedTeamsRequestBuilderGetQueryParameters{
 		Select: []string{"displayName", "id", "description"},
 	}
 	configuration := &users.ItemJoinedTeamsRequestBuilderGetRequestConfiguration{
 		QueryParameters: requestParameters,
 	}
-	r, err := tc.client.Me().JoinedTeams().Get(tc.ctx, configuration)
-	if err != nil {
-		return nil, NormalizeGraphAPIError(err)
+	requestBuilder := tc.client.Me().JoinedTeams()
+	if pageToken != "" {
+		requestBuilder = requestBuilder.WithUrl(pageToken)
 	}
-
-	pageIterator, err := msgraphcore.NewPageIterator[models.Teamable](r, tc.client.GetAdapter(), models.CreateTeamCollectionResponseFromDiscriminatorValue)
+	r, err := requestBuilder.Get(tc.ctx, configuration)
 	if err != nil {
-		return nil, NormalizeGraphAPIError(err)
+		return nil, "", NormalizeGraphAPIError(err)
 	}
 
 	teams := []clientmodels.Team{}
-	err = pageIterator.Iterate(context.Background(), func(t models.Teamable) bool {
+	for _, t := range r.GetValue() {
 		team := clientmodels.Team{}
 		if t.GetId() != nil {
 			team.ID = *t.GetId()
@@ -1983,33 +2070,31 @@ func (tc *ClientImpl) ListTeams() ([]clientmodels.Team, error) {
 		}
 
 		teams = append(teams, team)
-		return true
-	})
-	if err != nil {
-		return nil, NormalizeGraphAPIError(err)
 	}
-	return teams, nil
+
+	return teams, nextPageToken(r.GetOdataNextLink()), nil
 }
 
-func (tc *ClientImpl) ListChannels(teamID string) ([]clientmodels.Channel, error) {
+// ListChannels returns a page of the channels of the given team, starting from the given page
+// token, or from the first page if empty, along with the token of the next page, if any.
+func (tc *ClientImpl) ListChannels(teamID, pageToken string) ([]clientmodels.Channel, string, error) {
 	requestParameters := &teams.ItemChannelsRequestBuilderGetQueryParameters{
 		Select: []string{"displayName", "id", "description"},
 	}
 	configuration := &teams.ItemChannelsRequestBuilderGetRequestConfiguration{
 		QueryParameters: requestParameters,
 	}
-	r, err := tc.client.Teams().ByTeamId(teamID).Channels().Get(tc.ctx, configuration)
-	if err != nil {
-		return nil, NormalizeGraphAPIError(err)
+	requestBuilder := tc.client.Teams().ByTeamId(teamID).Channels()
+	if pageToken != "" {
+		requestBuilder = requestBuilder.WithUrl(pageToken)
 	}
-
-	pageIterator, err := msgraphcore.NewPageIterator[models.Channelable](r, tc.client.GetAdapter(), models.CreateChannelCollectionResponseFromDiscriminatorValue)
+	r, err := requestBuilder.Get(tc.ctx, configuration)
 	if err != nil {
-		return nil, NormalizeGraphAPIError(err)
+		return nil, "", NormalizeGraphAPIError(err)
 	}
 
 	channels := []clientmodels.Channel{}
-	err = pageIterator.Iterate(context.Background(), func(c models.Channelable) bool {
+	for _, c := range r.GetValue() {
 		channel := clientmodels.Channel{}
 		if c.GetId() != nil {
 			channel.ID = *c.GetId()
@@ -2022,12 +2107,42 @@ func (tc *ClientImpl) ListChannels(teamID string) ([]clientmodels.Channel, error
 		}
 
 		channels = append(channels, channel)
-		return true
-	})
+	}
+
+	return channels, nextPageToken(r.GetOdataNextLink()), nil
+}
+
+// ListTeamMembers returns a page of the members of the given team, starting from the given page
+// token, or from the first page if empty, along with the token of the next page, if any.
+func (tc *ClientImpl) ListTeamMembers(teamID, pageToken string) ([]clientmodels.TeamMember, string, error) {
+	requestBuilder := tc.client.Teams().ByTeamId(teamID).Members()
+	if pageToken != "" {
+		requestBuilder = requestBuilder.WithUrl(pageToken)
+	}
+	r, err := requestBuilder.Get(tc.ctx, nil)
 	if err != nil {
-		return nil, NormalizeGraphAPIError(err)
+		return nil, "", NormalizeGraphAPIError(err)
 	}
-	return channels, nil
+
+	members := []clientmodels.TeamMember{}
+	for _, m := range r.GetValue() {
+		member := clientmodels.TeamMember{
+			Roles: m.GetRoles(),
+		}
+		if m.GetDisplayName() != nil {
+			member.DisplayName = *m.GetDisplayName()
+		}
+		if userID, err := m.GetBackingStore().Get("userId"); err == nil && userID != nil {
+			member.UserID = *(userID.(*string))
+		}
+		if email, err := m.GetBackingStore().Get("email"); err == nil && email != nil {
+			member.Email = *(email.(*string))
+		}
+
+		members = append(members, member)
+	}
+
+	return members, nextPageToken(r.GetOdataNextLink()), nil
 }
 
 func (tc *ClientImpl) ListChannelMessages(teamID string, channelID string, since time.Time) ([]*clientmodels.Message, error) {
@@ -2121,11 +2236,11 @@ func (tc *ClientImpl) SendBatchRequestAndGetMessage(batchRequest msgraphcore.Bat
 	}
 
 	if resp == nil {
-		return nil, errors.New("received nil response from MS Graph for the message")
+		return nil, newConversionError("received nil response from MS Graph for the message")
 	}
 
 	if resp.GetLastModifiedDateTime() == nil {
-		return nil, errors.New("received nil last modified date time from MS Graph for the message")
+		return nil, newConversionError("received nil last modified date time from MS Graph for the message")
 	}
 
 	return &clientmodels.Message{LastUpdateAt: *resp.GetLastModifiedDateTime()}, nil
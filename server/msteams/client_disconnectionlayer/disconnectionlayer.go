@@ -22,7 +22,7 @@ import (
 type ClientDisconnectionLayer struct {
 	msteams.Client
 	userID       string
-	onDisconnect func(userID string)
+	onDisconnect func(userID string, cause error)
 }
 
 func (c *ClientDisconnectionLayer) Connect() error {
@@ -30,7 +30,7 @@ func (c *ClientDisconnectionLayer) Connect() error {
 	if err != nil {
 		var graphErr *msteams.GraphAPIError
 		if msteams.IsOAuthError(err) || (errors.As(err, &graphErr) && graphErr.StatusCode == http.StatusUnauthorized) {
-			c.onDisconnect(c.userID)
+			c.onDisconnect(c.userID, err)
 		}
 	}
 	return err
@@ -41,7 +41,7 @@ func (c *ClientDisconnectionLayer) CreateOrGetChatForUsers(usersIDs []string) (*
 	if err != nil {
 		var graphErr *msteams.GraphAPIError
 		if msteams.IsOAuthError(err) || (errors.As(err, &graphErr) && graphErr.StatusCode == http.StatusUnauthorized) {
-			c.onDisconnect(c.userID)
+			c.onDisconnect(c.userID, err)
 		}
 	}
 	return result, err
@@ -52,7 +52,7 @@ func (c *ClientDisconnectionLayer) DeleteChatMessage(userID string, chatID strin
 	if err != nil {
 		var graphErr *msteams.GraphAPIError
 		if msteams.IsOAuthError(err) || (errors.As(err, &graphErr) && graphErr.StatusCode == http.StatusUnauthorized) {
-			c.onDisconnect(c.userID)
+			c.onDisconnect(c.userID, err)
 		}
 	}
 	return err
@@ -63,7 +63,7 @@ func (c *ClientDisconnectionLayer) DeleteMessage(teamID string, channelID string
 	if err != nil {
 		var graphErr *msteams.GraphAPIError
 		if msteams.IsOAuthError(err) || (errors.As(err, &graphErr) && graphErr.StatusCode == http.StatusUnauthorized) {
-			c.onDisconnect(c.userID)
+			c.onDisconnect(c.userID, err)
 		}
 	}
 	return err
@@ -74,7 +74,7 @@ func (c *ClientDisconnectionLayer) DeleteSubscription(subscriptionID string) err
 	if err != nil {
 		var graphErr *msteams.GraphAPIError
 		if msteams.IsOAuthError(err) || (errors.As(err, &graphErr) && graphErr.StatusCode == http.StatusUnauthorized) {
-			c.onDisconnect(c.userID)
+			c.onDisconnect(c.userID, err)
 		}
 	}
 	return err
@@ -85,7 +85,7 @@ func (c *ClientDisconnectionLayer) GetApp(applicationID string) (*clientmodels.A
 	if err != nil {
 		var graphErr *msteams.GraphAPIError
 		if msteams.IsOAuthError(err) || (errors.As(err, &graphErr) && graphErr.StatusCode == http.StatusUnauthorized) {
-			c.onDisconnect(c.userID)
+			c.onDisconnect(c.userID, err)
 		}
 	}
 	return result, err
@@ -96,7 +96,7 @@ func (c *ClientDisconnectionLayer) GetChannelInTeam(teamID string, channelID str
 	if err != nil {
 		var graphErr *msteams.GraphAPIError
 		if msteams.IsOAuthError(err) || (errors.As(err, &graphErr) && graphErr.StatusCode == http.StatusUnauthorized) {
-			c.onDisconnect(c.userID)
+			c.onDisconnect(c.userID, err)
 		}
 	}
 	return result, err
@@ -107,7 +107,7 @@ func (c *ClientDisconnectionLayer) GetChannelsInTeam(teamID string, filterQuery
 	if err != nil {
 		var graphErr *msteams.GraphAPIError
 		if msteams.IsOAuthError(err) || (errors.As(err, &graphErr) && graphErr.StatusCode == http.StatusUnauthorized) {
-			c.onDisconnect(c.userID)
+			c.onDisconnect(c.userID, err)
 		}
 	}
 	return result, err
@@ -118,7 +118,7 @@ func (c *ClientDisconnectionLayer) GetChat(chatID string) (*clientmodels.Chat, e
 	if err != nil {
 		var graphErr *msteams.GraphAPIError
 		if msteams.IsOAuthError(err) || (errors.As(err, &graphErr) && graphErr.StatusCode == http.StatusUnauthorized) {
-			c.onDisconnect(c.userID)
+			c.onDisconnect(c.userID, err)
 		}
 	}
 	return result, err
@@ -129,7 +129,7 @@ func (c *ClientDisconnectionLayer) GetChatMessage(chatID string, messageID strin
 	if err != nil {
 		var graphErr *msteams.GraphAPIError
 		if msteams.IsOAuthError(err) || (errors.As(err, &graphErr) && graphErr.StatusCode == http.StatusUnauthorized) {
-			c.onDisconnect(c.userID)
+			c.onDisconnect(c.userID, err)
 		}
 	}
 	return result, err
@@ -140,7 +140,7 @@ func (c *ClientDisconnectionLayer) GetCodeSnippet(url string) (string, error) {
 	if err != nil {
 		var graphErr *msteams.GraphAPIError
 		if msteams.IsOAuthError(err) || (errors.As(err, &graphErr) && graphErr.StatusCode == http.StatusUnauthorized) {
-			c.onDisconnect(c.userID)
+			c.onDisconnect(c.userID, err)
 		}
 	}
 	return result, err
@@ -151,7 +151,7 @@ func (c *ClientDisconnectionLayer) GetFileContent(downloadURL string) ([]byte, e
 	if err != nil {
 		var graphErr *msteams.GraphAPIError
 		if msteams.IsOAuthError(err) || (errors.As(err, &graphErr) && graphErr.StatusCode == http.StatusUnauthorized) {
-			c.onDisconnect(c.userID)
+			c.onDisconnect(c.userID, err)
 		}
 	}
 	return result, err
@@ -162,7 +162,7 @@ func (c *ClientDisconnectionLayer) GetFileSizeAndDownloadURL(weburl string) (int
 	if err != nil {
 		var graphErr *msteams.GraphAPIError
 		if msteams.IsOAuthError(err) || (errors.As(err, &graphErr) && graphErr.StatusCode == http.StatusUnauthorized) {
-			c.onDisconnect(c.userID)
+			c.onDisconnect(c.userID, err)
 		}
 	}
 	return result, resultVar1, err
@@ -173,7 +173,7 @@ func (c *ClientDisconnectionLayer) GetHostedFileContent(activityIDs *clientmodel
 	if err != nil {
 		var graphErr *msteams.GraphAPIError
 		if msteams.IsOAuthError(err) || (errors.As(err, &graphErr) && graphErr.StatusCode == http.StatusUnauthorized) {
-			c.onDisconnect(c.userID)
+			c.onDisconnect(c.userID, err)
 		}
 	}
 	return result, err
@@ -184,7 +184,7 @@ func (c *ClientDisconnectionLayer) GetMe() (*clientmodels.User, error) {
 	if err != nil {
 		var graphErr *msteams.GraphAPIError
 		if msteams.IsOAuthError(err) || (errors.As(err, &graphErr) && graphErr.StatusCode == http.StatusUnauthorized) {
-			c.onDisconnect(c.userID)
+			c.onDisconnect(c.userID, err)
 		}
 	}
 	return result, err
@@ -195,7 +195,7 @@ func (c *ClientDisconnectionLayer) GetMessage(teamID string, channelID string, m
 	if err != nil {
 		var graphErr *msteams.GraphAPIError
 		if msteams.IsOAuthError(err) || (errors.As(err, &graphErr) && graphErr.StatusCode == http.StatusUnauthorized) {
-			c.onDisconnect(c.userID)
+			c.onDisconnect(c.userID, err)
 		}
 	}
 	return result, err
@@ -206,7 +206,7 @@ func (c *ClientDisconnectionLayer) GetMyID() (string, error) {
 	if err != nil {
 		var graphErr *msteams.GraphAPIError
 		if msteams.IsOAuthError(err) || (errors.As(err, &graphErr) && graphErr.StatusCode == http.StatusUnauthorized) {
-			c.onDisconnect(c.userID)
+			c.onDisconnect(c.userID, err)
 		}
 	}
 	return result, err
@@ -217,7 +217,7 @@ func (c *ClientDisconnectionLayer) GetPresencesForUsers(userIDs []string) (map[s
 	if err != nil {
 		var graphErr *msteams.GraphAPIError
 		if msteams.IsOAuthError(err) || (errors.As(err, &graphErr) && graphErr.StatusCode == http.StatusUnauthorized) {
-			c.onDisconnect(c.userID)
+			c.onDisconnect(c.userID, err)
 		}
 	}
 	return result, err
@@ -228,7 +228,7 @@ func (c *ClientDisconnectionLayer) GetReply(teamID string, channelID string, mes
 	if err != nil {
 		var graphErr *msteams.GraphAPIError
 		if msteams.IsOAuthError(err) || (errors.As(err, &graphErr) && graphErr.StatusCode == http.StatusUnauthorized) {
-			c.onDisconnect(c.userID)
+			c.onDisconnect(c.userID, err)
 		}
 	}
 	return result, err
@@ -239,7 +239,7 @@ func (c *ClientDisconnectionLayer) GetTeam(teamID string) (*clientmodels.Team, e
 	if err != nil {
 		var graphErr *msteams.GraphAPIError
 		if msteams.IsOAuthError(err) || (errors.As(err, &graphErr) && graphErr.StatusCode == http.StatusUnauthorized) {
-			c.onDisconnect(c.userID)
+			c.onDisconnect(c.userID, err)
 		}
 	}
 	return result, err
@@ -250,7 +250,7 @@ func (c *ClientDisconnectionLayer) GetTeams(filterQuery string) ([]*clientmodels
 	if err != nil {
 		var graphErr *msteams.GraphAPIError
 		if msteams.IsOAuthError(err) || (errors.As(err, &graphErr) && graphErr.StatusCode == http.StatusUnauthorized) {
-			c.onDisconnect(c.userID)
+			c.onDisconnect(c.userID, err)
 		}
 	}
 	return result, err
@@ -261,7 +261,7 @@ func (c *ClientDisconnectionLayer) GetUser(userID string) (*clientmodels.User, e
 	if err != nil {
 		var graphErr *msteams.GraphAPIError
 		if msteams.IsOAuthError(err) || (errors.As(err, &graphErr) && graphErr.StatusCode == http.StatusUnauthorized) {
-			c.onDisconnect(c.userID)
+			c.onDisconnect(c.userID, err)
 		}
 	}
 	return result, err
@@ -272,7 +272,7 @@ func (c *ClientDisconnectionLayer) GetUserAvatar(userID string) ([]byte, error)
 	if err != nil {
 		var graphErr *msteams.GraphAPIError
 		if msteams.IsOAuthError(err) || (errors.As(err, &graphErr) && graphErr.StatusCode == http.StatusUnauthorized) {
-			c.onDisconnect(c.userID)
+			c.onDisconnect(c.userID, err)
 		}
 	}
 	return result, err
@@ -283,21 +283,21 @@ func (c *ClientDisconnectionLayer) ListChannelMessages(teamID string, channelID
 	if err != nil {
 		var graphErr *msteams.GraphAPIError
 		if msteams.IsOAuthError(err) || (errors.As(err, &graphErr) && graphErr.StatusCode == http.StatusUnauthorized) {
-			c.onDisconnect(c.userID)
+			c.onDisconnect(c.userID, err)
 		}
 	}
 	return result, err
 }
 
-func (c *ClientDisconnectionLayer) ListChannels(teamID string) ([]clientmodels.Channel, error) {
-	result, err := c.Client.ListChannels(teamID)
+func (c *ClientDisconnectionLayer) ListChannels(teamID string, pageToken string) ([]clientmodels.Channel, string, error) {
+	result, resultVar1, err := c.Client.ListChannels(teamID, pageToken)
 	if err != nil {
 		var graphErr *msteams.GraphAPIError
 		if msteams.IsOAuthError(err) || (errors.As(err, &graphErr) && graphErr.StatusCode == http.StatusUnauthorized) {
-			c.onDisconnect(c.userID)
+			c.onDisconnect(c.userID, err)
 		}
 	}
-	return result, err
+	return result, resultVar1, err
 }
 
 func (c *ClientDisconnectionLayer) ListChatMessages(chatID string, since time.Time) ([]*clientmodels.Message, error) {
@@ -305,7 +305,7 @@ func (c *ClientDisconnectionLayer) ListChatMessages(chatID string, since time.Ti
 	if err != nil {
 		var graphErr *msteams.GraphAPIError
 		if msteams.IsOAuthError(err) || (errors.As(err, &graphErr) && graphErr.StatusCode == http.StatusUnauthorized) {
-			c.onDisconnect(c.userID)
+			c.onDisconnect(c.userID, err)
 		}
 	}
 	return result, err
@@ -316,21 +316,32 @@ func (c *ClientDisconnectionLayer) ListSubscriptions() ([]*clientmodels.Subscrip
 	if err != nil {
 		var graphErr *msteams.GraphAPIError
 		if msteams.IsOAuthError(err) || (errors.As(err, &graphErr) && graphErr.StatusCode == http.StatusUnauthorized) {
-			c.onDisconnect(c.userID)
+			c.onDisconnect(c.userID, err)
 		}
 	}
 	return result, err
 }
 
-func (c *ClientDisconnectionLayer) ListTeams() ([]clientmodels.Team, error) {
-	result, err := c.Client.ListTeams()
+func (c *ClientDisconnectionLayer) ListTeamMembers(teamID string, pageToken string) ([]clientmodels.TeamMember, string, error) {
+	result, resultVar1, err := c.Client.ListTeamMembers(teamID, pageToken)
 	if err != nil {
 		var graphErr *msteams.GraphAPIError
 		if msteams.IsOAuthError(err) || (errors.As(err, &graphErr) && graphErr.StatusCode == http.StatusUnauthorized) {
-			c.onDisconnect(c.userID)
+			c.onDisconnect(c.userID, err)
 		}
 	}
-	return result, err
+	return result, resultVar1, err
+}
+
+func (c *ClientDisconnectionLayer) ListTeams(pageToken string) ([]clientmodels.Team, string, error) {
+	result, resultVar1, err := c.Client.ListTeams(pageToken)
+	if err != nil {
+		var graphErr *msteams.GraphAPIError
+		if msteams.IsOAuthError(err) || (errors.As(err, &graphErr) && graphErr.StatusCode == http.StatusUnauthorized) {
+			c.onDisconnect(c.userID, err)
+		}
+	}
+	return result, resultVar1, err
 }
 
 func (c *ClientDisconnectionLayer) ListUsers() ([]clientmodels.User, error) {
@@ -338,7 +349,7 @@ func (c *ClientDisconnectionLayer) ListUsers() ([]clientmodels.User, error) {
 	if err != nil {
 		var graphErr *msteams.GraphAPIError
 		if msteams.IsOAuthError(err) || (errors.As(err, &graphErr) && graphErr.StatusCode == http.StatusUnauthorized) {
-			c.onDisconnect(c.userID)
+			c.onDisconnect(c.userID, err)
 		}
 	}
 	return result, err
@@ -349,7 +360,7 @@ func (c *ClientDisconnectionLayer) RefreshSubscription(subscriptionID string) (*
 	if err != nil {
 		var graphErr *msteams.GraphAPIError
 		if msteams.IsOAuthError(err) || (errors.As(err, &graphErr) && graphErr.StatusCode == http.StatusUnauthorized) {
-			c.onDisconnect(c.userID)
+			c.onDisconnect(c.userID, err)
 		}
 	}
 	return result, err
@@ -360,7 +371,7 @@ func (c *ClientDisconnectionLayer) RefreshToken(token *oauth2.Token) (*oauth2.To
 	if err != nil {
 		var graphErr *msteams.GraphAPIError
 		if msteams.IsOAuthError(err) || (errors.As(err, &graphErr) && graphErr.StatusCode == http.StatusUnauthorized) {
-			c.onDisconnect(c.userID)
+			c.onDisconnect(c.userID, err)
 		}
 	}
 	return result, err
@@ -371,7 +382,7 @@ func (c *ClientDisconnectionLayer) SendChat(chatID string, message string, paren
 	if err != nil {
 		var graphErr *msteams.GraphAPIError
 		if msteams.IsOAuthError(err) || (errors.As(err, &graphErr) && graphErr.StatusCode == http.StatusUnauthorized) {
-			c.onDisconnect(c.userID)
+			c.onDisconnect(c.userID, err)
 		}
 	}
 	return result, err
@@ -382,7 +393,7 @@ func (c *ClientDisconnectionLayer) SendMessage(teamID string, channelID string,
 	if err != nil {
 		var graphErr *msteams.GraphAPIError
 		if msteams.IsOAuthError(err) || (errors.As(err, &graphErr) && graphErr.StatusCode == http.StatusUnauthorized) {
-			c.onDisconnect(c.userID)
+			c.onDisconnect(c.userID, err)
 		}
 	}
 	return result, err
@@ -393,7 +404,7 @@ func (c *ClientDisconnectionLayer) SendMessageWithAttachments(teamID string, cha
 	if err != nil {
 		var graphErr *msteams.GraphAPIError
 		if msteams.IsOAuthError(err) || (errors.As(err, &graphErr) && graphErr.StatusCode == http.StatusUnauthorized) {
-			c.onDisconnect(c.userID)
+			c.onDisconnect(c.userID, err)
 		}
 	}
 	return result, err
@@ -404,7 +415,7 @@ func (c *ClientDisconnectionLayer) SetChatReaction(chatID string, messageID stri
 	if err != nil {
 		var graphErr *msteams.GraphAPIError
 		if msteams.IsOAuthError(err) || (errors.As(err, &graphErr) && graphErr.StatusCode == http.StatusUnauthorized) {
-			c.onDisconnect(c.userID)
+			c.onDisconnect(c.userID, err)
 		}
 	}
 	return result, err
@@ -415,7 +426,7 @@ func (c *ClientDisconnectionLayer) SetReaction(teamID string, channelID string,
 	if err != nil {
 		var graphErr *msteams.GraphAPIError
 		if msteams.IsOAuthError(err) || (errors.As(err, &graphErr) && graphErr.StatusCode == http.StatusUnauthorized) {
-			c.onDisconnect(c.userID)
+			c.onDisconnect(c.userID, err)
 		}
 	}
 	return result, err
@@ -426,7 +437,7 @@ func (c *ClientDisconnectionLayer) SubscribeToChannel(teamID string, channelID s
 	if err != nil {
 		var graphErr *msteams.GraphAPIError
 		if msteams.IsOAuthError(err) || (errors.As(err, &graphErr) && graphErr.StatusCode == http.StatusUnauthorized) {
-			c.onDisconnect(c.userID)
+			c.onDisconnect(c.userID, err)
 		}
 	}
 	return result, err
@@ -437,7 +448,7 @@ func (c *ClientDisconnectionLayer) SubscribeToChannels(baseURL string, webhookSe
 	if err != nil {
 		var graphErr *msteams.GraphAPIError
 		if msteams.IsOAuthError(err) || (errors.As(err, &graphErr) && graphErr.StatusCode == http.StatusUnauthorized) {
-			c.onDisconnect(c.userID)
+			c.onDisconnect(c.userID, err)
 		}
 	}
 	return result, err
@@ -448,7 +459,7 @@ func (c *ClientDisconnectionLayer) SubscribeToChats(baseURL string, webhookSecre
 	if err != nil {
 		var graphErr *msteams.GraphAPIError
 		if msteams.IsOAuthError(err) || (errors.As(err, &graphErr) && graphErr.StatusCode == http.StatusUnauthorized) {
-			c.onDisconnect(c.userID)
+			c.onDisconnect(c.userID, err)
 		}
 	}
 	return result, err
@@ -459,7 +470,7 @@ func (c *ClientDisconnectionLayer) SubscribeToUserChats(user string, baseURL str
 	if err != nil {
 		var graphErr *msteams.GraphAPIError
 		if msteams.IsOAuthError(err) || (errors.As(err, &graphErr) && graphErr.StatusCode == http.StatusUnauthorized) {
-			c.onDisconnect(c.userID)
+			c.onDisconnect(c.userID, err)
 		}
 	}
 	return result, err
@@ -470,7 +481,7 @@ func (c *ClientDisconnectionLayer) UnsetChatReaction(chatID string, messageID st
 	if err != nil {
 		var graphErr *msteams.GraphAPIError
 		if msteams.IsOAuthError(err) || (errors.As(err, &graphErr) && graphErr.StatusCode == http.StatusUnauthorized) {
-			c.onDisconnect(c.userID)
+			c.onDisconnect(c.userID, err)
 		}
 	}
 	return result, err
@@ -481,7 +492,7 @@ func (c *ClientDisconnectionLayer) UnsetReaction(teamID string, channelID string
 	if err != nil {
 		var graphErr *msteams.GraphAPIError
 		if msteams.IsOAuthError(err) || (errors.As(err, &graphErr) && graphErr.StatusCode == http.StatusUnauthorized) {
-			c.onDisconnect(c.userID)
+			c.onDisconnect(c.userID, err)
 		}
 	}
 	return result, err
@@ -492,7 +503,7 @@ func (c *ClientDisconnectionLayer) UpdateChatMessage(chatID string, msgID string
 	if err != nil {
 		var graphErr *msteams.GraphAPIError
 		if msteams.IsOAuthError(err) || (errors.As(err, &graphErr) && graphErr.StatusCode == http.StatusUnauthorized) {
-			c.onDisconnect(c.userID)
+			c.onDisconnect(c.userID, err)
 		}
 	}
 	return result, err
@@ -503,7 +514,7 @@ func (c *ClientDisconnectionLayer) UpdateMessage(teamID string, channelID string
 	if err != nil {
 		var graphErr *msteams.GraphAPIError
 		if msteams.IsOAuthError(err) || (errors.As(err, &graphErr) && graphErr.StatusCode == http.StatusUnauthorized) {
-			c.onDisconnect(c.userID)
+			c.onDisconnect(c.userID, err)
 		}
 	}
 	return result, err
@@ -514,13 +525,13 @@ func (c *ClientDisconnectionLayer) UploadFile(teamID string, channelID string, f
 	if err != nil {
 		var graphErr *msteams.GraphAPIError
 		if msteams.IsOAuthError(err) || (errors.As(err, &graphErr) && graphErr.StatusCode == http.StatusUnauthorized) {
-			c.onDisconnect(c.userID)
+			c.onDisconnect(c.userID, err)
 		}
 	}
 	return result, err
 }
 
-func New(childClient msteams.Client, userID string, onDisconnect func(userID string)) *ClientDisconnectionLayer {
+func New(childClient msteams.Client, userID string, onDisconnect func(userID string, cause error)) *ClientDisconnectionLayer {
 	return &ClientDisconnectionLayer{
 		Client:       childClient,
 		userID:       userID,
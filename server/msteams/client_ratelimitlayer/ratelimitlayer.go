@@ -0,0 +1,265 @@
+// Copyright (c) 2015-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+
+// Code generated by "make generate"
+// DO NOT EDIT
+
+package client_ratelimitlayer
+
+import (
+	"io"
+	"time"
+
+	"github.com/microsoftgraph/msgraph-sdk-go/models"
+	"golang.org/x/oauth2"
+
+	"github.com/mattermost/mattermost-plugin-msteams/server/msteams"
+	"github.com/mattermost/mattermost-plugin-msteams/server/msteams/clientmodels"
+)
+
+type ClientRateLimitLayer struct {
+	msteams.Client
+	limiter *msteams.RateLimiter
+}
+
+func (c *ClientRateLimitLayer) Connect() error {
+	c.limiter.Wait()
+	return c.Client.Connect()
+}
+
+func (c *ClientRateLimitLayer) CreateOrGetChatForUsers(usersIDs []string) (*clientmodels.Chat, error) {
+	c.limiter.Wait()
+	return c.Client.CreateOrGetChatForUsers(usersIDs)
+}
+
+func (c *ClientRateLimitLayer) DeleteChatMessage(userID string, chatID string, msgID string) error {
+	c.limiter.Wait()
+	return c.Client.DeleteChatMessage(userID, chatID, msgID)
+}
+
+func (c *ClientRateLimitLayer) DeleteMessage(teamID string, channelID string, parentID string, msgID string) error {
+	c.limiter.Wait()
+	return c.Client.DeleteMessage(teamID, channelID, parentID, msgID)
+}
+
+func (c *ClientRateLimitLayer) DeleteSubscription(subscriptionID string) error {
+	c.limiter.Wait()
+	return c.Client.DeleteSubscription(subscriptionID)
+}
+
+func (c *ClientRateLimitLayer) GetApp(applicationID string) (*clientmodels.App, error) {
+	c.limiter.Wait()
+	return c.Client.GetApp(applicationID)
+}
+
+func (c *ClientRateLimitLayer) GetChannelInTeam(teamID string, channelID string) (*clientmodels.Channel, error) {
+	c.limiter.Wait()
+	return c.Client.GetChannelInTeam(teamID, channelID)
+}
+
+func (c *ClientRateLimitLayer) GetChannelsInTeam(teamID string, filterQuery string) ([]*clientmodels.Channel, error) {
+	c.limiter.Wait()
+	return c.Client.GetChannelsInTeam(teamID, filterQuery)
+}
+
+func (c *ClientRateLimitLayer) GetChat(chatID string) (*clientmodels.Chat, error) {
+	c.limiter.Wait()
+	return c.Client.GetChat(chatID)
+}
+
+func (c *ClientRateLimitLayer) GetChatMessage(chatID string, messageID string) (*clientmodels.Message, error) {
+	c.limiter.Wait()
+	return c.Client.GetChatMessage(chatID, messageID)
+}
+
+func (c *ClientRateLimitLayer) GetCodeSnippet(url string) (string, error) {
+	c.limiter.Wait()
+	return c.Client.GetCodeSnippet(url)
+}
+
+func (c *ClientRateLimitLayer) GetFileContent(downloadURL string) ([]byte, error) {
+	c.limiter.Wait()
+	return c.Client.GetFileContent(downloadURL)
+}
+
+func (c *ClientRateLimitLayer) GetFileContentStream(downloadURL string, writer *io.PipeWriter, bufferSize int64) {
+	c.limiter.Wait()
+	c.Client.GetFileContentStream(downloadURL, writer, bufferSize)
+}
+
+func (c *ClientRateLimitLayer) GetFileSizeAndDownloadURL(weburl string) (int64, string, error) {
+	c.limiter.Wait()
+	return c.Client.GetFileSizeAndDownloadURL(weburl)
+}
+
+func (c *ClientRateLimitLayer) GetHostedFileContent(activityIDs *clientmodels.ActivityIds) ([]byte, error) {
+	c.limiter.Wait()
+	return c.Client.GetHostedFileContent(activityIDs)
+}
+
+func (c *ClientRateLimitLayer) GetMe() (*clientmodels.User, error) {
+	c.limiter.Wait()
+	return c.Client.GetMe()
+}
+
+func (c *ClientRateLimitLayer) GetMessage(teamID string, channelID string, messageID string) (*clientmodels.Message, error) {
+	c.limiter.Wait()
+	return c.Client.GetMessage(teamID, channelID, messageID)
+}
+
+func (c *ClientRateLimitLayer) GetMyID() (string, error) {
+	c.limiter.Wait()
+	return c.Client.GetMyID()
+}
+
+func (c *ClientRateLimitLayer) GetPresencesForUsers(userIDs []string) (map[string]*clientmodels.Presence, error) {
+	c.limiter.Wait()
+	return c.Client.GetPresencesForUsers(userIDs)
+}
+
+func (c *ClientRateLimitLayer) GetReply(teamID string, channelID string, messageID string, replyID string) (*clientmodels.Message, error) {
+	c.limiter.Wait()
+	return c.Client.GetReply(teamID, channelID, messageID, replyID)
+}
+
+func (c *ClientRateLimitLayer) GetTeam(teamID string) (*clientmodels.Team, error) {
+	c.limiter.Wait()
+	return c.Client.GetTeam(teamID)
+}
+
+func (c *ClientRateLimitLayer) GetTeams(filterQuery string) ([]*clientmodels.Team, error) {
+	c.limiter.Wait()
+	return c.Client.GetTeams(filterQuery)
+}
+
+func (c *ClientRateLimitLayer) GetUser(userID string) (*clientmodels.User, error) {
+	c.limiter.Wait()
+	return c.Client.GetUser(userID)
+}
+
+func (c *ClientRateLimitLayer) GetUserAvatar(userID string) ([]byte, error) {
+	c.limiter.Wait()
+	return c.Client.GetUserAvatar(userID)
+}
+
+func (c *ClientRateLimitLayer) ListChannelMessages(teamID string, channelID string, since time.Time) ([]*clientmodels.Message, error) {
+	c.limiter.Wait()
+	return c.Client.ListChannelMessages(teamID, channelID, since)
+}
+
+func (c *ClientRateLimitLayer) ListChannels(teamID string, pageToken string) ([]clientmodels.Channel, string, error) {
+	c.limiter.Wait()
+	return c.Client.ListChannels(teamID, pageToken)
+}
+
+func (c *ClientRateLimitLayer) ListChatMessages(chatID string, since time.Time) ([]*clientmodels.Message, error) {
+	c.limiter.Wait()
+	return c.Client.ListChatMessages(chatID, since)
+}
+
+func (c *ClientRateLimitLayer) ListSubscriptions() ([]*clientmodels.Subscription, error) {
+	c.limiter.Wait()
+	return c.Client.ListSubscriptions()
+}
+
+func (c *ClientRateLimitLayer) ListTeamMembers(teamID string, pageToken string) ([]clientmodels.TeamMember, string, error) {
+	c.limiter.Wait()
+	return c.Client.ListTeamMembers(teamID, pageToken)
+}
+
+func (c *ClientRateLimitLayer) ListTeams(pageToken string) ([]clientmodels.Team, string, error) {
+	c.limiter.Wait()
+	return c.Client.ListTeams(pageToken)
+}
+
+func (c *ClientRateLimitLayer) ListUsers() ([]clientmodels.User, error) {
+	c.limiter.Wait()
+	return c.Client.ListUsers()
+}
+
+func (c *ClientRateLimitLayer) RefreshSubscription(subscriptionID string) (*time.Time, error) {
+	c.limiter.Wait()
+	return c.Client.RefreshSubscription(subscriptionID)
+}
+
+func (c *ClientRateLimitLayer) RefreshToken(token *oauth2.Token) (*oauth2.Token, error) {
+	c.limiter.Wait()
+	return c.Client.RefreshToken(token)
+}
+
+func (c *ClientRateLimitLayer) SendChat(chatID string, message string, parentMessage *clientmodels.Message, attachments []*clientmodels.Attachment, mentions []models.ChatMessageMentionable) (*clientmodels.Message, error) {
+	c.limiter.Wait()
+	return c.Client.SendChat(chatID, message, parentMessage, attachments, mentions)
+}
+
+func (c *ClientRateLimitLayer) SendMessage(teamID string, channelID string, parentID string, message string) (*clientmodels.Message, error) {
+	c.limiter.Wait()
+	return c.Client.SendMessage(teamID, channelID, parentID, message)
+}
+
+func (c *ClientRateLimitLayer) SendMessageWithAttachments(teamID string, channelID string, parentID string, message string, attachments []*clientmodels.Attachment, mentions []models.ChatMessageMentionable) (*clientmodels.Message, error) {
+	c.limiter.Wait()
+	return c.Client.SendMessageWithAttachments(teamID, channelID, parentID, message, attachments, mentions)
+}
+
+func (c *ClientRateLimitLayer) SetChatReaction(chatID string, messageID string, userID string, emoji string) (*clientmodels.Message, error) {
+	c.limiter.Wait()
+	return c.Client.SetChatReaction(chatID, messageID, userID, emoji)
+}
+
+func (c *ClientRateLimitLayer) SetReaction(teamID string, channelID string, parentID string, messageID string, userID string, emoji string) (*clientmodels.Message, error) {
+	c.limiter.Wait()
+	return c.Client.SetReaction(teamID, channelID, parentID, messageID, userID, emoji)
+}
+
+func (c *ClientRateLimitLayer) SubscribeToChannel(teamID string, channelID string, baseURL string, webhookSecret string, certificate string) (*clientmodels.Subscription, error) {
+	c.limiter.Wait()
+	return c.Client.SubscribeToChannel(teamID, channelID, baseURL, webhookSecret, certificate)
+}
+
+func (c *ClientRateLimitLayer) SubscribeToChannels(baseURL string, webhookSecret string, pay bool, certificate string) (*clientmodels.Subscription, error) {
+	c.limiter.Wait()
+	return c.Client.SubscribeToChannels(baseURL, webhookSecret, pay, certificate)
+}
+
+func (c *ClientRateLimitLayer) SubscribeToChats(baseURL string, webhookSecret string, pay bool, certificate string) (*clientmodels.Subscription, error) {
+	c.limiter.Wait()
+	return c.Client.SubscribeToChats(baseURL, webhookSecret, pay, certificate)
+}
+
+func (c *ClientRateLimitLayer) SubscribeToUserChats(user string, baseURL string, webhookSecret string, pay bool, certificate string) (*clientmodels.Subscription, error) {
+	c.limiter.Wait()
+	return c.Client.SubscribeToUserChats(user, baseURL, webhookSecret, pay, certificate)
+}
+
+func (c *ClientRateLimitLayer) UnsetChatReaction(chatID string, messageID string, userID string, emoji string) (*clientmodels.Message, error) {
+	c.limiter.Wait()
+	return c.Client.UnsetChatReaction(chatID, messageID, userID, emoji)
+}
+
+func (c *ClientRateLimitLayer) UnsetReaction(teamID string, channelID string, parentID string, messageID string, userID string, emoji string) (*clientmodels.Message, error) {
+	c.limiter.Wait()
+	return c.Client.UnsetReaction(teamID, channelID, parentID, messageID, userID, emoji)
+}
+
+func (c *ClientRateLimitLayer) UpdateChatMessage(chatID string, msgID string, message string, mentions []models.ChatMessageMentionable) (*clientmodels.Message, error) {
+	c.limiter.Wait()
+	return c.Client.UpdateChatMessage(chatID, msgID, message, mentions)
+}
+
+func (c *ClientRateLimitLayer) UpdateMessage(teamID string, channelID string, parentID string, msgID string, message string, mentions []models.ChatMessageMentionable) (*clientmodels.Message, error) {
+	c.limiter.Wait()
+	return c.Client.UpdateMessage(teamID, channelID, parentID, msgID, message, mentions)
+}
+
+func (c *ClientRateLimitLayer) UploadFile(teamID string, channelID string, filename string, filesize int, mimeType string, data io.Reader, chat *clientmodels.Chat) (*clientmodels.Attachment, error) {
+	c.limiter.Wait()
+	return c.Client.UploadFile(teamID, channelID, filename, filesize, mimeType, data, chat)
+}
+
+func New(childClient msteams.Client, limiter *msteams.RateLimiter) *ClientRateLimitLayer {
+	return &ClientRateLimitLayer{
+		Client:  childClient,
+		limiter: limiter,
+	}
+}
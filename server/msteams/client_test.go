@@ -8,6 +8,7 @@ import (
 	"github.com/mattermost/mattermost/server/public/model"
 	"github.com/microsoftgraph/msgraph-sdk-go/models"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestConvertToMessage(t *testing.T) {
@@ -119,6 +120,36 @@ func TestConvertToMessage(t *testing.T) {
 				ChatID:    "mockChatID",
 			},
 		},
+		{
+			Name: "ConvertToMessage: From an application",
+			ChatMessage: func() models.ChatMessageable {
+				applicationID := "mockApplicationID"
+				applicationDisplayName := "Azure DevOps"
+				from := models.NewIdentitySet()
+				application := models.NewIdentity()
+				application.SetId(&applicationID)
+				application.SetDisplayName(&applicationDisplayName)
+				from.SetApplication(application)
+
+				message := models.NewChatMessage()
+				message.SetFrom(from)
+				message.SetCreatedDateTime(&time.Time{})
+				message.SetLastModifiedDateTime(&time.Time{})
+				return message
+			}(),
+			ExpectedResult: clientmodels.Message{
+				ApplicationID:          "mockApplicationID",
+				ApplicationDisplayName: "Azure DevOps",
+				Attachments:            []clientmodels.Attachment{},
+				Reactions:              []clientmodels.Reaction{},
+				Mentions:               []clientmodels.Mention{},
+				CreateAt:               time.Time{},
+				LastUpdateAt:           time.Time{},
+				ChannelID:              channelID,
+				TeamID:                 "mockTeamsTeamID",
+				ChatID:                 "mockChatID",
+			},
+		},
 		{
 			Name: "ConvertToMessage: With no data filled",
 			ChatMessage: func() models.ChatMessageable {
@@ -199,3 +230,41 @@ func TestGetResourceIds(t *testing.T) {
 		})
 	}
 }
+
+func TestParseChatMessage(t *testing.T) {
+	t.Run("chat message", func(t *testing.T) {
+		message, err := ParseChatMessage([]byte(`{
+  "id": "1700000000001",
+  "chatId": "19:chat-id@unq.gbl.spaces",
+  "createdDateTime": "2024-01-02T03:04:05Z",
+  "lastModifiedDateTime": "2024-01-02T03:04:06Z",
+  "from": {"user": {"id": "user-id", "displayName": "Sender Name"}},
+  "body": {"contentType": "html", "content": "<p>Hello <at id=\"0\">Everyone</at></p>"},
+  "attachments": [{"id": "file-id", "contentType": "reference", "contentUrl": "https://example.com/file.txt", "name": "file.txt"}],
+  "mentions": [{"id": 0, "mentionText": "Everyone", "mentioned": {"conversation": {"id": "19:chat-id@unq.gbl.spaces", "conversationIdentityType": "chat"}}}]
+}`))
+		require.NoError(t, err)
+
+		assert.Equal(t, &clientmodels.Message{
+			ID:              "1700000000001",
+			UserID:          "user-id",
+			UserDisplayName: "Sender Name",
+			Text:            `<p>Hello <at id="0">Everyone</at></p>`,
+			Attachments: []clientmodels.Attachment{
+				{ContentType: "reference", Name: "file.txt", ContentURL: "https://example.com/file.txt"},
+			},
+			Mentions: []clientmodels.Mention{
+				{ID: 0, MentionedText: "Everyone", ConversationID: "19:chat-id@unq.gbl.spaces"},
+			},
+			Reactions:    []clientmodels.Reaction{},
+			ChatID:       "19:chat-id@unq.gbl.spaces",
+			CreateAt:     time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+			LastUpdateAt: time.Date(2024, 1, 2, 3, 4, 6, 0, time.UTC),
+		}, message)
+	})
+
+	t.Run("invalid JSON", func(t *testing.T) {
+		_, err := ParseChatMessage([]byte(`{"id":`))
+		assert.Error(t, err)
+	})
+}
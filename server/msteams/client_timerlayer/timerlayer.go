@@ -566,12 +566,12 @@ func (c *ClientTimerLayer) ListChannelMessages(teamID string, channelID string,
 	return result, err
 }
 
-func (c *ClientTimerLayer) ListChannels(teamID string) ([]clientmodels.Channel, error) {
+func (c *ClientTimerLayer) ListChannels(teamID string, pageToken string) ([]clientmodels.Channel, string, error) {
 	statusCode := "2XX"
 	success := "true"
 	start := time.Now()
 
-	result, err := c.Client.ListChannels(teamID)
+	result, resultVar1, err := c.Client.ListChannels(teamID, pageToken)
 
 	elapsed := float64(time.Since(start)) / float64(time.Second)
 
@@ -585,7 +585,7 @@ func (c *ClientTimerLayer) ListChannels(teamID string) ([]clientmodels.Channel,
 	}
 
 	c.metrics.ObserveMSGraphClientMethodDuration("Client.ListChannels", success, statusCode, elapsed)
-	return result, err
+	return result, resultVar1, err
 }
 
 func (c *ClientTimerLayer) ListChatMessages(chatID string, since time.Time) ([]*clientmodels.Message, error) {
@@ -632,12 +632,34 @@ func (c *ClientTimerLayer) ListSubscriptions() ([]*clientmodels.Subscription, er
 	return result, err
 }
 
-func (c *ClientTimerLayer) ListTeams() ([]clientmodels.Team, error) {
+func (c *ClientTimerLayer) ListTeamMembers(teamID string, pageToken string) ([]clientmodels.TeamMember, string, error) {
 	statusCode := "2XX"
 	success := "true"
 	start := time.Now()
 
-	result, err := c.Client.ListTeams()
+	result, resultVar1, err := c.Client.ListTeamMembers(teamID, pageToken)
+
+	elapsed := float64(time.Since(start)) / float64(time.Second)
+
+	if err != nil {
+		success = "false"
+		statusCode = "0"
+		var apiErr *msteams.GraphAPIError
+		if errors.As(err, &apiErr) {
+			statusCode = strconv.Itoa(apiErr.StatusCode)
+		}
+	}
+
+	c.metrics.ObserveMSGraphClientMethodDuration("Client.ListTeamMembers", success, statusCode, elapsed)
+	return result, resultVar1, err
+}
+
+func (c *ClientTimerLayer) ListTeams(pageToken string) ([]clientmodels.Team, string, error) {
+	statusCode := "2XX"
+	success := "true"
+	start := time.Now()
+
+	result, resultVar1, err := c.Client.ListTeams(pageToken)
 
 	elapsed := float64(time.Since(start)) / float64(time.Second)
 
@@ -651,7 +673,7 @@ func (c *ClientTimerLayer) ListTeams() ([]clientmodels.Team, error) {
 	}
 
 	c.metrics.ObserveMSGraphClientMethodDuration("Client.ListTeams", success, statusCode, elapsed)
-	return result, err
+	return result, resultVar1, err
 }
 
 func (c *ClientTimerLayer) ListUsers() ([]clientmodels.User, error) {
@@ -18,6 +18,13 @@ type ChatMember struct {
 	Email       string
 }
 
+type TeamMember struct {
+	DisplayName string
+	UserID      string
+	Email       string
+	Roles       []string
+}
+
 type Attachment struct {
 	ID           string
 	ContentType  string
@@ -55,6 +62,11 @@ type Message struct {
 	ChatID          string
 	CreateAt        time.Time
 	LastUpdateAt    time.Time
+
+	// ApplicationID and ApplicationDisplayName identify the bot or connector that sent the
+	// message, if it was not sent by a user.
+	ApplicationID          string
+	ApplicationDisplayName string
 }
 
 type Subscription struct {
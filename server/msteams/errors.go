@@ -0,0 +1,69 @@
+package msteams
+
+import (
+	"errors"
+	"net/http"
+
+	azidentity "github.com/Azure/azure-sdk-for-go/sdk/azidentity"
+	"golang.org/x/oauth2"
+)
+
+// ErrorCategory classifies an error returned by the client by its likely remedy.
+type ErrorCategory string
+
+const (
+	ErrorCategoryUnknown    ErrorCategory = "unknown"
+	ErrorCategoryAuth       ErrorCategory = "auth"
+	ErrorCategoryPermission ErrorCategory = "permission"
+	ErrorCategoryThrottling ErrorCategory = "throttling"
+	ErrorCategoryNotFound   ErrorCategory = "not_found"
+	ErrorCategoryConversion ErrorCategory = "conversion"
+)
+
+// ConversionError is returned when a response from MS Graph is missing data the client requires.
+type ConversionError struct {
+	Message string
+}
+
+func (e *ConversionError) Error() string {
+	return e.Message
+}
+
+func newConversionError(message string) error {
+	return &ConversionError{Message: message}
+}
+
+// CategorizeError returns the category of an error returned by the client, unwrapping it as
+// needed. Errors that cannot be classified are reported as ErrorCategoryUnknown.
+func CategorizeError(err error) ErrorCategory {
+	if err == nil {
+		return ErrorCategoryUnknown
+	}
+
+	var conversionErr *ConversionError
+	if errors.As(err, &conversionErr) {
+		return ErrorCategoryConversion
+	}
+
+	var graphErr *GraphAPIError
+	if errors.As(err, &graphErr) {
+		switch graphErr.StatusCode {
+		case http.StatusUnauthorized:
+			return ErrorCategoryAuth
+		case http.StatusForbidden:
+			return ErrorCategoryPermission
+		case http.StatusNotFound:
+			return ErrorCategoryNotFound
+		case http.StatusTooManyRequests, http.StatusServiceUnavailable:
+			return ErrorCategoryThrottling
+		}
+	}
+
+	var retrieveErr *oauth2.RetrieveError
+	var authFailedErr *azidentity.AuthenticationFailedError
+	if errors.As(err, &retrieveErr) || errors.As(err, &authFailedErr) || IsOAuthError(err) {
+		return ErrorCategoryAuth
+	}
+
+	return ErrorCategoryUnknown
+}
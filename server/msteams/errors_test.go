@@ -0,0 +1,114 @@
+package msteams
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/microsoftgraph/msgraph-sdk-go/models/odataerrors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/oauth2"
+)
+
+func TestCategorizeError(t *testing.T) {
+	for _, testCase := range []struct {
+		Name     string
+		Err      error
+		Expected ErrorCategory
+	}{
+		{
+			Name:     "nil error",
+			Err:      nil,
+			Expected: ErrorCategoryUnknown,
+		},
+		{
+			Name:     "generic error",
+			Err:      errors.New("connection reset"),
+			Expected: ErrorCategoryUnknown,
+		},
+		{
+			Name:     "oauth error",
+			Err:      errors.New(`oauth2: "invalid_grant"`),
+			Expected: ErrorCategoryAuth,
+		},
+		{
+			Name:     "wrapped oauth retrieve error",
+			Err:      fmt.Errorf("failed to refresh: %w", &oauth2.RetrieveError{}),
+			Expected: ErrorCategoryAuth,
+		},
+		{
+			Name:     "unauthorized",
+			Err:      &GraphAPIError{StatusCode: http.StatusUnauthorized},
+			Expected: ErrorCategoryAuth,
+		},
+		{
+			Name:     "forbidden",
+			Err:      &GraphAPIError{StatusCode: http.StatusForbidden},
+			Expected: ErrorCategoryPermission,
+		},
+		{
+			Name:     "not found",
+			Err:      fmt.Errorf("wrapped: %w", &GraphAPIError{StatusCode: http.StatusNotFound}),
+			Expected: ErrorCategoryNotFound,
+		},
+		{
+			Name:     "too many requests",
+			Err:      &GraphAPIError{StatusCode: http.StatusTooManyRequests},
+			Expected: ErrorCategoryThrottling,
+		},
+		{
+			Name:     "service unavailable",
+			Err:      &GraphAPIError{StatusCode: http.StatusServiceUnavailable},
+			Expected: ErrorCategoryThrottling,
+		},
+		{
+			Name:     "other graph error",
+			Err:      &GraphAPIError{StatusCode: http.StatusBadRequest},
+			Expected: ErrorCategoryUnknown,
+		},
+		{
+			Name:     "conversion error",
+			Err:      newConversionError("received empty user ID from MS Graph"),
+			Expected: ErrorCategoryConversion,
+		},
+	} {
+		t.Run(testCase.Name, func(t *testing.T) {
+			assert.Equal(t, testCase.Expected, CategorizeError(testCase.Err))
+		})
+	}
+}
+
+func TestCategorizeNormalizedODataError(t *testing.T) {
+	for _, testCase := range []struct {
+		StatusCode int
+		Expected   ErrorCategory
+	}{
+		{http.StatusUnauthorized, ErrorCategoryAuth},
+		{http.StatusForbidden, ErrorCategoryPermission},
+		{http.StatusNotFound, ErrorCategoryNotFound},
+		{http.StatusTooManyRequests, ErrorCategoryThrottling},
+		{http.StatusServiceUnavailable, ErrorCategoryThrottling},
+		{http.StatusBadRequest, ErrorCategoryUnknown},
+	} {
+		t.Run(http.StatusText(testCase.StatusCode), func(t *testing.T) {
+			code := "Forbidden"
+			message := "Missing role permissions on the request."
+			mainErr := odataerrors.NewMainError()
+			mainErr.SetCode(&code)
+			mainErr.SetMessage(&message)
+
+			odataErr := odataerrors.NewODataError()
+			odataErr.SetErrorEscaped(mainErr)
+			odataErr.SetStatusCode(testCase.StatusCode)
+
+			err := NormalizeGraphAPIError(odataErr)
+			var graphErr *GraphAPIError
+			require.ErrorAs(t, err, &graphErr)
+			assert.Equal(t, testCase.StatusCode, graphErr.StatusCode)
+			assert.Equal(t, "Missing role permissions on the request.", graphErr.Message)
+			assert.Equal(t, testCase.Expected, CategorizeError(err))
+		})
+	}
+}
@@ -52,8 +52,9 @@ type Client interface {
 	GetCodeSnippet(url string) (string, error)
 	RefreshToken(token *oauth2.Token) (*oauth2.Token, error)
 	ListUsers() ([]clientmodels.User, error)
-	ListTeams() ([]clientmodels.Team, error)
-	ListChannels(teamID string) ([]clientmodels.Channel, error)
+	ListTeams(pageToken string) ([]clientmodels.Team, string, error)
+	ListChannels(teamID, pageToken string) ([]clientmodels.Channel, string, error)
+	ListTeamMembers(teamID, pageToken string) ([]clientmodels.TeamMember, string, error)
 	ListChannelMessages(teamID, channelID string, since time.Time) ([]*clientmodels.Message, error)
 	ListChatMessages(chatID string, since time.Time) ([]*clientmodels.Message, error)
 	GetApp(applicationID string) (*clientmodels.App, error)
@@ -33,6 +33,9 @@ func main() {
 	if err := buildDisconnectionLayer(); err != nil {
 		log.Fatal(err)
 	}
+	if err := buildRateLimitLayer(); err != nil {
+		log.Fatal(err)
+	}
 }
 
 func buildTimerLayer() error {
@@ -71,6 +74,24 @@ func buildDisconnectionLayer() error {
 	return os.WriteFile(path.Join("client_disconnectionlayer", "disconnectionlayer.go"), formatedCode, 0600)
 }
 
+func buildRateLimitLayer() error {
+	code, err := generateLayer("ClientRateLimitLayer", "ratelimit_layer.go.tmpl")
+	if err != nil {
+		return err
+	}
+
+	formatedCode, err := format.Source(code)
+	if err != nil {
+		return err
+	}
+
+	if err = os.MkdirAll("client_ratelimitlayer", 0700); err != nil {
+		return err
+	}
+
+	return os.WriteFile(path.Join("client_ratelimitlayer", "ratelimitlayer.go"), formatedCode, 0600)
+}
+
 type methodParam struct {
 	Name string
 	Type string
@@ -543,27 +543,34 @@ func (_m *Client) ListChannelMessages(teamID string, channelID string, since tim
 	return r0, r1
 }
 
-// ListChannels provides a mock function with given fields: teamID
-func (_m *Client) ListChannels(teamID string) ([]clientmodels.Channel, error) {
-	ret := _m.Called(teamID)
+// ListChannels provides a mock function with given fields: teamID, pageToken
+func (_m *Client) ListChannels(teamID string, pageToken string) ([]clientmodels.Channel, string, error) {
+	ret := _m.Called(teamID, pageToken)
 
 	var r0 []clientmodels.Channel
-	if rf, ok := ret.Get(0).(func(string) []clientmodels.Channel); ok {
-		r0 = rf(teamID)
+	if rf, ok := ret.Get(0).(func(string, string) []clientmodels.Channel); ok {
+		r0 = rf(teamID, pageToken)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).([]clientmodels.Channel)
 		}
 	}
 
-	var r1 error
-	if rf, ok := ret.Get(1).(func(string) error); ok {
-		r1 = rf(teamID)
+	var r1 string
+	if rf, ok := ret.Get(1).(func(string, string) string); ok {
+		r1 = rf(teamID, pageToken)
 	} else {
-		r1 = ret.Error(1)
+		r1 = ret.Get(1).(string)
 	}
 
-	return r0, r1
+	var r2 error
+	if rf, ok := ret.Get(2).(func(string, string) error); ok {
+		r2 = rf(teamID, pageToken)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
 }
 
 // ListChatMessages provides a mock function with given fields: chatID, since
@@ -612,27 +619,64 @@ func (_m *Client) ListSubscriptions() ([]*clientmodels.Subscription, error) {
 	return r0, r1
 }
 
-// ListTeams provides a mock function with given fields:
-func (_m *Client) ListTeams() ([]clientmodels.Team, error) {
-	ret := _m.Called()
+// ListTeamMembers provides a mock function with given fields: teamID, pageToken
+func (_m *Client) ListTeamMembers(teamID string, pageToken string) ([]clientmodels.TeamMember, string, error) {
+	ret := _m.Called(teamID, pageToken)
+
+	var r0 []clientmodels.TeamMember
+	if rf, ok := ret.Get(0).(func(string, string) []clientmodels.TeamMember); ok {
+		r0 = rf(teamID, pageToken)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]clientmodels.TeamMember)
+		}
+	}
+
+	var r1 string
+	if rf, ok := ret.Get(1).(func(string, string) string); ok {
+		r1 = rf(teamID, pageToken)
+	} else {
+		r1 = ret.Get(1).(string)
+	}
+
+	var r2 error
+	if rf, ok := ret.Get(2).(func(string, string) error); ok {
+		r2 = rf(teamID, pageToken)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
+// ListTeams provides a mock function with given fields: pageToken
+func (_m *Client) ListTeams(pageToken string) ([]clientmodels.Team, string, error) {
+	ret := _m.Called(pageToken)
 
 	var r0 []clientmodels.Team
-	if rf, ok := ret.Get(0).(func() []clientmodels.Team); ok {
-		r0 = rf()
+	if rf, ok := ret.Get(0).(func(string) []clientmodels.Team); ok {
+		r0 = rf(pageToken)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).([]clientmodels.Team)
 		}
 	}
 
-	var r1 error
-	if rf, ok := ret.Get(1).(func() error); ok {
-		r1 = rf()
+	var r1 string
+	if rf, ok := ret.Get(1).(func(string) string); ok {
+		r1 = rf(pageToken)
 	} else {
-		r1 = ret.Error(1)
+		r1 = ret.Get(1).(string)
 	}
 
-	return r0, r1
+	var r2 error
+	if rf, ok := ret.Get(2).(func(string) error); ok {
+		r2 = rf(pageToken)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
 }
 
 // ListUsers provides a mock function with given fields:
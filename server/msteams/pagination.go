@@ -0,0 +1,46 @@
+package msteams
+
+import "errors"
+
+// MaxListPages caps the pages followed by ForEachPage, guarding against runaway listings.
+const MaxListPages = 50
+
+// ErrListPageLimitReached is returned by ForEachPage when the listing was cut short after
+// MaxListPages pages.
+var ErrListPageLimitReached = errors.New("listing stopped after reaching the page limit")
+
+// ForEachPage streams the items of a paginated listing to handle, fetching each page with
+// listPage, starting from an empty page token and following the returned page tokens. It stops
+// when handle returns false, at the end of the listing, or after MaxListPages pages.
+func ForEachPage[T any](listPage func(pageToken string) ([]T, string, error), handle func(item T) bool) error {
+	pageToken := ""
+	for page := 0; page < MaxListPages; page++ {
+		items, nextPageToken, err := listPage(pageToken)
+		if err != nil {
+			return err
+		}
+
+		for _, item := range items {
+			if !handle(item) {
+				return nil
+			}
+		}
+
+		if nextPageToken == "" {
+			return nil
+		}
+		pageToken = nextPageToken
+	}
+
+	return ErrListPageLimitReached
+}
+
+// nextPageToken returns the token of the page following a listing response, or an empty string
+// if it was the last page. The token is the @odata.nextLink of the response.
+func nextPageToken(nextLink *string) string {
+	if nextLink == nil {
+		return ""
+	}
+
+	return *nextLink
+}
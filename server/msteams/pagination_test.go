@@ -0,0 +1,82 @@
+package msteams
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestForEachPage(t *testing.T) {
+	// pages serves numbered items, three per page, over the given number of pages.
+	pages := func(count int, requested *[]string) func(pageToken string) ([]int, string, error) {
+		return func(pageToken string) ([]int, string, error) {
+			*requested = append(*requested, pageToken)
+
+			page := len(*requested) - 1
+			items := []int{page * 3, page*3 + 1, page*3 + 2}
+			if page == count-1 {
+				return items, "", nil
+			}
+			return items, fmt.Sprintf("page-%d", page+1), nil
+		}
+	}
+
+	t.Run("follows every page", func(t *testing.T) {
+		var requested []string
+		var items []int
+		err := ForEachPage(pages(3, &requested), func(item int) bool {
+			items = append(items, item)
+			return true
+		})
+
+		require.NoError(t, err)
+		assert.Equal(t, []string{"", "page-1", "page-2"}, requested)
+		assert.Equal(t, []int{0, 1, 2, 3, 4, 5, 6, 7, 8}, items)
+	})
+
+	t.Run("stops when the caller is done", func(t *testing.T) {
+		var requested []string
+		var items []int
+		err := ForEachPage(pages(3, &requested), func(item int) bool {
+			items = append(items, item)
+			return item < 4
+		})
+
+		require.NoError(t, err)
+		assert.Equal(t, []string{"", "page-1"}, requested)
+		assert.Equal(t, []int{0, 1, 2, 3, 4}, items)
+	})
+
+	t.Run("caps the page count", func(t *testing.T) {
+		var requested []string
+		count := 0
+		err := ForEachPage(pages(MaxListPages+1, &requested), func(int) bool {
+			count++
+			return true
+		})
+
+		assert.ErrorIs(t, err, ErrListPageLimitReached)
+		assert.Len(t, requested, MaxListPages)
+		assert.Equal(t, MaxListPages*3, count)
+	})
+
+	t.Run("page error", func(t *testing.T) {
+		pageErr := errors.New("throttled")
+		var items []int
+		err := ForEachPage(func(pageToken string) ([]int, string, error) {
+			if pageToken == "" {
+				return []int{1}, "page-1", nil
+			}
+			return nil, "", pageErr
+		}, func(item int) bool {
+			items = append(items, item)
+			return true
+		})
+
+		assert.ErrorIs(t, err, pageErr)
+		assert.Equal(t, []int{1}, items)
+	})
+}
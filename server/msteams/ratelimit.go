@@ -0,0 +1,91 @@
+package msteams
+
+import (
+	"sync"
+	"time"
+)
+
+// maxRateLimiterWait bounds how long a request waits for the rate limiter. Requests that would
+// wait longer are made after that wait regardless, leaving any throttling to MS Graph.
+const maxRateLimiterWait = 30 * time.Second
+
+// RateLimiter is a token bucket limiting the rate of requests made to MS Graph. It is safe for
+// concurrent use, and may be shared by all clients for the tenant.
+type RateLimiter struct {
+	lock   sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+	now    func() time.Time
+	sleep  func(time.Duration)
+}
+
+// NewRateLimiter creates a RateLimiter allowing the given number of requests per second on average,
+// with bursts of up to the given size. A non-positive rate disables limiting.
+func NewRateLimiter(ratePerSecond float64, burst int) *RateLimiter {
+	l := &RateLimiter{
+		now:   time.Now,
+		sleep: time.Sleep,
+	}
+	l.SetRate(ratePerSecond, burst)
+
+	return l
+}
+
+// SetRate changes the rate and burst size of the limiter, refilling the bucket.
+func (l *RateLimiter) SetRate(ratePerSecond float64, burst int) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	if burst < 1 {
+		burst = 1
+	}
+
+	l.rate = ratePerSecond
+	l.burst = float64(burst)
+	l.tokens = l.burst
+	l.last = l.now()
+}
+
+// Wait blocks until a request may be made, or for at most maxRateLimiterWait.
+func (l *RateLimiter) Wait() {
+	if wait := l.reserve(); wait > 0 {
+		l.sleep(wait)
+	}
+}
+
+// reserve consumes a token, returning how long the caller must wait before the token is
+// available. Tokens may be borrowed from the future, queueing callers fairly in arrival order, but
+// no further ahead than maxRateLimiterWait: beyond that, callers wait that long without a token.
+func (l *RateLimiter) reserve() time.Duration {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	if l.rate <= 0 {
+		return 0
+	}
+
+	l.refill()
+	if l.tokens-1 < -l.rate*maxRateLimiterWait.Seconds() {
+		return maxRateLimiterWait
+	}
+
+	l.tokens--
+	if l.tokens >= 0 {
+		return 0
+	}
+
+	return time.Duration(-l.tokens / l.rate * float64(time.Second))
+}
+
+// refill adds the tokens accumulated since the last refill, up to the burst size. Must be called
+// with the lock held.
+func (l *RateLimiter) refill() {
+	now := l.now()
+	l.tokens += now.Sub(l.last).Seconds() * l.rate
+	if l.tokens > l.burst {
+		l.tokens = l.burst
+	}
+	l.last = now
+}
@@ -0,0 +1,74 @@
+package msteams
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestRateLimiter(ratePerSecond float64, burst int) (*RateLimiter, *time.Time, *[]time.Duration) {
+	now := time.Now()
+	var sleeps []time.Duration
+
+	l := &RateLimiter{
+		now: func() time.Time { return now },
+		sleep: func(d time.Duration) {
+			sleeps = append(sleeps, d)
+			now = now.Add(d)
+		},
+	}
+	l.SetRate(ratePerSecond, burst)
+
+	return l, &now, &sleeps
+}
+
+func TestRateLimiter(t *testing.T) {
+	t.Run("unlimited", func(t *testing.T) {
+		l, _, sleeps := newTestRateLimiter(0, 1)
+		for i := 0; i < 100; i++ {
+			l.Wait()
+		}
+		assert.Empty(t, *sleeps)
+	})
+
+	t.Run("burst then wait", func(t *testing.T) {
+		l, _, sleeps := newTestRateLimiter(10, 2)
+		l.Wait()
+		l.Wait()
+		assert.Empty(t, *sleeps)
+
+		l.Wait()
+		assert.Equal(t, []time.Duration{100 * time.Millisecond}, *sleeps)
+	})
+
+	t.Run("tokens refill over time", func(t *testing.T) {
+		l, now, _ := newTestRateLimiter(1, 1)
+		assert.Zero(t, l.reserve())
+		assert.Equal(t, time.Second, l.reserve())
+
+		*now = now.Add(2 * time.Second)
+		assert.Zero(t, l.reserve())
+	})
+
+	t.Run("tokens do not exceed burst", func(t *testing.T) {
+		l, now, _ := newTestRateLimiter(1, 2)
+		*now = now.Add(time.Hour)
+		assert.Zero(t, l.reserve())
+		assert.Zero(t, l.reserve())
+		assert.Equal(t, time.Second, l.reserve())
+	})
+
+	t.Run("wait is bounded", func(t *testing.T) {
+		l, _, sleeps := newTestRateLimiter(1, 1)
+		for i := 0; i < 100; i++ {
+			l.reserve()
+		}
+		for _, wait := range []time.Duration{l.reserve(), l.reserve()} {
+			assert.Equal(t, maxRateLimiterWait, wait)
+		}
+
+		l.Wait()
+		assert.Equal(t, []time.Duration{maxRateLimiterWait}, *sleeps)
+	})
+}
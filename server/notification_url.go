@@ -0,0 +1,72 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/pkg/errors"
+)
+
+const probeNotificationURLTimeout = 10 * time.Second
+
+// checkNotificationURL diagnoses why MS Graph might be unable to deliver change notifications to
+// the given plugin URL: MS Graph requires an https URL resolving to a public address, and
+// validates it by expecting the validation token it sends to be echoed back. A private address is
+// only reported as a warning, since the URL may resolve differently from the public internet, e.g.
+// with split-horizon DNS.
+func checkNotificationURL(client *http.Client, baseURL string) (string, error) {
+	notificationURL, err := url.Parse(baseURL + "changes")
+	if err != nil {
+		return "", errors.Wrap(err, "invalid notification URL")
+	}
+
+	if notificationURL.Scheme != "https" {
+		return "", errors.Errorf("the Site URL must use https, but %s does not", notificationURL.Redacted())
+	}
+
+	ips, err := net.LookupIP(notificationURL.Hostname())
+	if err != nil {
+		return "", errors.Wrapf(err, "unable to resolve %s", notificationURL.Hostname())
+	}
+
+	warning := ""
+	for _, ip := range ips {
+		if ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsUnspecified() {
+			warning = fmt.Sprintf("%s resolves to %s from this server, which is not reachable from the public internet unless it resolves to a public address there", notificationURL.Hostname(), ip)
+			break
+		}
+	}
+
+	return warning, probeNotificationURL(client, notificationURL)
+}
+
+// probeNotificationURL performs the validation handshake MS Graph makes when subscribing.
+func probeNotificationURL(client *http.Client, notificationURL *url.URL) error {
+	validationToken := model.NewId()
+	probeURL := *notificationURL
+	query := probeURL.Query()
+	query.Set("validationToken", validationToken)
+	probeURL.RawQuery = query.Encode()
+
+	resp, err := client.Post(probeURL.String(), "text/plain", nil)
+	if err != nil {
+		return errors.Wrap(err, "unable to reach the notification URL")
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, int64(len(validationToken)+1)))
+	if err != nil {
+		return errors.Wrap(err, "unable to read the response from the notification URL")
+	}
+	if resp.StatusCode != http.StatusOK || strings.TrimSpace(string(body)) != validationToken {
+		return errors.Errorf("the notification URL answered with status code %d without echoing the validation token, so a proxy may be intercepting it", resp.StatusCode)
+	}
+
+	return nil
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckNotificationURL(t *testing.T) {
+	t.Run("http", func(t *testing.T) {
+		_, err := checkNotificationURL(http.DefaultClient, "http://example.com/plugins/com.mattermost.msteams-sync/")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "must use https")
+	})
+
+	t.Run("loopback is a warning, still probed", func(t *testing.T) {
+		server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte(r.URL.Query().Get("validationToken")))
+		}))
+		t.Cleanup(server.Close)
+
+		warning, err := checkNotificationURL(server.Client(), server.URL+"/plugins/com.mattermost.msteams-sync/")
+		require.NoError(t, err)
+		assert.Contains(t, warning, "not reachable from the public internet")
+	})
+}
+
+func TestProbeNotificationURL(t *testing.T) {
+	t.Run("validation token echoed", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte(r.URL.Query().Get("validationToken")))
+		}))
+		defer server.Close()
+
+		notificationURL, err := url.Parse(server.URL + "/changes")
+		require.NoError(t, err)
+		assert.NoError(t, probeNotificationURL(server.Client(), notificationURL))
+	})
+
+	t.Run("intercepted by a proxy", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte("<html>Sign in</html>"))
+		}))
+		defer server.Close()
+
+		notificationURL, err := url.Parse(server.URL + "/changes")
+		require.NoError(t, err)
+		err = probeNotificationURL(server.Client(), notificationURL)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "without echoing the validation token")
+	})
+
+	t.Run("unreachable", func(t *testing.T) {
+		server := httptest.NewServer(http.NotFoundHandler())
+		server.Close()
+
+		notificationURL, err := url.Parse(server.URL + "/changes")
+		require.NoError(t, err)
+		assert.Error(t, probeNotificationURL(http.DefaultClient, notificationURL))
+	})
+}
@@ -3,13 +3,48 @@ package main
 import (
 	"database/sql"
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/mattermost/mattermost-plugin-msteams/server/metrics"
 	"github.com/mattermost/mattermost-plugin-msteams/server/msteams/clientmodels"
 	"github.com/mattermost/mattermost-plugin-msteams/server/store/storemodels"
 )
 
-func (ah *ActivityHandler) handleCreatedActivityNotification(msg *clientmodels.Message, chat *clientmodels.Chat) string {
+// messageStatsMonthLayout formats the month in which messages are counted for licensing audits.
+const messageStatsMonthLayout = "2006-01"
+
+// messageStatsMonth returns the month, in UTC, in which a message relayed at the given time is
+// counted.
+func messageStatsMonth(t time.Time) string {
+	return t.UTC().Format(messageStatsMonthLayout)
+}
+
+// messageSenderDisplayName names the sender of a message in notifications, attributing messages
+// posted by bots and connectors to their application rather than to a user. Notifications are
+// still posted by the plugin's bot, as for any other chat message: a bot user per application
+// would need a DM channel per application with every recipient, and MS Graph doesn't expose the
+// icon of the application behind a bot or connector.
+func messageSenderDisplayName(msg *clientmodels.Message) string {
+	if msg.UserID != "" || msg.ApplicationID == "" {
+		return msg.UserDisplayName
+	}
+
+	if msg.ApplicationDisplayName == "" {
+		return "An app"
+	}
+
+	return msg.ApplicationDisplayName + " (app)"
+}
+
+// teamsMessageLink returns a link opening the given chat message in MS Teams.
+func teamsMessageLink(chatID, messageID, tenantID string) string {
+	return fmt.Sprintf("https://teams.microsoft.com/l/message/%s/%s?tenantId=%s&context={\"contextType\":\"chat\"}", chatID, messageID, tenantID)
+}
+
+// handleCreatedActivityNotification notifies the chat members of a new message, returning the
+// discarded reason.
+func (ah *ActivityHandler) handleCreatedActivityNotification(logger *activityLogger, msg *clientmodels.Message, chat *clientmodels.Chat) string {
 	if chat == nil {
 		// We're only going to support notifications from chats for now.
 		return metrics.DiscardedReasonChannelNotificationsUnsupported
@@ -28,14 +63,16 @@ func (ah *ActivityHandler) handleCreatedActivityNotification(msg *clientmodels.M
 
 	presences, err := ah.plugin.GetClientForApp().GetPresencesForUsers(userIDs)
 	if err != nil {
-		ah.plugin.GetAPI().LogWarn("Failed to fetch presence information for chat members", "chat_id", chat.ID, "message_id", msg.ID, "error", err)
+		logger.LogWarn("Failed to fetch presence information for chat members", "chat_id", chat.ID, "message_id", msg.ID, "error", err)
 	}
 
 	botUserID := ah.plugin.GetBotUserID()
 
-	chatLink := fmt.Sprintf("https://teams.microsoft.com/l/message/%s/%s?tenantId=%s&context={\"contextType\":\"chat\"}", chat.ID, msg.ID, ah.plugin.GetTenantID())
+	chatLink := teamsMessageLink(chat.ID, msg.ID, ah.plugin.GetTenantID())
 	isGroupChat := len(chat.Members) >= 3
 	hasFilesUnknown := false
+	conversionFailed := false
+	var observedUserIDs []string
 	for _, member := range chat.Members {
 		// Don't notify senders about their own posts.
 		if member.UserID == msg.UserID {
@@ -46,26 +83,26 @@ func (ah *ActivityHandler) handleCreatedActivityNotification(msg *clientmodels.M
 		if err == sql.ErrNoRows {
 			continue
 		} else if err != nil {
-			ah.plugin.GetAPI().LogWarn("Failed to map Teams user to Mattermost user", "teams_user_id", member.UserID, "error", err)
-			ah.plugin.metricsService.ObserveNotification(isGroupChat, hasFilesUnknown, metrics.DiscardedReasonInternalError)
+			logger.LogWarn("Failed to map Teams user to Mattermost user", "teams_user_id", member.UserID, "error", err)
+			ah.plugin.GetMetrics().ObserveNotification(isGroupChat, hasFilesUnknown, metrics.DiscardedReasonInternalError)
 			continue
 		}
 
 		if !ah.plugin.getNotificationPreference(mattermostUserID) {
-			ah.plugin.GetAPI().LogInfo(
+			logger.LogInfo(
 				"Skipping notification for chat member who disabled notifications",
 				"user_id", mattermostUserID,
 				"teams_user_id", member.UserID,
 				"chat_id", chat.ID,
 				"message_id", msg.ID,
 			)
-			ah.plugin.metricsService.ObserveNotification(isGroupChat, hasFilesUnknown, metrics.DiscardedReasonUserDisabledNotifications)
+			ah.plugin.GetMetrics().ObserveNotification(isGroupChat, hasFilesUnknown, metrics.DiscardedReasonUserDisabledNotifications)
 			continue
 		}
 
 		// Don't notify users active in Teams.
 		if userPresenceIsActive(presences[member.UserID]) {
-			ah.plugin.GetAPI().LogInfo(
+			logger.LogInfo(
 				"Skipping notification for chat member present in Teams",
 				"user_id", mattermostUserID,
 				"teams_user_id", member.UserID,
@@ -74,41 +111,101 @@ func (ah *ActivityHandler) handleCreatedActivityNotification(msg *clientmodels.M
 				"activity", presences[member.UserID].Activity,
 				"availability", presences[member.UserID].Availability,
 			)
-			ah.plugin.metricsService.ObserveNotification(isGroupChat, hasFilesUnknown, metrics.DiscardedReasonUserActiveInTeams)
+			ah.plugin.GetMetrics().ObserveNotification(isGroupChat, hasFilesUnknown, metrics.DiscardedReasonUserActiveInTeams)
+			continue
+		}
+
+		// When only observing, record the outcome instead of creating the bot DM channel or
+		// posting to it.
+		if ah.plugin.getConfiguration().ObserveOnly {
+			ah.plugin.GetMetrics().ObserveNotification(isGroupChat, len(msg.Attachments) > 0, metrics.DiscardedReasonObserveOnly)
+			observedUserIDs = append(observedUserIDs, mattermostUserID)
 			continue
 		}
 
 		channel, err := ah.plugin.apiClient.Channel.GetDirect(mattermostUserID, ah.plugin.botUserID)
 		if err != nil {
-			ah.plugin.GetAPI().LogWarn("Failed to get bot DM channel with user", "user_id", mattermostUserID, "teams_user_id", member.UserID, "error", err)
-			ah.plugin.metricsService.ObserveNotification(isGroupChat, hasFilesUnknown, metrics.DiscardedReasonInternalError)
+			logger.LogWarn("Failed to get bot DM channel with user", "user_id", mattermostUserID, "teams_user_id", member.UserID, "error", err)
+			ah.plugin.GetMetrics().ObserveNotification(isGroupChat, hasFilesUnknown, metrics.DiscardedReasonInternalError)
 			continue
 		}
 
-		post, skippedFileAttachments, _ := ah.msgToPost(channel.Id, botUserID, msg, chat, []string{})
+		post, skippedFileAttachments, errorFound := ah.msgToPost(logger, channel.Id, botUserID, mattermostUserID, msg, chat, []string{})
+		if errorFound {
+			// Notify the user regardless: the files that failed are reported as skipped in the
+			// notification, and the activity isn't quarantined, since replaying it would notify
+			// the user twice.
+			logger.LogWarn("Failed to convert chat message completely", "user_id", mattermostUserID, "chat_id", chat.ID, "message_id", msg.ID, "skipped_file_count", skippedFileAttachments)
+			conversionFailed = true
+		}
+		logger.LogDebug("Converted chat message", "user_id", mattermostUserID, "chat_id", chat.ID, "message_id", msg.ID, "file_count", len(post.FileIds), "skipped_file_count", skippedFileAttachments)
+		ah.syncDebugLog(logger, chat.ID, "Converted message", append([]any{"user_id", mattermostUserID, "message_id", msg.ID}, syncDebugPostOutput(post, skippedFileAttachments, ah.plugin.getConfiguration().SyncDebugLoggingContent)...)...)
 
 		hasFiles := len(post.FileIds) > 0
-		ah.plugin.metricsService.ObserveNotification(isGroupChat, hasFiles, metrics.DiscardedReasonNone)
-		ah.plugin.notifyChat(
+		notificationDiscardedReason := metrics.DiscardedReasonNone
+		if errorFound {
+			notificationDiscardedReason = metrics.DiscardedReasonConversionFailed
+		}
+		ah.plugin.GetMetrics().ObserveNotification(isGroupChat, hasFiles, notificationDiscardedReason)
+		err = ah.plugin.notifyChat(
 			mattermostUserID,
-			msg.UserDisplayName,
+			messageSenderDisplayName(msg),
 			chat.Topic,
 			len(chat.Members),
 			chatLink,
 			post.Message,
 			post.FileIds,
+			post.Attachments(),
 			skippedFileAttachments,
 		)
+		if err != nil {
+			logger.LogWarn("Failed to send notification message", "user_id", mattermostUserID, "chat_id", chat.ID, "message_id", msg.ID, "error", err)
+		} else {
+			logger.LogDebug("Sent notification message", "user_id", mattermostUserID, "chat_id", chat.ID, "message_id", msg.ID)
+			ah.observeNotificationLatency(logger, msg.CreateAt)
+
+			if err = ah.plugin.GetStore().IncrementMessageCount(mattermostUserID, messageStatsMonth(time.Now()), storemodels.MessageDirectionMSTeamsToMattermost); err != nil {
+				logger.LogWarn("Unable to count the notification message", "user_id", mattermostUserID, "error", err)
+			}
+		}
 
 		err = ah.plugin.GetStore().SetUserLastChatReceivedAt(mattermostUserID, storemodels.MilliToMicroSeconds(post.CreateAt))
 		if err != nil {
-			ah.plugin.GetAPI().LogWarn("Unable to set the last chat received at", "error", err)
+			logger.LogWarn("Unable to set the last chat received at", "error", err)
 		}
 	}
 
+	if len(observedUserIDs) > 0 {
+		ah.auditObservedNotification(logger, msg, chat, observedUserIDs)
+	}
+
+	if conversionFailed {
+		return metrics.DiscardedReasonConversionFailed
+	}
+
 	return metrics.DiscardedReasonNone
 }
 
+// auditObservedNotification converts the message as if notifying the first of the given users, and
+// records the outcome for all of them in a single audit entry. Files are neither downloaded nor
+// scanned when only observing.
+func (ah *ActivityHandler) auditObservedNotification(logger *activityLogger, msg *clientmodels.Message, chat *clientmodels.Chat, userIDs []string) {
+	post, skippedFileAttachments, _ := ah.msgToPost(logger, "", ah.plugin.GetBotUserID(), userIDs[0], msg, chat, []string{})
+	ah.plugin.audit(
+		auditEventNotificationObserved,
+		auditActorSystem,
+		auditStatusSuccess,
+		"user_ids", strings.Join(userIDs, ","),
+		"chat_id", chat.ID,
+		"message_id", msg.ID,
+		"correlation_id", logger.correlationID,
+		"message_length", len(post.Message),
+		"attachment_count", len(msg.Attachments),
+		"skipped_file_count", skippedFileAttachments,
+		"link_preview_count", len(post.Attachments()),
+	)
+}
+
 // Intentionally keep this block of code around as illustrative of what might be necessary to
 // process channel notifications.
 // // TODO: permissions
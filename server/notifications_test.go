@@ -1,8 +1,11 @@
 package main
 
 import (
+	"strings"
 	"testing"
+	"unicode/utf8"
 
+	"github.com/mattermost/mattermost-plugin-msteams/server/msteams/clientmodels"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -233,4 +236,45 @@ SELECT * FROM Users
 			assert.Equal(t, tc.ExpectedMessage, actualMessage)
 		})
 	}
+
+	t.Run("message too long to post", func(t *testing.T) {
+		message := strings.Repeat("Hello!\n", maxNotificationMessageRunes/5)
+		actualMessage := formatNotificationMessage("Sender", "", 2, "http://teams.microsoft.com/chat/1", message, 0, 1)
+
+		assert.LessOrEqual(t, utf8.RuneCountInString(actualMessage), maxNotificationMessageRunes)
+		assert.True(t, strings.HasPrefix(actualMessage, "**Sender** messaged you in an [MS Teams chat](http://teams.microsoft.com/chat/1):\n> Hello!\n> Hello!"))
+		assert.True(t, strings.HasSuffix(actualMessage, `…
+
+*This message was too long to display in full. [View the full message in MS Teams](http://teams.microsoft.com/chat/1).*
+
+*Some file attachments from this message could not be delivered.*`))
+	})
+}
+
+func TestMessageSenderDisplayName(t *testing.T) {
+	for _, testCase := range []struct {
+		Name     string
+		Message  *clientmodels.Message
+		Expected string
+	}{
+		{
+			Name:     "user",
+			Message:  &clientmodels.Message{UserID: "user_id", UserDisplayName: "Sender"},
+			Expected: "Sender",
+		},
+		{
+			Name:     "application",
+			Message:  &clientmodels.Message{ApplicationID: "app_id", ApplicationDisplayName: "Azure DevOps"},
+			Expected: "Azure DevOps (app)",
+		},
+		{
+			Name:     "application without a name",
+			Message:  &clientmodels.Message{ApplicationID: "app_id"},
+			Expected: "An app",
+		},
+	} {
+		t.Run(testCase.Name, func(t *testing.T) {
+			assert.Equal(t, testCase.Expected, messageSenderDisplayName(testCase.Message))
+		})
+	}
 }
@@ -21,6 +21,7 @@ import (
 	"github.com/mattermost/mattermost-plugin-msteams/server/metrics"
 	"github.com/mattermost/mattermost-plugin-msteams/server/msteams"
 	"github.com/mattermost/mattermost-plugin-msteams/server/msteams/client_disconnectionlayer"
+	"github.com/mattermost/mattermost-plugin-msteams/server/msteams/client_ratelimitlayer"
 	client_timerlayer "github.com/mattermost/mattermost-plugin-msteams/server/msteams/client_timerlayer"
 	"github.com/mattermost/mattermost-plugin-msteams/server/store"
 	sqlstore "github.com/mattermost/mattermost-plugin-msteams/server/store/sqlstore"
@@ -60,6 +61,9 @@ type Plugin struct {
 	msteamsAppClientMutex sync.RWMutex
 	msteamsAppClient      msteams.Client
 
+	// graphRateLimiter is shared by all MS Graph clients to limit requests made to the tenant.
+	graphRateLimiter *msteams.RateLimiter
+
 	stopSubscriptions func()
 	stopContext       context.Context
 
@@ -71,18 +75,22 @@ type Plugin struct {
 	subscriptionsClusterMutex *cluster.Mutex
 	connectClusterMutex       *cluster.Mutex
 	monitor                   *Monitor
-	checkCredentialsJob       *cluster.Job
+	jobs                      *JobScheduler
 	apiHandler                *API
 
 	activityHandler *ActivityHandler
 
 	clientBuilderWithToken func(string, string, string, string, *oauth2.Token, *pluginapi.LogService) msteams.Client
+	checkAppCredentials    func(context.Context, string, string, string) error
+	webhookIPAllowlist     webhookIPAllowlist
+	featureFlagsCache      featureFlagsCache
+	graphUsage             *graphUsage
 	metricsService         metrics.Metrics
 	metricsHandler         http.Handler
-	metricsJob             *cluster.Job
 
-	subCommands      []string
-	subCommandsMutex sync.RWMutex
+	subCommands              []string
+	subCommandsMutex         sync.RWMutex
+	registeredCommandTrigger string
 }
 
 func (p *Plugin) ServeHTTP(_ *plugin.Context, w http.ResponseWriter, r *http.Request) {
@@ -159,8 +167,25 @@ func (p *Plugin) GetRelativeURL() string {
 	return getRelativeURL(p.API.GetConfig())
 }
 
-func (p *Plugin) OnDisconnectedTokenHandler(userID string) {
-	p.API.LogInfo("Token for user disconnected", "user_id", userID)
+// describeDisconnectCause explains to the user why their MS Teams connection was lost.
+func describeDisconnectCause(cause error) string {
+	var graphErr *msteams.GraphAPIError
+	switch {
+	case cause == nil:
+		return "your Microsoft sign-in is no longer valid"
+	case msteams.IsOAuthError(cause) && strings.Contains(cause.Error(), "invalid_grant"):
+		return "your Microsoft sign-in has expired or your consent for this app was revoked"
+	case msteams.IsOAuthError(cause):
+		return "Microsoft refused to renew your sign-in"
+	case errors.As(cause, &graphErr) && graphErr.StatusCode == http.StatusUnauthorized:
+		return "Microsoft Teams no longer accepts your sign-in"
+	default:
+		return "your Microsoft sign-in is no longer valid"
+	}
+}
+
+func (p *Plugin) OnDisconnectedTokenHandler(userID string, cause error) {
+	p.API.LogInfo("Token for user disconnected", "user_id", userID, "error", cause)
 	p.metricsService.ObserveOAuthTokenInvalidated()
 
 	teamsUserID, err := p.store.MattermostToTeamsUserID(userID)
@@ -170,15 +195,17 @@ func (p *Plugin) OnDisconnectedTokenHandler(userID string) {
 	}
 	if err2 := p.store.SetUserInfo(userID, teamsUserID, nil); err2 != nil {
 		p.API.LogWarn("Unable clean invalid token for the user", "user_id", userID, "error", err2.Error())
+		p.audit(auditEventUserDisconnected, auditActorSystem, auditStatusFail, "user_id", userID, "teams_user_id", teamsUserID, "reason", describeDisconnectCause(cause))
 		return
 	}
+	p.audit(auditEventUserDisconnected, auditActorSystem, auditStatusSuccess, "user_id", userID, "teams_user_id", teamsUserID, "reason", describeDisconnectCause(cause))
 	channel, appErr := p.API.GetDirectChannel(userID, p.GetBotUserID())
 	if appErr != nil {
 		p.API.LogWarn("Unable to get direct channel for send message to user", "user_id", userID, "error", appErr.Error())
 		return
 	}
 
-	message := "Your connection to Microsoft Teams has been lost. "
+	message := fmt.Sprintf("Your connection to Microsoft Teams has been lost because %s, so you will no longer receive notifications of your Teams chats. Reconnect your account to resume them.", describeDisconnectCause(cause))
 	p.SendConnectMessage(channel.Id, userID, message)
 }
 
@@ -190,6 +217,7 @@ func (p *Plugin) GetClientForUser(userID string) (msteams.Client, error) {
 
 	client := p.clientBuilderWithToken(p.GetURL()+"/oauth-redirect", p.getConfiguration().TenantID, p.getConfiguration().ClientID, p.getConfiguration().ClientSecret, token, &p.apiClient.Log)
 	client = client_timerlayer.New(client, p.GetMetrics())
+	client = client_ratelimitlayer.New(client, p.graphRateLimiter)
 	client = client_disconnectionlayer.New(client, userID, p.OnDisconnectedTokenHandler)
 
 	if token.Expiry.Before(time.Now()) {
@@ -234,7 +262,7 @@ func (p *Plugin) connectTeamsAppClient() error {
 		&p.apiClient.Log,
 	)
 
-	p.msteamsAppClient = client_timerlayer.New(msteamsAppClient, p.GetMetrics())
+	p.msteamsAppClient = client_ratelimitlayer.New(client_timerlayer.New(msteamsAppClient, p.GetMetrics()), p.graphRateLimiter)
 	err := p.msteamsAppClient.Connect()
 	if err != nil {
 		p.API.LogError("Unable to connect to the app client", "error", err)
@@ -247,22 +275,13 @@ func (p *Plugin) start(isRestart bool) {
 	var err error
 
 	if !isRestart {
-		p.metricsJob, err = cluster.Schedule(
-			p.API,
-			metricsJobName,
-			cluster.MakeWaitForRoundedInterval(updateMetricsTaskFrequency),
-			p.updateMetrics,
-		)
-		if err != nil {
+		// Run the job right away so we immediately populate metrics.
+		if err = p.jobs.Register(metricsJobName, updateMetricsTaskFrequency, true, p.updateMetrics); err != nil {
 			p.API.LogError("failed to start metrics job", "error", err)
 		}
-
-		// Run the job above right away so we immediately populate metrics.
-		go p.updateMetrics()
 	}
 
-	p.metricsService.ObserveConnectedUsersLimit(int64(p.configuration.ConnectedUsersAllowed))
-	p.metricsService.ObservePendingInvitesLimit(int64(p.configuration.ConnectedUsersMaxPendingInvites))
+	p.applyLiveSettings()
 
 	// We don't restart the activity handler since it's stateless.
 	if !isRestart {
@@ -274,7 +293,7 @@ func (p *Plugin) start(isRestart bool) {
 		return
 	}
 
-	p.monitor = NewMonitor(p.GetClientForApp(), p.store, p.API, p.GetMetrics(), p.GetURL()+"/", p.getConfiguration().WebhookSecret, p.getConfiguration().EvaluationAPI)
+	p.monitor = NewMonitor(p.GetClientForApp(), p.store, p.API, p.GetMetrics(), p.jobs, p.GetURL()+"/", p.getConfiguration().WebhookSecret, p.getConfiguration().EvaluationAPI)
 	if err = p.monitor.Start(); err != nil {
 		p.API.LogError("Unable to start the monitoring system", "error", err.Error())
 	}
@@ -283,30 +302,33 @@ func (p *Plugin) start(isRestart bool) {
 	p.stopSubscriptions = stop
 	p.stopContext = ctx
 
+	if p.getConfiguration().WebhookIPAllowlist {
+		go p.refreshWebhookIPRangesPeriodically(ctx)
+	}
+
 	if !p.getConfiguration().DisableCheckCredentials {
-		checkCredentialsJob, jobErr := cluster.Schedule(
-			p.API,
-			checkCredentialsJobName,
-			cluster.MakeWaitForRoundedInterval(24*time.Hour),
-			p.checkCredentials,
-		)
-		if jobErr != nil {
+		// Run the job right away so we immediately populate metrics.
+		if jobErr := p.jobs.Register(checkCredentialsJobName, 24*time.Hour, true, p.checkCredentials); jobErr != nil {
 			p.API.LogError("error in scheduling the check credentials job", "error", jobErr)
 			return
 		}
-		p.checkCredentialsJob = checkCredentialsJob
-
-		// Run the job above right away so we immediately populate metrics.
-		go p.checkCredentials()
 	}
 
-	// Unregister and re-register slash command to reflect any configuration changes.
-	if err = p.API.UnregisterCommand("", "msteams"); err != nil {
-		p.API.LogWarn("Failed to unregister command", "error", err)
+	// Unregister and re-register slash command to reflect any configuration changes, including
+	// a change of the trigger itself.
+	trigger := p.getCommandTrigger()
+	if p.registeredCommandTrigger != "" && p.registeredCommandTrigger != trigger {
+		if err = p.API.UnregisterCommand("", p.registeredCommandTrigger); err != nil {
+			p.API.LogWarn("Failed to unregister previous command", "trigger", p.registeredCommandTrigger, "error", err)
+		}
+	}
+	if err = p.API.UnregisterCommand("", trigger); err != nil {
+		p.API.LogWarn("Failed to unregister command", "trigger", trigger, "error", err)
 	}
-	if err = p.API.RegisterCommand(p.createCommand()); err != nil {
-		p.API.LogError("Failed to register command", "error", err)
+	if err = p.API.RegisterCommand(p.createCommand(trigger)); err != nil {
+		p.API.LogError("Failed to register command", "trigger", trigger, "error", err)
 	}
+	p.registeredCommandTrigger = trigger
 	p.API.LogDebug("plugin started")
 }
 
@@ -324,16 +346,11 @@ func (p *Plugin) stop(isRestart bool) {
 		p.activityHandler.Stop()
 	}
 
-	if p.checkCredentialsJob != nil {
-		if err := p.checkCredentialsJob.Close(); err != nil {
-			p.API.LogError("Failed to close background check credentials job", "error", err)
-		}
-		p.checkCredentialsJob = nil
-	}
+	if p.jobs != nil {
+		p.jobs.Unregister(checkCredentialsJobName)
 
-	if !isRestart && p.metricsJob != nil {
-		if err := p.metricsJob.Close(); err != nil {
-			p.API.LogError("failed to close metrics job", "error", err)
+		if !isRestart {
+			p.jobs.Close()
 		}
 	}
 
@@ -387,15 +404,22 @@ func (p *Plugin) onActivate() error {
 	if p.clientBuilderWithToken == nil {
 		p.clientBuilderWithToken = msteams.NewTokenClient
 	}
+	if p.checkAppCredentials == nil {
+		p.checkAppCredentials = msteams.CheckAppCredentials
+	}
 	err := p.generatePluginSecrets()
 	if err != nil {
 		return err
 	}
 
-	p.metricsService = metrics.NewMetrics(metrics.InstanceInfo{
-		InstallationID: os.Getenv("MM_CLOUD_INSTALLATION_ID"),
-		PluginVersion:  manifest.Version,
-	})
+	p.graphUsage = newGraphUsage()
+	p.metricsService = &graphUsageMetrics{
+		Metrics: metrics.NewMetrics(metrics.InstanceInfo{
+			InstallationID: os.Getenv("MM_CLOUD_INSTALLATION_ID"),
+			PluginVersion:  manifest.Version,
+		}),
+		usage: p.graphUsage,
+	}
 	p.metricsHandler = metrics.NewMetricsHandler(p.GetMetrics())
 
 	p.apiClient = pluginapi.NewClient(p.API, p.Driver)
@@ -406,7 +430,12 @@ func (p *Plugin) onActivate() error {
 		return errors.New("this plugin requires an enterprise license")
 	}
 
+	p.graphRateLimiter = msteams.NewRateLimiter(0, 1)
 	p.activityHandler = NewActivityHandler(p)
+	// Redact last, so that any text added by the other stages, such as the subject, is covered.
+	p.activityHandler.registerMessageTransformer(messageTransformerRedaction, redactMessage, "")
+	p.activityHandler.registerMessageTransformer(messageTransformerFileNameRedaction, redactFileNames, messageTransformerAttachments)
+	p.jobs = NewJobScheduler(p.API, p.GetMetrics())
 
 	p.subscriptionsClusterMutex, err = cluster.NewMutex(p.API, subscriptionsClusterMutexKey)
 	if err != nil {
@@ -1,11 +1,15 @@
 package main
 
 import (
+	"errors"
+	"fmt"
+	"net/http"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"github.com/mattermost/mattermost-plugin-msteams/server/msteams"
 	"github.com/mattermost/mattermost/server/public/model"
 )
 
@@ -94,6 +98,41 @@ func TestGetRelativeURL(t *testing.T) {
 	}
 }
 
+func TestDescribeDisconnectCause(t *testing.T) {
+	testCases := []struct {
+		Name     string
+		Cause    error
+		Expected string
+	}{
+		{
+			Name:     "no cause",
+			Cause:    nil,
+			Expected: "your Microsoft sign-in is no longer valid",
+		},
+		{
+			Name:     "expired or revoked refresh token",
+			Cause:    errors.New(`oauth2: "invalid_grant" "AADSTS700082: The refresh token has expired"`),
+			Expected: "your Microsoft sign-in has expired or your consent for this app was revoked",
+		},
+		{
+			Name:     "other oauth error",
+			Cause:    errors.New(`oauth2: "invalid_client"`),
+			Expected: "Microsoft refused to renew your sign-in",
+		},
+		{
+			Name:     "unauthorized graph error",
+			Cause:    fmt.Errorf("wrapped: %w", &msteams.GraphAPIError{StatusCode: http.StatusUnauthorized}),
+			Expected: "Microsoft Teams no longer accepts your sign-in",
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.Name, func(t *testing.T) {
+			assert.Equal(t, testCase.Expected, describeDisconnectCause(testCase.Cause))
+		})
+	}
+}
+
 func TestGetClientForUser(t *testing.T) {
 	th := setupTestHelper(t)
 	team := th.SetupTeam(t)
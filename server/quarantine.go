@@ -0,0 +1,392 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/mattermost/mattermost-plugin-msteams/server/metrics"
+	"github.com/mattermost/mattermost-plugin-msteams/server/msteams"
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/pkg/errors"
+)
+
+const (
+	// quarantineKeyPrefix prefixes the keys of the quarantined activities, each stored under its
+	// own key so that workers quarantining concurrently don't contend on a single one.
+	quarantineKeyPrefix = "activity_quarantine_"
+
+	// quarantineIndexKey holds the ids of the quarantined activities in the order they were
+	// quarantined, so the quarantine can be trimmed and listed without scanning every key.
+	quarantineIndexKey = "quarantined_activity_ids"
+
+	// maxQuarantineIndexAttempts bounds the attempts at updating the quarantine index when other
+	// workers update it concurrently.
+	maxQuarantineIndexAttempts = 10
+
+	// maxQuarantinedActivities caps the quarantine, the oldest activities making room for new ones.
+	maxQuarantinedActivities = 100
+
+	// quarantineAfterAttempts is the number of times an activity failing for a transient reason is
+	// handled before being quarantined.
+	quarantineAfterAttempts = 3
+
+	// quarantineRetryBackoff is how long a failed activity waits before being handled again,
+	// multiplied by the number of attempts so far.
+	quarantineRetryBackoff = 1 * time.Minute
+)
+
+// quarantinedReasons are the discarded reasons for which an activity is quarantined rather than
+// dropped, since it may succeed when replayed once the cause is fixed. Transient reasons are
+// retried before the activity is quarantined.
+var quarantinedReasons = map[string]bool{
+	metrics.DiscardedReasonUnableToGetTeamsData: true,
+	metrics.DiscardedReasonInvalidChangeType:    true,
+}
+
+// retriedReasons are the discarded reasons for which an activity is handled again before being
+// quarantined.
+var retriedReasons = map[string]bool{
+	metrics.DiscardedReasonUnableToGetTeamsData: true,
+}
+
+// QuarantinedActivity is an activity that could not be handled, kept for inspection and replay.
+type QuarantinedActivity struct {
+	ID              string           `json:"id"`
+	QuarantinedAt   int64            `json:"quarantined_at"`
+	DiscardedReason string           `json:"discarded_reason"`
+	CorrelationID   string           `json:"correlation_id"`
+	Attempts        int              `json:"attempts"`
+	Activity        msteams.Activity `json:"activity"`
+}
+
+// retryOrQuarantineActivity handles a failed activity again later if it failed for a transient
+// reason and has attempts left, or quarantines it otherwise.
+func (ah *ActivityHandler) retryOrQuarantineActivity(logger *activityLogger, activity msteams.Activity, discardedReason string) {
+	activity.Attempts++
+
+	if retriedReasons[discardedReason] && activity.Attempts < quarantineAfterAttempts {
+		delay := time.Duration(activity.Attempts) * quarantineRetryBackoff
+		if ah.deferActivity(activity, delay) {
+			logger.LogInfo("Retrying failed activity", "discarded_reason", discardedReason, "attempts", activity.Attempts, "delay", delay.String())
+			return
+		}
+		logger.LogWarn("Unable to retry failed activity, too many activities deferred", "discarded_reason", discardedReason, "attempts", activity.Attempts)
+	}
+
+	if err := ah.plugin.quarantineActivity(activity, discardedReason); err != nil {
+		logger.LogWarn("Unable to quarantine activity", "discarded_reason", discardedReason, "error", err.Error())
+	}
+}
+
+// withoutActivitySecrets returns the activity without the webhook secret and any encrypted content
+// sent along with it by MS Graph, neither being needed once the activity was received.
+func withoutActivitySecrets(activity msteams.Activity) msteams.Activity {
+	activity.ClientState = ""
+	activity.EncryptedContent = nil
+	return activity
+}
+
+// quarantineActivity keeps an activity that could not be handled. The webhook secret and any
+// encrypted content sent along with it by MS Graph are not kept.
+func (p *Plugin) quarantineActivity(activity msteams.Activity, discardedReason string) error {
+	quarantined := QuarantinedActivity{
+		ID:              model.NewId(),
+		QuarantinedAt:   model.GetMillis(),
+		DiscardedReason: discardedReason,
+		CorrelationID:   activity.CorrelationID,
+		Attempts:        activity.Attempts,
+		Activity:        withoutActivitySecrets(activity),
+	}
+
+	return p.storeQuarantinedActivity(&quarantined)
+}
+
+// storeQuarantinedActivity stores a quarantined activity under its own key and adds it to the
+// quarantine index, removing the oldest activities beyond maxQuarantinedActivities.
+func (p *Plugin) storeQuarantinedActivity(quarantined *QuarantinedActivity) error {
+	data, err := json.Marshal(quarantined)
+	if err != nil {
+		return errors.Wrap(err, "failed to encode the quarantined activity")
+	}
+	if appErr := p.GetAPI().KVSet(quarantineKeyPrefix+quarantined.ID, data); appErr != nil {
+		return errors.Wrap(appErr, "failed to store the quarantined activity")
+	}
+
+	var trimmedIDs []string
+	err = p.updateQuarantineIndex(func(ids []string) []string {
+		ids = append(ids, quarantined.ID)
+		trimmedIDs = nil
+		if len(ids) > maxQuarantinedActivities {
+			trimmedIDs = ids[:len(ids)-maxQuarantinedActivities]
+			ids = ids[len(ids)-maxQuarantinedActivities:]
+		}
+		return ids
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, id := range trimmedIDs {
+		if appErr := p.GetAPI().KVDelete(quarantineKeyPrefix + id); appErr != nil {
+			return errors.Wrap(appErr, "failed to remove the oldest quarantined activity")
+		}
+	}
+
+	return nil
+}
+
+// getQuarantineIndex returns the ids of the quarantined activities, in the order they were
+// quarantined, along with the stored index for updating it.
+func (p *Plugin) getQuarantineIndex() ([]string, []byte, error) {
+	data, appErr := p.GetAPI().KVGet(quarantineIndexKey)
+	if appErr != nil {
+		return nil, nil, errors.Wrap(appErr, "failed to get the quarantine index")
+	}
+
+	var ids []string
+	if data != nil {
+		if err := json.Unmarshal(data, &ids); err != nil {
+			return nil, nil, errors.Wrap(err, "failed to decode the quarantine index")
+		}
+	}
+
+	return ids, data, nil
+}
+
+// updateQuarantineIndex replaces the ids of the quarantined activities with those returned by
+// update, retrying if the index changed concurrently.
+func (p *Plugin) updateQuarantineIndex(update func(ids []string) []string) error {
+	for attempt := 0; attempt < maxQuarantineIndexAttempts; attempt++ {
+		ids, oldData, err := p.getQuarantineIndex()
+		if err != nil {
+			return err
+		}
+
+		newData, err := json.Marshal(update(ids))
+		if err != nil {
+			return errors.Wrap(err, "failed to encode the quarantine index")
+		}
+
+		updated, appErr := p.GetAPI().KVCompareAndSet(quarantineIndexKey, oldData, newData)
+		if appErr != nil {
+			return errors.Wrap(appErr, "failed to store the quarantine index")
+		}
+		if updated {
+			return nil
+		}
+	}
+
+	return errors.New("failed to update the quarantine index: too many concurrent updates")
+}
+
+// getQuarantinedActivities returns the quarantined activities, oldest first.
+func (p *Plugin) getQuarantinedActivities() ([]QuarantinedActivity, error) {
+	ids, _, err := p.getQuarantineIndex()
+	if err != nil {
+		return nil, err
+	}
+
+	quarantined := []QuarantinedActivity{}
+	for _, id := range ids {
+		data, appErr := p.GetAPI().KVGet(quarantineKeyPrefix + id)
+		if appErr != nil {
+			return nil, errors.Wrap(appErr, "failed to get the quarantined activity")
+		}
+		if data == nil {
+			// Removed since listed.
+			continue
+		}
+
+		var activity QuarantinedActivity
+		if err := json.Unmarshal(data, &activity); err != nil {
+			return nil, errors.Wrap(err, "failed to decode the quarantined activity")
+		}
+		quarantined = append(quarantined, activity)
+	}
+
+	sort.SliceStable(quarantined, func(i, j int) bool {
+		return quarantined[i].QuarantinedAt < quarantined[j].QuarantinedAt
+	})
+
+	return quarantined, nil
+}
+
+// removeQuarantinedActivity removes the quarantined activity with the given id, returning it, or
+// nil if not found. The activity is claimed by deleting it, so only one caller gets it.
+func (p *Plugin) removeQuarantinedActivity(id string) (*QuarantinedActivity, error) {
+	key := quarantineKeyPrefix + id
+	data, appErr := p.GetAPI().KVGet(key)
+	if appErr != nil {
+		return nil, errors.Wrap(appErr, "failed to get the quarantined activity")
+	}
+	if data == nil {
+		return nil, nil
+	}
+
+	deleted, appErr := p.GetAPI().KVCompareAndDelete(key, data)
+	if appErr != nil {
+		return nil, errors.Wrap(appErr, "failed to remove the quarantined activity")
+	}
+	if !deleted {
+		return nil, nil
+	}
+
+	var quarantined QuarantinedActivity
+	if err := json.Unmarshal(data, &quarantined); err != nil {
+		return nil, errors.Wrap(err, "failed to decode the quarantined activity")
+	}
+
+	err := p.updateQuarantineIndex(func(ids []string) []string {
+		return slices.DeleteFunc(ids, func(indexedID string) bool { return indexedID == id })
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &quarantined, nil
+}
+
+// purgeQuarantine removes every quarantined activity, including any missing from the index, and
+// the index itself.
+func (p *Plugin) purgeQuarantine() error {
+	keys, appErr := p.listKVKeys(quarantineKeyPrefix)
+	if appErr != nil {
+		return errors.Wrap(appErr, "failed to list the quarantined activities")
+	}
+
+	for _, key := range keys {
+		if appErr := p.GetAPI().KVDelete(key); appErr != nil {
+			return errors.Wrap(appErr, "failed to remove the quarantined activity")
+		}
+	}
+
+	if appErr := p.GetAPI().KVDelete(quarantineIndexKey); appErr != nil {
+		return errors.Wrap(appErr, "failed to remove the quarantine index")
+	}
+
+	return nil
+}
+
+// validateQuarantinedActivity checks a quarantined activity before replaying it, since its webhook
+// secret isn't kept: it must be about a resource of a subscription still in use.
+func (p *Plugin) validateQuarantinedActivity(quarantined *QuarantinedActivity) error {
+	if quarantined.Activity.Resource == "" {
+		return errors.New("missing resource")
+	}
+
+	if _, err := p.GetStore().GetGlobalSubscription(quarantined.Activity.SubscriptionID); err != nil {
+		return errors.Wrap(err, "unknown subscription")
+	}
+
+	return nil
+}
+
+func (a *API) getQuarantine(w http.ResponseWriter, r *http.Request) {
+	userID := r.Header.Get("Mattermost-User-ID")
+	if !a.p.API.HasPermissionTo(userID, model.PermissionManageSystem) {
+		a.p.API.LogWarn("Insufficient permissions", "user_id", userID)
+		http.Error(w, "not able to authorize the user", http.StatusForbidden)
+		return
+	}
+
+	quarantined, err := a.p.getQuarantinedActivities()
+	if err != nil {
+		a.p.API.LogWarn("Unable to get the quarantined activities", "error", err.Error())
+		http.Error(w, "unable to get the quarantined activities", http.StatusInternalServerError)
+		return
+	}
+
+	a.returnJSON(w, quarantined)
+}
+
+// replayQuarantine queues a quarantined activity to be handled again, removing it from the
+// quarantine. It is quarantined anew if it fails again.
+func (a *API) replayQuarantine(w http.ResponseWriter, r *http.Request) {
+	userID := r.Header.Get("Mattermost-User-ID")
+	if !a.p.API.HasPermissionTo(userID, model.PermissionManageSystem) {
+		a.p.API.LogWarn("Insufficient permissions", "user_id", userID)
+		http.Error(w, "not able to authorize the user", http.StatusForbidden)
+		return
+	}
+
+	id := r.URL.Query().Get(QueryParamID)
+	if id == "" {
+		http.Error(w, "missing quarantined activity id", http.StatusBadRequest)
+		return
+	}
+
+	quarantined, err := a.p.removeQuarantinedActivity(id)
+	if err != nil {
+		a.p.API.LogWarn("Unable to remove the quarantined activity", "id", id, "error", err.Error())
+		http.Error(w, "unable to replay the quarantined activity", http.StatusInternalServerError)
+		return
+	}
+	if quarantined == nil {
+		http.Error(w, "quarantined activity not found", http.StatusNotFound)
+		return
+	}
+
+	if err = a.p.validateQuarantinedActivity(quarantined); err != nil {
+		a.p.API.LogWarn("Refusing to replay the quarantined activity", "id", id, "error", err.Error())
+		if err = a.p.storeQuarantinedActivity(quarantined); err != nil {
+			a.p.API.LogWarn("Unable to quarantine the activity again", "id", id, "error", err.Error())
+		}
+		http.Error(w, "invalid quarantined activity", http.StatusConflict)
+		return
+	}
+
+	// Keep the original correlation ID, so the replay can be traced alongside the failure.
+	quarantined.Activity.CorrelationID = quarantined.CorrelationID
+	if err = a.p.activityHandler.Handle(quarantined.Activity); err != nil {
+		a.p.API.LogWarn("Unable to replay the quarantined activity", "id", id, "error", err.Error())
+		if err = a.p.storeQuarantinedActivity(quarantined); err != nil {
+			a.p.API.LogWarn("Unable to quarantine the activity again", "id", id, "error", err.Error())
+		}
+		http.Error(w, "unable to replay the quarantined activity", http.StatusServiceUnavailable)
+		return
+	}
+
+	a.p.audit(auditEventQuarantineReplayed, userID, auditStatusSuccess, "id", id, "correlation_id", quarantined.CorrelationID)
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// deleteQuarantine purges the quarantined activity with the given id, or all of them if none is
+// given.
+func (a *API) deleteQuarantine(w http.ResponseWriter, r *http.Request) {
+	userID := r.Header.Get("Mattermost-User-ID")
+	if !a.p.API.HasPermissionTo(userID, model.PermissionManageSystem) {
+		a.p.API.LogWarn("Insufficient permissions", "user_id", userID)
+		http.Error(w, "not able to authorize the user", http.StatusForbidden)
+		return
+	}
+
+	id := r.URL.Query().Get(QueryParamID)
+	if id == "" {
+		if err := a.p.purgeQuarantine(); err != nil {
+			a.p.API.LogWarn("Unable to purge the quarantined activities", "error", err.Error())
+			http.Error(w, "unable to purge the quarantined activities", http.StatusInternalServerError)
+			return
+		}
+
+		a.p.audit(auditEventQuarantinePurged, userID, auditStatusSuccess)
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	quarantined, err := a.p.removeQuarantinedActivity(id)
+	if err != nil {
+		a.p.API.LogWarn("Unable to remove the quarantined activity", "id", id, "error", err.Error())
+		http.Error(w, "unable to remove the quarantined activity", http.StatusInternalServerError)
+		return
+	}
+	if quarantined == nil {
+		http.Error(w, "quarantined activity not found", http.StatusNotFound)
+		return
+	}
+
+	a.p.audit(auditEventQuarantinePurged, userID, auditStatusSuccess, "id", id)
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/mattermost/mattermost-plugin-msteams/server/metrics"
+	"github.com/mattermost/mattermost-plugin-msteams/server/msteams"
+	"github.com/mattermost/mattermost-plugin-msteams/server/msteams/clientmodels"
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQuarantine(t *testing.T) {
+	th := setupTestHelper(t)
+
+	t.Cleanup(func() {
+		require.NoError(t, th.p.purgeQuarantine())
+	})
+
+	t.Run("quarantine and remove", func(t *testing.T) {
+		require.NoError(t, th.p.purgeQuarantine())
+
+		activity := msteams.Activity{Resource: "chats('chat-id')/messages('message-id')", ChangeType: "created", CorrelationID: "correlation-id"}
+		require.NoError(t, th.p.quarantineActivity(activity, metrics.DiscardedReasonUnableToGetTeamsData))
+
+		quarantined, err := th.p.getQuarantinedActivities()
+		require.NoError(t, err)
+		require.Len(t, quarantined, 1)
+		assert.Equal(t, metrics.DiscardedReasonUnableToGetTeamsData, quarantined[0].DiscardedReason)
+		assert.Equal(t, "correlation-id", quarantined[0].CorrelationID)
+		assert.Equal(t, activity.Resource, quarantined[0].Activity.Resource)
+
+		removed, err := th.p.removeQuarantinedActivity(quarantined[0].ID)
+		require.NoError(t, err)
+		require.NotNil(t, removed)
+		assert.Equal(t, quarantined[0].ID, removed.ID)
+
+		removed, err = th.p.removeQuarantinedActivity(quarantined[0].ID)
+		require.NoError(t, err)
+		assert.Nil(t, removed)
+
+		quarantined, err = th.p.getQuarantinedActivities()
+		require.NoError(t, err)
+		assert.Empty(t, quarantined)
+	})
+
+	t.Run("oldest activities make room", func(t *testing.T) {
+		require.NoError(t, th.p.purgeQuarantine())
+
+		for i := 0; i < maxQuarantinedActivities+2; i++ {
+			activity := msteams.Activity{Resource: fmt.Sprintf("chats('chat-id')/messages('%d')", i), ChangeType: "created"}
+			require.NoError(t, th.p.quarantineActivity(activity, metrics.DiscardedReasonUnableToGetTeamsData))
+		}
+
+		quarantined, err := th.p.getQuarantinedActivities()
+		require.NoError(t, err)
+		require.Len(t, quarantined, maxQuarantinedActivities)
+		assert.Equal(t, "chats('chat-id')/messages('2')", quarantined[0].Activity.Resource)
+	})
+
+	t.Run("webhook secret not kept", func(t *testing.T) {
+		require.NoError(t, th.p.purgeQuarantine())
+
+		activity := msteams.Activity{
+			Resource:         "chats('chat-id')/messages('message-id')",
+			ChangeType:       "created",
+			ClientState:      "webhook-secret",
+			EncryptedContent: &msteams.EncryptedContent{Data: "data"},
+		}
+		require.NoError(t, th.p.quarantineActivity(activity, metrics.DiscardedReasonUnableToGetTeamsData))
+
+		quarantined, err := th.p.getQuarantinedActivities()
+		require.NoError(t, err)
+		require.Len(t, quarantined, 1)
+		assert.Empty(t, quarantined[0].Activity.ClientState)
+		assert.Nil(t, quarantined[0].Activity.EncryptedContent)
+	})
+
+	t.Run("transient failures retried before quarantine", func(t *testing.T) {
+		require.NoError(t, th.p.purgeQuarantine())
+
+		logger := th.p.activityHandler.newActivityLogger("correlation-id")
+		activity := msteams.Activity{Resource: "chats('chat-id')/messages('message-id')", ChangeType: "created"}
+		th.p.activityHandler.retryOrQuarantineActivity(logger, activity, metrics.DiscardedReasonUnableToGetTeamsData)
+
+		deferred := th.p.activityHandler.takeDeferredActivities()
+		require.Len(t, deferred, 1)
+		assert.Equal(t, 1, deferred[0].Attempts)
+
+		quarantined, err := th.p.getQuarantinedActivities()
+		require.NoError(t, err)
+		assert.Empty(t, quarantined)
+
+		activity.Attempts = quarantineAfterAttempts - 1
+		th.p.activityHandler.retryOrQuarantineActivity(logger, activity, metrics.DiscardedReasonUnableToGetTeamsData)
+
+		quarantined, err = th.p.getQuarantinedActivities()
+		require.NoError(t, err)
+		require.Len(t, quarantined, 1)
+		assert.Equal(t, quarantineAfterAttempts, quarantined[0].Attempts)
+	})
+
+	t.Run("partially converted message notified once, not quarantined", func(t *testing.T) {
+		th.Reset(t)
+		require.NoError(t, th.p.purgeQuarantine())
+		team := th.SetupTeam(t)
+
+		senderUser := th.SetupUser(t, team)
+		th.ConnectUser(t, senderUser.Id)
+
+		user1 := th.SetupUser(t, team)
+		th.ConnectUser(t, user1.Id)
+
+		botUser, err := th.p.apiClient.User.Get(th.p.botUserID)
+		require.NoError(t, err)
+
+		mockTeams := newMockTeamsHelper(th)
+		mockTeams.registerChat("chat_id", []*model.User{user1, senderUser})
+		th.clientMock.On("GetChatMessage", "chat_id", "message_id").Return(&clientmodels.Message{
+			ID:       "message_id",
+			UserID:   "t" + senderUser.Id,
+			ChatID:   "chat_id",
+			Text:     "message",
+			CreateAt: time.Now(),
+			Attachments: []clientmodels.Attachment{
+				{
+					Name:        "file.png",
+					ContentType: "reference",
+					ContentURL:  "https://example.com/path/to/file.png",
+				},
+			},
+		}, nil).Times(1)
+		th.appClientMock.On("GetPresencesForUsers", []string{"t" + user1.Id}).Return(map[string]*clientmodels.Presence{}, nil).Times(1)
+		th.appClientMock.On("GetFileSizeAndDownloadURL", "https://example.com/path/to/file.png").Return(int64(0), "", errors.New("unavailable")).Times(1)
+
+		since := model.GetMillis()
+		th.p.activityHandler.handleActivity(msteams.Activity{
+			Resource:      "chats('chat_id')/messages('message_id')",
+			ChangeType:    "created",
+			CorrelationID: "correlation-id",
+		})
+
+		th.assertDMFromUserRe(t, botUser.Id, user1.Id, "message")
+		channel, appErr := th.p.API.GetDirectChannel(botUser.Id, user1.Id)
+		require.Nil(t, appErr)
+		postList, appErr := th.p.API.GetPostsSince(channel.Id, since)
+		require.Nil(t, appErr)
+		assert.Len(t, postList.Posts, 1)
+
+		// Nothing is left to replay, so the user can't be notified a second time.
+		quarantined, err := th.p.getQuarantinedActivities()
+		require.NoError(t, err)
+		assert.Empty(t, quarantined)
+	})
+}
@@ -375,6 +375,29 @@ func (_m *Store) GetLinkedChannelsCount() (int64, error) {
 	return r0, r1
 }
 
+// GetMessageStats provides a mock function with given fields: month
+func (_m *Store) GetMessageStats(month string) ([]*storemodels.MessageStats, error) {
+	ret := _m.Called(month)
+
+	var r0 []*storemodels.MessageStats
+	if rf, ok := ret.Get(0).(func(string) []*storemodels.MessageStats); ok {
+		r0 = rf(month)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*storemodels.MessageStats)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(month)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // GetPostInfoByMSTeamsID provides a mock function with given fields: chatID, postID
 func (_m *Store) GetPostInfoByMSTeamsID(chatID string, postID string) (*storemodels.PostInfo, error) {
 	ret := _m.Called(chatID, postID)
@@ -578,6 +601,20 @@ func (_m *Store) GetWhitelistEmails(page int, perPage int) ([]string, error) {
 	return r0, r1
 }
 
+// IncrementMessageCount provides a mock function with given fields: mmUserID, month, direction
+func (_m *Store) IncrementMessageCount(mmUserID string, month string, direction string) error {
+	ret := _m.Called(mmUserID, month, direction)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, string, string) error); ok {
+		r0 = rf(mmUserID, month, direction)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // Init provides a mock function with given fields: remoteID
 func (_m *Store) Init(remoteID string) error {
 	ret := _m.Called(remoteID)
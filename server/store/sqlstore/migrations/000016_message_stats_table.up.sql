@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS msteamssync_message_stats (
+    mmUserID VARCHAR(255) NOT NULL,
+    month VARCHAR(7) NOT NULL,
+    direction VARCHAR(64) NOT NULL,
+    count BIGINT NOT NULL DEFAULT 0,
+    PRIMARY KEY (mmUserID, month, direction)
+);
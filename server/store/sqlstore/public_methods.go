@@ -94,6 +94,10 @@ func (s *SQLStore) GetLinkedChannelsCount() (int64, error) {
 	return s.getLinkedChannelsCount(s.replica)
 }
 
+func (s *SQLStore) GetMessageStats(month string) ([]*storemodels.MessageStats, error) {
+	return s.getMessageStats(s.replica, month)
+}
+
 func (s *SQLStore) GetPostInfoByMSTeamsID(chatID string, postID string) (*storemodels.PostInfo, error) {
 	return s.getPostInfoByMSTeamsID(s.replica, chatID, postID)
 }
@@ -130,6 +134,10 @@ func (s *SQLStore) GetWhitelistEmails(page int, perPage int) ([]string, error) {
 	return s.getWhitelistEmails(s.replica, page, perPage)
 }
 
+func (s *SQLStore) IncrementMessageCount(mmUserID string, month string, direction string) error {
+	return s.incrementMessageCount(s.db, mmUserID, month, direction)
+}
+
 func (s *SQLStore) IsUserWhitelisted(userID string) (bool, error) {
 	return s.isUserWhitelisted(s.replica, userID)
 }
@@ -34,6 +34,7 @@ const (
 	whitelistedUsersLegacyTableName = "msteamssync_whitelisted_users" // LEGACY-UNUSED
 	whitelistTableName              = "msteamssync_whitelist"
 	invitedUsersTableName           = "msteamssync_invited_users"
+	messageStatsTableName           = "msteamssync_message_stats"
 	PGUniqueViolationErrorCode      = "23505" // See https://github.com/lib/pq/blob/master/error.go#L178
 )
 
@@ -1164,3 +1165,43 @@ func (s *SQLStore) setUsersLastChatReceivedAt(db sq.BaseRunner, mmUsersID []stri
 
 	return nil
 }
+
+func (s *SQLStore) incrementMessageCount(db sq.BaseRunner, mmUserID, month, direction string) error {
+	query := s.getQueryBuilder(db).
+		Insert(messageStatsTableName).
+		Columns("mmUserID", "month", "direction", "count").
+		Values(mmUserID, month, direction, 1).
+		Suffix("ON CONFLICT (mmUserID, month, direction) DO UPDATE SET count = " + messageStatsTableName + ".count + 1")
+	if _, err := query.Exec(); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+//db:withReplica
+func (s *SQLStore) getMessageStats(db sq.BaseRunner, month string) ([]*storemodels.MessageStats, error) {
+	query := s.getQueryBuilder(db).
+		Select("mmUserID, COALESCE(Users.Email, ''), direction, count").
+		From(messageStatsTableName).
+		LeftJoin("Users ON Users.Id = "+messageStatsTableName+".mmUserID").
+		Where(sq.Eq{"month": month}).
+		OrderBy("mmUserID", "direction")
+	rows, err := query.Query()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var stats []*storemodels.MessageStats
+	for rows.Next() {
+		stat := &storemodels.MessageStats{Month: month}
+		if err := rows.Scan(&stat.MattermostUserID, &stat.Email, &stat.Direction, &stat.Count); err != nil {
+			return nil, err
+		}
+
+		stats = append(stats, stat)
+	}
+
+	return stats, rows.Err()
+}
@@ -1274,3 +1274,41 @@ func TestGetActiveUsersCount(t *testing.T) {
 		assert.EqualValues(4, nb)
 	})
 }
+
+func TestMessageStats(t *testing.T) {
+	store, _ := setupTestStore(t)
+
+	cleanup := func() {
+		t.Helper()
+		_, err := store.getQueryBuilder(store.db).Delete(messageStatsTableName).Where("1=1").Exec()
+		require.Nil(t, err)
+	}
+	cleanup()
+	defer cleanup()
+
+	t.Run("no messages", func(t *testing.T) {
+		assert := require.New(t)
+		stats, err := store.GetMessageStats("2024-01")
+		assert.Nil(err)
+		assert.Empty(stats)
+	})
+
+	t.Run("counts per user and month", func(t *testing.T) {
+		assert := require.New(t)
+		userID1 := model.NewId()
+		userID2 := model.NewId()
+
+		for i := 0; i < 3; i++ {
+			assert.Nil(store.IncrementMessageCount(userID1, "2024-02", storemodels.MessageDirectionMSTeamsToMattermost))
+		}
+		assert.Nil(store.IncrementMessageCount(userID2, "2024-02", storemodels.MessageDirectionMSTeamsToMattermost))
+		assert.Nil(store.IncrementMessageCount(userID2, "2024-03", storemodels.MessageDirectionMSTeamsToMattermost))
+
+		stats, err := store.GetMessageStats("2024-02")
+		assert.Nil(err)
+		assert.ElementsMatch([]*storemodels.MessageStats{
+			{MattermostUserID: userID1, Month: "2024-02", Direction: storemodels.MessageDirectionMSTeamsToMattermost, Count: 3},
+			{MattermostUserID: userID2, Month: "2024-02", Direction: storemodels.MessageDirectionMSTeamsToMattermost, Count: 1},
+		}, stats)
+	})
+}
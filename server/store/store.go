@@ -47,6 +47,8 @@ type Store interface {
 	GetLinkedChannelsCount() (linkedChannels int64, err error)
 	GetConnectedUsersCount() (connectedUsers int64, err error)
 	GetActiveUsersCount(dur time.Duration) (activeUsers int64, err error)
+	IncrementMessageCount(mmUserID, month, direction string) error
+	GetMessageStats(month string) ([]*storemodels.MessageStats, error)
 
 	// links, channels, posts
 	GetLinkByChannelID(channelID string) (*storemodels.ChannelLink, error)
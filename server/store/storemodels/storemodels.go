@@ -74,6 +74,20 @@ type InvitedUser struct {
 	InviteLastSentAt   time.Time
 }
 
+// MessageDirectionMSTeamsToMattermost counts chat messages from MS Teams delivered to a
+// Mattermost user as notifications.
+const MessageDirectionMSTeamsToMattermost = "msteams_to_mattermost"
+
+// MessageStats is the number of messages relayed for a user in one direction in a month,
+// formatted as YYYY-MM.
+type MessageStats struct {
+	MattermostUserID string
+	Email            string
+	Month            string
+	Direction        string
+	Count            int64
+}
+
 func MilliToMicroSeconds(milli int64) int64 {
 	return milli * 1000
 }
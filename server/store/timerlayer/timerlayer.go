@@ -273,6 +273,20 @@ func (s *TimerLayer) GetLinkedChannelsCount() (int64, error) {
 	return result, err
 }
 
+func (s *TimerLayer) GetMessageStats(month string) ([]*storemodels.MessageStats, error) {
+	start := time.Now()
+
+	result, err := s.Store.GetMessageStats(month)
+
+	elapsed := float64(time.Since(start)) / float64(time.Second)
+	success := "false"
+	if err == nil {
+		success = "true"
+	}
+	s.metrics.ObserveStoreMethodDuration("Store.GetMessageStats", success, elapsed)
+	return result, err
+}
+
 func (s *TimerLayer) GetPostInfoByMSTeamsID(chatID string, postID string) (*storemodels.PostInfo, error) {
 	start := time.Now()
 
@@ -399,6 +413,20 @@ func (s *TimerLayer) GetWhitelistEmails(page int, perPage int) ([]string, error)
 	return result, err
 }
 
+func (s *TimerLayer) IncrementMessageCount(mmUserID string, month string, direction string) error {
+	start := time.Now()
+
+	err := s.Store.IncrementMessageCount(mmUserID, month, direction)
+
+	elapsed := float64(time.Since(start)) / float64(time.Second)
+	success := "false"
+	if err == nil {
+		success = "true"
+	}
+	s.metrics.ObserveStoreMethodDuration("Store.IncrementMessageCount", success, elapsed)
+	return err
+}
+
 func (s *TimerLayer) Init(remoteID string) error {
 	start := time.Now()
 
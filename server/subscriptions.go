@@ -113,8 +113,25 @@ func (m *Monitor) checkGlobalChatsSubscription(remoteSubscription *clientmodels.
 	if localSubscription == nil && remoteSubscription == nil {
 		m.api.LogInfo("Creating global chats subscription")
 
+		// MS Graph validates the notification URL when subscribing, so check this server can be
+		// reached first to report the cause rather than an opaque subscription failure.
+		reachWarning, reachErr := m.checkNotificationURL(m.baseURL)
+		if reachWarning != "" {
+			m.api.LogWarn("MS Graph may be unable to reach your server at "+m.baseURL+"changes", "reason", reachWarning)
+		}
+		if reachErr != nil {
+			m.api.LogError("Failed to create global chats subscription: MS Graph cannot reach your server at "+m.baseURL+"changes", "reason", reachErr.Error())
+			return
+		}
+
 		remoteSubscription, err = m.client.SubscribeToChats(m.baseURL, m.webhookSecret, !m.useEvaluationAPI, "")
 		if err != nil {
+			// The server may have become unreachable since the check above, so check again.
+			if _, reachErr = m.checkNotificationURL(m.baseURL); reachErr != nil {
+				m.api.LogError("Failed to create global chats subscription: MS Graph cannot reach your server at "+m.baseURL+"changes", "reason", reachErr.Error(), "error", err.Error())
+				return
+			}
+
 			m.api.LogError("Failed to create global chats subscription", "error", err.Error())
 			return
 		}
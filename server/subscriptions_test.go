@@ -9,6 +9,7 @@ import (
 	"github.com/mattermost/mattermost-plugin-msteams/server/store/storemodels"
 	"github.com/mattermost/mattermost/server/public/model"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 )
 
@@ -75,6 +76,18 @@ func TestMonitorCheckGlobalChatsSubscription(t *testing.T) {
 		expectLocalSubscription(th, t, newRemoteSubscription)
 	})
 
+	t.Run("no local subscription, no remote subscription, server unreachable", func(t *testing.T) {
+		th.Reset(t)
+
+		th.p.monitor.checkNotificationURL = func(string) (string, error) {
+			return "", fmt.Errorf("unable to reach the notification URL")
+		}
+
+		th.p.monitor.checkGlobalChatsSubscription(nil)
+		th.appClientMock.AssertNotCalled(t, "SubscribeToChats", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+		expectLocalSubscription(th, t, nil)
+	})
+
 	t.Run("no local subscription, existing remote subscription", func(t *testing.T) {
 		th.Reset(t)
 
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mattermost/mattermost-plugin-msteams/server/msteams/clientmodels"
+	"github.com/mattermost/mattermost/server/public/model"
+)
+
+// syncDebugLoggingEnabledAtKey stores the time sync debug logging was enabled, so the window
+// survives plugin restarts and is shared by every node.
+const syncDebugLoggingEnabledAtKey = "sync_debug_logging_enabled_at"
+
+// syncDebugLoggingStart returns the time sync debug logging was enabled, as persisted by the first
+// node to see it enabled. The time is cleared whenever logging is seen disabled, so enabling it
+// again starts a new window.
+func (p *Plugin) syncDebugLoggingStart() time.Time {
+	now := time.Now()
+
+	// Only set the time if not already set by another node or before a restart.
+	if _, appErr := p.API.KVSetWithOptions(syncDebugLoggingEnabledAtKey, []byte(strconv.FormatInt(now.UnixMilli(), 10)), model.PluginKVSetOptions{Atomic: true, OldValue: nil}); appErr != nil {
+		p.API.LogWarn("Unable to store the sync debug logging window", "error", appErr.Error())
+	}
+
+	data, appErr := p.API.KVGet(syncDebugLoggingEnabledAtKey)
+	if appErr != nil || data == nil {
+		return now
+	}
+
+	enabledAt, err := strconv.ParseInt(string(data), 10, 64)
+	if err != nil {
+		return now
+	}
+
+	return time.UnixMilli(enabledAt)
+}
+
+// syncDebugLoggingEnabled reports whether sync debug logging is active for the given chat,
+// honouring both the configured time window and the optional chat filter.
+func (c *configuration) syncDebugLoggingEnabled(chatID string, now time.Time) bool {
+	if !c.SyncDebugLogging {
+		return false
+	}
+
+	if c.SyncDebugLoggingMinutes > 0 && now.After(c.syncDebugLoggingEnabledAt.Add(time.Duration(c.SyncDebugLoggingMinutes)*time.Minute)) {
+		return false
+	}
+
+	if c.SyncDebugLoggingChatIDs == "" {
+		return true
+	}
+
+	for _, id := range strings.Split(c.SyncDebugLoggingChatIDs, ",") {
+		if strings.TrimSpace(id) == chatID {
+			return true
+		}
+	}
+
+	return false
+}
+
+// syncDebugLog logs the given message at info level, regardless of the server log level, when sync
+// debug logging is enabled for the chat.
+func (ah *ActivityHandler) syncDebugLog(logger *activityLogger, chatID string, msg string, keyValuePairs ...any) {
+	if !ah.plugin.getConfiguration().syncDebugLoggingEnabled(chatID, time.Now()) {
+		return
+	}
+
+	logger.LogInfo("Sync debug: "+msg, append([]any{"chat_id", chatID}, keyValuePairs...)...)
+}
+
+// syncDebugGraphRequest logs a summary of a Graph request made while processing an activity.
+func (ah *ActivityHandler) syncDebugGraphRequest(logger *activityLogger, chatID string, request string, start time.Time, err error) {
+	ah.syncDebugLog(logger, chatID, "Graph request", "request", request, "duration", time.Since(start).String(), "error", err)
+}
+
+// syncDebugMessageInput summarizes a message received from MS Teams before conversion, including
+// its content only when the admin opted in.
+func syncDebugMessageInput(msg *clientmodels.Message, includeContent bool) []any {
+	attachments := make([]string, 0, len(msg.Attachments))
+	for _, attachment := range msg.Attachments {
+		attachments = append(attachments, attachment.ContentType+" "+redactURL(attachment.ContentURL))
+	}
+
+	summary := []any{
+		"message_id", msg.ID,
+		"reply_to_id", msg.ReplyToID,
+		"mentions", len(msg.Mentions),
+		"attachments", attachments,
+	}
+	summary = append(summary, syncDebugContent("subject", msg.Subject, includeContent)...)
+	return append(summary, syncDebugContent("text", msg.Text, includeContent)...)
+}
+
+// syncDebugPostOutput summarizes the post resulting from the conversion of a message, including
+// its content only when the admin opted in.
+func syncDebugPostOutput(post *model.Post, skippedFileAttachments int, includeContent bool) []any {
+	summary := []any{
+		"file_ids", post.FileIds,
+		"link_previews", len(post.Attachments()),
+		"skipped_file_attachments", skippedFileAttachments,
+	}
+	return append(summary, syncDebugContent("message", post.Message, includeContent)...)
+}
+
+// syncDebugContent returns the given message content as key value pairs to log, or only its length
+// and a hash identifying it unless includeContent is set.
+func syncDebugContent(key string, content string, includeContent bool) []any {
+	if includeContent {
+		return []any{key, content}
+	}
+
+	hash := sha256.Sum256([]byte(content))
+	return []any{key + "_length", len(content), key + "_hash", hex.EncodeToString(hash[:8])}
+}
+
+// redactURL strips the query string and fragment from the given URL, where MS Teams may embed
+// access tokens for hosted content.
+func redactURL(rawURL string) string {
+	if rawURL == "" {
+		return ""
+	}
+
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return "[unparseable url]"
+	}
+
+	if parsed.RawQuery != "" {
+		parsed.RawQuery = "redacted"
+	}
+	parsed.Fragment = ""
+	parsed.User = nil
+
+	return parsed.String()
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/mattermost/mattermost-plugin-msteams/server/msteams"
+	"github.com/mattermost/mattermost/server/public/model"
+)
+
+// testCredentialsTimeout bounds the live token acquisition made when testing credentials.
+const testCredentialsTimeout = 30 * time.Second
+
+type ValidateSettingsResult struct {
+	Valid bool   `json:"valid"`
+	Error string `json:"error,omitempty"`
+}
+
+type TestCredentialsRequest struct {
+	TenantID     string `json:"tenant_id"`
+	ClientID     string `json:"client_id"`
+	ClientSecret string `json:"client_secret"`
+}
+
+type TestCredentialsResult struct {
+	Success bool   `json:"success"`
+	Message string `json:"message"`
+}
+
+// resolveCredentials fills in the credentials left empty or masked by the System Console
+// from the active configuration, so unsaved values can be tested alongside saved secrets.
+func (r *TestCredentialsRequest) resolveCredentials(config *configuration) {
+	if r.TenantID == "" {
+		r.TenantID = config.TenantID
+	}
+	if r.ClientID == "" {
+		r.ClientID = config.ClientID
+	}
+	if r.ClientSecret == "" || r.ClientSecret == model.FakeSetting {
+		r.ClientSecret = config.ClientSecret
+	}
+}
+
+// getSettingsSchema returns the settings schema of the plugin, to render a custom System
+// Console section.
+func (a *API) getSettingsSchema(w http.ResponseWriter, r *http.Request) {
+	userID := r.Header.Get("Mattermost-User-ID")
+	if userID == "" {
+		a.p.API.LogWarn("Not authorized")
+		http.Error(w, "not authorized", http.StatusUnauthorized)
+		return
+	}
+
+	if !a.p.API.HasPermissionTo(userID, model.PermissionManageSystem) {
+		a.p.API.LogWarn("Insufficient permissions", "user_id", userID)
+		http.Error(w, "not able to authorize the user", http.StatusForbidden)
+		return
+	}
+
+	if manifest.SettingsSchema == nil {
+		a.returnJSON(w, &model.PluginSettingsSchema{})
+		return
+	}
+
+	a.returnJSON(w, manifest.SettingsSchema)
+}
+
+// validateSettings checks unsaved plugin settings as submitted by the System Console, without
+// applying them.
+func (a *API) validateSettings(w http.ResponseWriter, r *http.Request) {
+	userID := r.Header.Get("Mattermost-User-ID")
+	if userID == "" {
+		a.p.API.LogWarn("Not authorized")
+		http.Error(w, "not authorized", http.StatusUnauthorized)
+		return
+	}
+
+	if !a.p.API.HasPermissionTo(userID, model.PermissionManageSystem) {
+		a.p.API.LogWarn("Insufficient permissions", "user_id", userID)
+		http.Error(w, "not able to authorize the user", http.StatusForbidden)
+		return
+	}
+
+	var settings configuration
+	if err := json.NewDecoder(r.Body).Decode(&settings); err != nil {
+		a.p.API.LogWarn("Error parsing settings", "error", err.Error())
+		http.Error(w, "error parsing settings", http.StatusBadRequest)
+		return
+	}
+
+	result := &ValidateSettingsResult{Valid: true}
+	if err := a.p.validateConfiguration(&settings); err != nil {
+		result.Valid = false
+		result.Error = err.Error()
+	}
+
+	a.returnJSON(w, result)
+}
+
+// testCredentials acquires an application token for MS Graph with the submitted credentials,
+// reporting whether they are valid.
+func (a *API) testCredentials(w http.ResponseWriter, r *http.Request) {
+	userID := r.Header.Get("Mattermost-User-ID")
+	if userID == "" {
+		a.p.API.LogWarn("Not authorized")
+		http.Error(w, "not authorized", http.StatusUnauthorized)
+		return
+	}
+
+	if !a.p.API.HasPermissionTo(userID, model.PermissionManageSystem) {
+		a.p.API.LogWarn("Insufficient permissions", "user_id", userID)
+		http.Error(w, "not able to authorize the user", http.StatusForbidden)
+		return
+	}
+
+	var request TestCredentialsRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			a.p.API.LogWarn("Error parsing credentials", "error", err.Error())
+			http.Error(w, "error parsing credentials", http.StatusBadRequest)
+			return
+		}
+	}
+	request.resolveCredentials(a.p.getConfiguration())
+
+	result := &TestCredentialsResult{}
+	switch {
+	case request.TenantID == "":
+		result.Message = "Tenant ID should not be empty."
+	case request.ClientID == "":
+		result.Message = "Client ID should not be empty."
+	case request.ClientSecret == "":
+		result.Message = "Client secret should not be empty."
+	default:
+		ctx, cancel := context.WithTimeout(r.Context(), testCredentialsTimeout)
+		defer cancel()
+
+		if err := a.p.checkAppCredentials(ctx, request.TenantID, request.ClientID, request.ClientSecret); err != nil {
+			a.p.API.LogInfo("Azure credentials test failed", "user_id", userID, "tenant_id", request.TenantID, "client_id", request.ClientID, "error", err.Error(), "error_category", msteams.CategorizeError(err))
+			result.Message = "Unable to acquire a token from Microsoft Entra ID: " + err.Error()
+		} else {
+			result.Success = true
+			result.Message = "Successfully acquired a token from Microsoft Entra ID."
+		}
+	}
+
+	a.returnJSON(w, result)
+}
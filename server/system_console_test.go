@@ -0,0 +1,87 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTestCredentials(t *testing.T) {
+	th := setupTestHelper(t)
+	apiURL := th.pluginURL(t, "/settings/test-credentials")
+	team := th.SetupTeam(t)
+
+	sendRequest := func(t *testing.T, user *model.User, body *TestCredentialsRequest) (*http.Response, *TestCredentialsResult) {
+		t.Helper()
+		client1 := th.SetupClient(t, user.Id)
+
+		data, err := json.Marshal(body)
+		require.NoError(t, err)
+
+		request, err := http.NewRequest(http.MethodPost, apiURL, bytes.NewReader(data))
+		require.NoError(t, err)
+
+		request.Header.Set(model.HeaderAuth, client1.AuthType+" "+client1.AuthToken)
+
+		response, err := http.DefaultClient.Do(request)
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			require.NoError(t, response.Body.Close())
+		})
+
+		var result *TestCredentialsResult
+		if response.StatusCode == http.StatusOK {
+			require.NoError(t, json.NewDecoder(response.Body).Decode(&result))
+		}
+
+		return response, result
+	}
+
+	t.Run("insufficient permissions", func(t *testing.T) {
+		th.Reset(t)
+		user := th.SetupUser(t, team)
+
+		response, result := sendRequest(t, user, &TestCredentialsRequest{})
+		assert.Equal(t, http.StatusForbidden, response.StatusCode)
+		assert.Nil(t, result)
+	})
+
+	t.Run("valid credentials, falling back to the saved secret", func(t *testing.T) {
+		th.Reset(t)
+		sysadmin := th.SetupSysadmin(t, team)
+
+		var clientSecret string
+		th.p.checkAppCredentials = func(_ context.Context, tenantID, clientID, secret string) error {
+			clientSecret = secret
+			return nil
+		}
+
+		response, result := sendRequest(t, sysadmin, &TestCredentialsRequest{ClientSecret: model.FakeSetting})
+		assert.Equal(t, http.StatusOK, response.StatusCode)
+		require.NotNil(t, result)
+		assert.True(t, result.Success)
+		assert.Equal(t, th.p.getConfiguration().ClientSecret, clientSecret)
+	})
+
+	t.Run("invalid credentials", func(t *testing.T) {
+		th.Reset(t)
+		sysadmin := th.SetupSysadmin(t, team)
+
+		th.p.checkAppCredentials = func(_ context.Context, tenantID, clientID, secret string) error {
+			return errors.New("AADSTS7000215: Invalid client secret provided")
+		}
+
+		response, result := sendRequest(t, sysadmin, &TestCredentialsRequest{ClientSecret: "wrong"})
+		assert.Equal(t, http.StatusOK, response.StatusCode)
+		require.NotNil(t, result)
+		assert.False(t, result.Success)
+		assert.Contains(t, result.Message, "Invalid client secret provided")
+	})
+}
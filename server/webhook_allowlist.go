@@ -0,0 +1,205 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+const (
+	// microsoftEndpointsURL publishes the IP ranges used by Microsoft 365 services, including
+	// the ones delivering MS Graph change notifications.
+	microsoftEndpointsURL = "https://endpoints.office.com/endpoints/worldwide"
+
+	refreshWebhookIPRangesFrequency = 24 * time.Hour
+	fetchWebhookIPRangesTimeout     = 30 * time.Second
+
+	// retryWebhookIPRangesInterval is how soon the IP ranges are fetched again after failing to
+	// load them at all, since every address is allowed until then.
+	retryWebhookIPRangesInterval = 5 * time.Minute
+)
+
+// webhookIPAllowlist holds the IP ranges change notifications may be sent from.
+type webhookIPAllowlist struct {
+	mutex  sync.RWMutex
+	ranges []*net.IPNet
+}
+
+func (l *webhookIPAllowlist) set(ranges []*net.IPNet) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	l.ranges = ranges
+}
+
+// loaded reports whether the ranges have been loaded.
+func (l *webhookIPAllowlist) loaded() bool {
+	l.mutex.RLock()
+	defer l.mutex.RUnlock()
+
+	return l.ranges != nil
+}
+
+// allows reports whether the given address belongs to one of the known ranges. Until the
+// ranges have been loaded, every address is allowed rather than rejecting all notifications.
+func (l *webhookIPAllowlist) allows(ip net.IP) bool {
+	l.mutex.RLock()
+	defer l.mutex.RUnlock()
+
+	if l.ranges == nil {
+		return true
+	}
+	if ip == nil {
+		return false
+	}
+
+	for _, ipRange := range l.ranges {
+		if ipRange.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
+
+type microsoftEndpointSet struct {
+	IPs []string `json:"ips"`
+}
+
+// parseMicrosoftIPRanges extracts the IP ranges from the Microsoft 365 endpoints web service
+// response.
+func parseMicrosoftIPRanges(r io.Reader) ([]*net.IPNet, error) {
+	var endpointSets []microsoftEndpointSet
+	if err := json.NewDecoder(r).Decode(&endpointSets); err != nil {
+		return nil, errors.Wrap(err, "failed to decode Microsoft 365 endpoints")
+	}
+
+	seen := make(map[string]bool)
+	ranges := []*net.IPNet{}
+	for _, endpointSet := range endpointSets {
+		for _, cidr := range endpointSet.IPs {
+			if seen[cidr] {
+				continue
+			}
+			seen[cidr] = true
+
+			_, ipRange, err := net.ParseCIDR(cidr)
+			if err != nil {
+				return nil, errors.Wrapf(err, "invalid IP range %q", cidr)
+			}
+			ranges = append(ranges, ipRange)
+		}
+	}
+
+	if len(ranges) == 0 {
+		return nil, errors.New("no IP ranges found in Microsoft 365 endpoints")
+	}
+
+	return ranges, nil
+}
+
+func fetchMicrosoftIPRanges(client *http.Client, endpointsURL string) ([]*net.IPNet, error) {
+	u, err := url.Parse(endpointsURL)
+	if err != nil {
+		return nil, err
+	}
+	query := u.Query()
+	query.Set("clientrequestid", uuid.NewString())
+	u.RawQuery = query.Encode()
+
+	resp, err := client.Get(u.String())
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to fetch Microsoft 365 endpoints")
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("unexpected status code %d fetching Microsoft 365 endpoints", resp.StatusCode)
+	}
+
+	return parseMicrosoftIPRanges(resp.Body)
+}
+
+// refreshWebhookIPRangesPeriodically keeps the IP ranges of this node up to date until the context
+// is done. Every node refreshes its own ranges, since each checks the notifications it receives.
+func (p *Plugin) refreshWebhookIPRangesPeriodically(ctx context.Context) {
+	for {
+		p.refreshWebhookIPRanges()
+
+		wait := refreshWebhookIPRangesFrequency
+		if !p.webhookIPAllowlist.loaded() {
+			wait = retryWebhookIPRangesInterval
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		}
+	}
+}
+
+// refreshWebhookIPRanges loads the latest Microsoft 365 IP ranges, keeping the previous ones
+// if they cannot be fetched.
+func (p *Plugin) refreshWebhookIPRanges() {
+	ranges, err := fetchMicrosoftIPRanges(&http.Client{Timeout: fetchWebhookIPRangesTimeout}, microsoftEndpointsURL)
+	if err != nil {
+		p.API.LogWarn("Failed to refresh the IP ranges allowed to send change notifications", "error", err.Error())
+		return
+	}
+
+	p.webhookIPAllowlist.set(ranges)
+	p.API.LogInfo("Refreshed the IP ranges allowed to send change notifications", "ranges", len(ranges))
+}
+
+// requestSourceIP returns the address the request originated from, honouring the proxy headers
+// the server is configured to trust.
+func requestSourceIP(r *http.Request, trustedProxyIPHeaders []string) net.IP {
+	for _, header := range trustedProxyIPHeaders {
+		if value := r.Header.Get(header); value != "" {
+			// X-Forwarded-For lists the originating client first, but a client may send its own
+			// entries ahead of it: only the right-most one, added by the trusted proxy, is reliable.
+			addresses := strings.Split(value, ",")
+			return net.ParseIP(strings.TrimSpace(addresses[len(addresses)-1]))
+		}
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+
+	return net.ParseIP(host)
+}
+
+// isWebhookSourceAllowed checks the origin of a change notification against the Microsoft 365
+// IP ranges, when enabled.
+func (p *Plugin) isWebhookSourceAllowed(r *http.Request) bool {
+	if !p.getConfiguration().WebhookIPAllowlist {
+		return true
+	}
+
+	var trustedProxyIPHeaders []string
+	if config := p.API.GetConfig(); config != nil {
+		trustedProxyIPHeaders = config.ServiceSettings.TrustedProxyIPHeader
+	}
+
+	ip := requestSourceIP(r, trustedProxyIPHeaders)
+	if !p.webhookIPAllowlist.allows(ip) {
+		p.API.LogWarn("Rejecting change notification from an address outside the Microsoft 365 IP ranges", "ip", ip.String())
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testMicrosoftEndpoints = `[
+	{"id": 1, "serviceArea": "Exchange", "ips": ["13.107.6.152/31", "2603:1006::/40"]},
+	{"id": 2, "serviceArea": "Common", "urls": ["*.office.com"]},
+	{"id": 3, "serviceArea": "Common", "ips": ["52.96.0.0/14", "13.107.6.152/31"]}
+]`
+
+func TestParseMicrosoftIPRanges(t *testing.T) {
+	t.Run("ranges from all endpoint sets", func(t *testing.T) {
+		ranges, err := parseMicrosoftIPRanges(strings.NewReader(testMicrosoftEndpoints))
+		require.NoError(t, err)
+
+		var cidrs []string
+		for _, ipRange := range ranges {
+			cidrs = append(cidrs, ipRange.String())
+		}
+		assert.Equal(t, []string{"13.107.6.152/31", "2603:1006::/40", "52.96.0.0/14"}, cidrs)
+	})
+
+	t.Run("invalid range", func(t *testing.T) {
+		_, err := parseMicrosoftIPRanges(strings.NewReader(`[{"ips": ["not-a-range"]}]`))
+		assert.Error(t, err)
+	})
+
+	t.Run("no ranges", func(t *testing.T) {
+		_, err := parseMicrosoftIPRanges(strings.NewReader(`[]`))
+		assert.Error(t, err)
+	})
+}
+
+func TestFetchMicrosoftIPRanges(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("clientrequestid") == "" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		_, _ = w.Write([]byte(testMicrosoftEndpoints))
+	}))
+	defer server.Close()
+
+	ranges, err := fetchMicrosoftIPRanges(server.Client(), server.URL)
+	require.NoError(t, err)
+	assert.Len(t, ranges, 3)
+}
+
+func TestWebhookIPAllowlist(t *testing.T) {
+	var allowlist webhookIPAllowlist
+
+	// Until loaded, every address is allowed.
+	assert.True(t, allowlist.allows(net.ParseIP("198.51.100.1")))
+
+	ranges, err := parseMicrosoftIPRanges(strings.NewReader(testMicrosoftEndpoints))
+	require.NoError(t, err)
+	allowlist.set(ranges)
+
+	assert.True(t, allowlist.allows(net.ParseIP("52.97.1.2")))
+	assert.True(t, allowlist.allows(net.ParseIP("2603:1006::1")))
+	assert.False(t, allowlist.allows(net.ParseIP("198.51.100.1")))
+	assert.False(t, allowlist.allows(nil))
+}
+
+func TestRequestSourceIP(t *testing.T) {
+	for _, testCase := range []struct {
+		Name           string
+		RemoteAddr     string
+		Headers        map[string]string
+		TrustedHeaders []string
+		Expected       string
+	}{
+		{
+			Name:       "remote address",
+			RemoteAddr: "52.97.1.2:443",
+			Expected:   "52.97.1.2",
+		},
+		{
+			Name:       "untrusted forwarded header",
+			RemoteAddr: "10.0.0.1:443",
+			Headers:    map[string]string{"X-Forwarded-For": "52.97.1.2"},
+			Expected:   "10.0.0.1",
+		},
+		{
+			Name:           "trusted forwarded header",
+			RemoteAddr:     "10.0.0.1:443",
+			Headers:        map[string]string{"X-Forwarded-For": "52.97.1.2"},
+			TrustedHeaders: []string{"X-Forwarded-For"},
+			Expected:       "52.97.1.2",
+		},
+		{
+			Name:           "trusted forwarded header, spoofed by the client",
+			RemoteAddr:     "10.0.0.1:443",
+			Headers:        map[string]string{"X-Forwarded-For": "52.97.1.2, 203.0.113.7"},
+			TrustedHeaders: []string{"X-Forwarded-For"},
+			Expected:       "203.0.113.7",
+		},
+	} {
+		t.Run(testCase.Name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/changes", nil)
+			r.RemoteAddr = testCase.RemoteAddr
+			for key, value := range testCase.Headers {
+				r.Header.Set(key, value)
+			}
+
+			assert.Equal(t, testCase.Expected, requestSourceIP(r, testCase.TrustedHeaders).String())
+		})
+	}
+}